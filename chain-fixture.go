@@ -0,0 +1,567 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	core "abelian.info/sdk/core"
+	"github.com/abesuite/abec/abecrypto"
+	"github.com/abesuite/abec/abeutil"
+	"github.com/abesuite/abec/blockchain"
+	"github.com/abesuite/abec/chainhash"
+	"github.com/abesuite/abec/wire"
+	oqsrand "github.com/cryptosuite/liboqs-go/oqs/rand"
+)
+
+// Define data types and methods for chain fixtures served by the mock abec server.
+type DemoFixtureBlock struct {
+	Block *core.AbecBlock `json:"block"`
+	Hex   string          `json:"hex"`
+}
+
+type DemoFixtureTx struct {
+	Tx  *core.AbecTx `json:"tx"`
+	Hex string       `json:"hex"`
+}
+
+// DemoFixtureCoin records a coin of a generated chain together with its expected owner and serial number,
+// so that scanning and spend tracking can be checked against it.
+type DemoFixtureCoin struct {
+	ID           string `json:"id"`
+	Owner        int    `json:"owner"`
+	Value        int64  `json:"value"`
+	Height       int64  `json:"height"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	SpentTxid    string `json:"spentTxid,omitempty"`
+	SpentHeight  int64  `json:"spentHeight,omitempty"`
+}
+
+type DemoChainFixture struct {
+	ChainInfo core.AbecChainInfo        `json:"chainInfo"`
+	Blocks    []*DemoFixtureBlock       `json:"blocks"`
+	Txs       map[string]*DemoFixtureTx `json:"txs"`
+	Coins     []*DemoFixtureCoin        `json:"coins,omitempty"`
+}
+
+func LoadDemoChainFixture(dir string) (*DemoChainFixture, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "chain.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &DemoChainFixture{}
+	err = json.Unmarshal(data, fixture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chain fixture in %s: %v", dir, err)
+	}
+	if fixture.Txs == nil {
+		fixture.Txs = make(map[string]*DemoFixtureTx)
+	}
+	for height, fixtureBlock := range fixture.Blocks {
+		if fixtureBlock.Block.Height != int64(height) {
+			return nil, fmt.Errorf("block at index %d of chain fixture in %s has height %d", height, dir, fixtureBlock.Block.Height)
+		}
+	}
+
+	return fixture, nil
+}
+
+func (fixture *DemoChainFixture) Save(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "chain.json"), data, 0644)
+}
+
+func (fixture *DemoChainFixture) Tip() int64 {
+	return int64(len(fixture.Blocks)) - 1
+}
+
+func (fixture *DemoChainFixture) FindBlock(hash string) *DemoFixtureBlock {
+	for _, fixtureBlock := range fixture.Blocks {
+		if fixtureBlock.Block.BlockHash == hash {
+			return fixtureBlock
+		}
+	}
+	return nil
+}
+
+// AppendBlock adds a block on top of the fixture chain, including the given txs. The block has no coinbase tx.
+func (fixture *DemoChainFixture) AppendBlock(txs []*DemoFixtureTx, blockTime int64) (*DemoFixtureBlock, error) {
+	msgTxs := make([]*wire.MsgTxAbe, 0, len(txs))
+	for _, fixtureTx := range txs {
+		msgTx := &wire.MsgTxAbe{}
+		err := msgTx.DeserializeFull(bytes.NewReader(core.MakeBytesFromHexString(fixtureTx.Hex)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tx %s: %v", fixtureTx.Tx.TxID, err)
+		}
+		msgTxs = append(msgTxs, msgTx)
+	}
+	fixtureBlock, blockTxs, err := newDemoFixtureBlock(fixture, blockTime, msgTxs)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range blockTxs {
+		fixture.Txs[tx.TxID] = &DemoFixtureTx{Tx: tx, Hex: tx.Hex}
+	}
+	fixture.Blocks = append(fixture.Blocks, fixtureBlock)
+	return fixtureBlock, nil
+}
+
+// newDemoFixtureBlock serializes a block of the given txs on top of the fixture chain, and decodes its txs.
+func newDemoFixtureBlock(fixture *DemoChainFixture, blockTime int64, txs []*wire.MsgTxAbe) (*DemoFixtureBlock, []*core.AbecTx, error) {
+	height := int64(len(fixture.Blocks))
+	var prevHash chainhash.Hash
+	if height > 0 {
+		hash, err := chainhash.NewHashFromStr(fixture.Blocks[height-1].Block.BlockHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		prevHash = *hash
+	}
+
+	msgBlock := &wire.MsgBlockAbe{
+		Header: wire.BlockHeader{
+			Version:   int32(wire.BlockVersionEthashPow),
+			PrevBlock: prevHash,
+			Timestamp: time.Unix(blockTime, 0),
+			Bits:      0x207fffff,
+			Height:    int32(height),
+		},
+	}
+	utilTxs := make([]*abeutil.TxAbe, 0, len(txs))
+	for _, msgTx := range txs {
+		msgBlock.AddTransaction(msgTx)
+		utilTxs = append(utilTxs, abeutil.NewTxAbe(msgTx))
+	}
+	// The merkle tree of an empty block is left as the zero hash.
+	if len(utilTxs) > 0 {
+		merkles := blockchain.BuildMerkleTreeStoreAbe(utilTxs, true)
+		msgBlock.Header.MerkleRoot = *merkles[len(merkles)-1]
+	}
+
+	blockBuffer := bytes.NewBuffer(make([]byte, 0, msgBlock.SerializeSize()))
+	var err error
+	if len(txs) > 0 {
+		err = msgBlock.Serialize(blockBuffer)
+	} else {
+		// Serialize looks for witnesses in the first tx, an empty block has none.
+		err = msgBlock.BtcEncode(blockBuffer, 0, wire.BaseEncoding)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	block := &core.AbecBlock{
+		Height:     height,
+		Version:    int64(msgBlock.Header.Version),
+		VersionHex: fmt.Sprintf("%08x", msgBlock.Header.Version),
+		Time:       blockTime,
+		Size:       int64(msgBlock.SerializeSizeStripped()),
+		FullSize:   int64(blockBuffer.Len()),
+		BlockHash:  msgBlock.BlockHash().String(),
+		MerkleRoot: msgBlock.Header.MerkleRoot.String(),
+		Bits:       fmt.Sprintf("%08x", msgBlock.Header.Bits),
+		TxHashes:   make([]string, 0, len(txs)),
+	}
+	if height > 0 {
+		block.PrevBlockHash = prevHash.String()
+	}
+
+	blockTxs := make([]*core.AbecTx, 0, len(txs))
+	for _, msgTx := range txs {
+		txBuffer := bytes.NewBuffer(make([]byte, 0, msgTx.SerializeSizeFull()))
+		err = msgTx.SerializeFull(txBuffer)
+		if err != nil {
+			return nil, nil, err
+		}
+		tx, err := DecodeDemoAbecTx(txBuffer.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+		tx.Time = blockTime
+		tx.BlockHash = block.BlockHash
+		tx.BlockTime = blockTime
+		block.TxHashes = append(block.TxHashes, tx.TxID)
+		blockTxs = append(blockTxs, tx)
+	}
+
+	return &DemoFixtureBlock{Block: block, Hex: hex.EncodeToString(blockBuffer.Bytes())}, blockTxs, nil
+}
+
+func (ds *DemoSet) getDemoFixturePath() string {
+	return ds.getDemoFilePath(fmt.Sprintf("fixture-chain-%d", ds.getDemoChainID()))
+}
+
+// RecordDemoChainFixture copies the first count blocks and their txs from a node into a chain fixture.
+func RecordDemoChainFixture(client DemoAbecRPCClient, count int64) (*DemoChainFixture, error) {
+	_, chainInfo, err := client.GetChainInfo()
+	if err != nil {
+		return nil, err
+	}
+	if count > chainInfo.NumBlocks+1 {
+		count = chainInfo.NumBlocks + 1
+	}
+
+	fixture := &DemoChainFixture{ChainInfo: *chainInfo, Txs: make(map[string]*DemoFixtureTx)}
+	for height := int64(0); height < count; height++ {
+		_, blockHash, err := client.GetBlockHash(height)
+		if err != nil {
+			return nil, err
+		}
+		_, block, err := client.GetBlock(*blockHash)
+		if err != nil {
+			return nil, err
+		}
+		blockBytes, err := client.GetBlockBytes(*blockHash)
+		if err != nil {
+			return nil, err
+		}
+		block.Confirmations = 0
+		block.NextBlockHash = ""
+		fixture.Blocks = append(fixture.Blocks, &DemoFixtureBlock{Block: block, Hex: blockBytes.HexString()})
+
+		for _, txHash := range block.TxHashes {
+			_, tx, err := client.GetRawTx(txHash)
+			if err != nil {
+				return nil, err
+			}
+			tx.Confirmations = 0
+			fixture.Txs[txHash] = &DemoFixtureTx{Tx: tx, Hex: tx.Hex}
+		}
+	}
+
+	return fixture, nil
+}
+
+// Define data types and methods for generating synthetic chain fixtures.
+//
+// The generated chain is deterministic: which accounts receive the coinbase of each block, which coins are
+// spent at which heights, values, fees and block times follow from the spec, and the randomness of the
+// lattice-based tx generation is drawn from a stream seeded by the spec, so that the same spec always
+// generates the same txs and blocks.
+type DemoChainFixtureSpec struct {
+	ChainID       int8
+	NumBlocks     int64
+	Accounts      []*DemoAccount
+	CoinbaseValue int64
+	TxFee         int64
+	GenesisTime   int64
+	BlockInterval int64
+}
+
+type demoChainGenerator struct {
+	spec     *DemoChainFixtureSpec
+	fixture  *DemoChainFixture
+	accounts map[int]*DemoAccount
+	coins    map[string]*DemoFixtureCoin
+	txOuts   map[string]core.Bytes
+}
+
+// GenerateDemoChainFixture generates a chain in which the coinbase of block h pays account h mod n, and,
+// from height 4 on, every block at height 3k+1 includes a tx spending the coinbase coin of block 3k-3 to the
+// next account. The serial number of every spent coin is checked against DecodeCoinSerialNumbers.
+func GenerateDemoChainFixture(spec *DemoChainFixtureSpec) (*DemoChainFixture, error) {
+	if len(spec.Accounts) == 0 {
+		return nil, fmt.Errorf("no account to pay")
+	}
+	if spec.CoinbaseValue <= spec.TxFee {
+		return nil, fmt.Errorf("coinbase value %d does not cover tx fee %d", spec.CoinbaseValue, spec.TxFee)
+	}
+
+	gen := &demoChainGenerator{
+		spec: spec,
+		fixture: &DemoChainFixture{
+			ChainInfo: core.AbecChainInfo{IsTestnet: spec.ChainID != 0, NetID: byte(spec.ChainID), RelayFee: core.NeutrinoToAbel(spec.TxFee)},
+			Txs:       make(map[string]*DemoFixtureTx),
+		},
+		accounts: make(map[int]*DemoAccount),
+		coins:    make(map[string]*DemoFixtureCoin),
+		txOuts:   make(map[string]core.Bytes),
+	}
+	for _, account := range spec.Accounts {
+		gen.accounts[account.SerialNo] = account
+	}
+
+	err := withDemoSeededRandomness(spec.seed(), gen.generate)
+	if err != nil {
+		return nil, err
+	}
+	return gen.fixture, nil
+}
+
+// seed returns the bytes the randomness of the generation is seeded with, which cover the whole spec.
+func (spec *DemoChainFixtureSpec) seed() []byte {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d:%d:%d:%d:%d:%d", spec.ChainID, spec.NumBlocks, spec.CoinbaseValue, spec.TxFee, spec.GenesisTime, spec.BlockInterval)
+	for _, account := range spec.Accounts {
+		fmt.Fprintf(hash, ":%d:%x", account.SerialNo, account.Fingerprint)
+	}
+	return hash.Sum(nil)
+}
+
+// demoRandomnessMutex serializes the swaps of the process-wide randomness, so that two generations never
+// draw from the same stream or restore the system sources in the wrong order.
+var demoRandomnessMutex sync.Mutex
+
+// withDemoSeededRandomness runs fn with the randomness of crypto/rand and liboqs drawn from an AES-CTR stream
+// keyed by the seed. The SDK and pqringct draw from these process-wide sources and take no source as argument,
+// so they are swapped for the time of fn: nothing else in the process may draw randomness meanwhile, which is why
+// fixtures are only generated by the fixture demo and the tests, before any server is started. If the system
+// randomness of liboqs cannot be restored, it is drawn from crypto/rand instead, and an error is returned.
+func withDemoSeededRandomness(seed []byte, fn func() error) (err error) {
+	demoRandomnessMutex.Lock()
+	defer demoRandomnessMutex.Unlock()
+
+	key := sha256.Sum256(seed)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return err
+	}
+	stream := cipher.NewCTR(block, make([]byte, aes.BlockSize))
+	var mutex sync.Mutex
+	read := func(b []byte) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for i := range b {
+			b[i] = 0
+		}
+		stream.XORKeyStream(b, b)
+	}
+
+	systemReader := rand.Reader
+	rand.Reader = demoRandReader(read)
+	defer func() {
+		rand.Reader = systemReader
+	}()
+	err = oqsrand.RandomBytesCustomAlgorithm(func(b []byte, n int) {
+		read(b[:n])
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		resetErr := oqsrand.RandomBytesSwitchAlgorithm("system")
+		if resetErr == nil {
+			return
+		}
+		if fallbackErr := oqsrand.RandomBytesCustomAlgorithm(func(b []byte, n int) {
+			if _, err := io.ReadFull(systemReader, b[:n]); err != nil {
+				panic(err)
+			}
+		}); fallbackErr != nil {
+			panic(fmt.Sprintf("liboqs randomness is left seeded: %v, %v", resetErr, fallbackErr))
+		}
+		err = fmt.Errorf("failed to restore the system randomness of liboqs: %v", resetErr)
+	}()
+
+	return fn()
+}
+
+type demoRandReader func([]byte)
+
+func (read demoRandReader) Read(b []byte) (int, error) {
+	read(b)
+	return len(b), nil
+}
+
+func (gen *demoChainGenerator) generate() error {
+	spec := gen.spec
+	for height := int64(0); height < spec.NumBlocks; height++ {
+		var txs []*wire.MsgTxAbe
+		fees := int64(0)
+		if height >= 4 && height%3 == 1 {
+			spendTx, err := gen.spendCoinbase(height-4, height)
+			if err != nil {
+				return fmt.Errorf("failed to spend coinbase of block %d: %v", height-4, err)
+			}
+			txs = append(txs, spendTx)
+			fees += spec.TxFee
+		}
+
+		owner := spec.Accounts[height%int64(len(spec.Accounts))]
+		coinbaseTx, err := gen.coinbaseTx(height, owner, spec.CoinbaseValue+fees)
+		if err != nil {
+			return fmt.Errorf("failed to generate coinbase of block %d: %v", height, err)
+		}
+		txs = append([]*wire.MsgTxAbe{coinbaseTx}, txs...)
+
+		err = gen.appendBlock(height, txs)
+		if err != nil {
+			return fmt.Errorf("failed to generate block %d: %v", height, err)
+		}
+	}
+
+	// Fill in the serial numbers of all coins whose ring group is complete.
+	for _, coin := range gen.fixture.Coins {
+		if coin.SerialNumber != "" || getDemoRingChain(gen.spec.ChainID).LastRingBlockHeight(coin.Height) > gen.fixture.Tip() {
+			continue
+		}
+		serialNumber, err := gen.serialNumber(coin)
+		if err != nil {
+			return fmt.Errorf("failed to decode serial number of coin %s: %v", coin.ID, err)
+		}
+		coin.SerialNumber = serialNumber.HexString()
+	}
+	gen.fixture.ChainInfo.NumBlocks = gen.fixture.Tip()
+
+	return nil
+}
+
+func (gen *demoChainGenerator) coinbaseTx(height int64, owner *DemoAccount, value int64) (*wire.MsgTxAbe, error) {
+	// Build the coinbase template the same way abec miners do, the input value is carried by TxFee.
+	msgTx := wire.NewMsgTxAbe(wire.TxVersion)
+	txIn, err := wire.NewStandardCoinbaseTxIn(int32(height), msgTx.Version)
+	if err != nil {
+		return nil, err
+	}
+	msgTx.AddTxIn(txIn)
+	msgTx.TxFee = uint64(value)
+	msgTx.TxMemo = []byte{byte(msgTx.Version >> 24), byte(msgTx.Version >> 16), byte(msgTx.Version >> 8), byte(msgTx.Version)}
+
+	outputDescs := []*abecrypto.AbeTxOutputDesc{abecrypto.NewAbeTxOutDesc(owner.CryptoAddress.Data(), uint64(value))}
+	return abecrypto.CoinbaseTxGen(outputDescs, msgTx)
+}
+
+func (gen *demoChainGenerator) ringBlockDescs(height int64) map[int64]*core.TxBlockDesc {
+	ringBlockDescs := make(map[int64]*core.TxBlockDesc)
+	for _, ringBlockHeight := range getDemoRingChain(gen.spec.ChainID).RingBlockHeights(height) {
+		ringBlockBytes := core.MakeBytesFromHexString(gen.fixture.Blocks[ringBlockHeight].Hex)
+		ringBlockDescs[ringBlockHeight] = core.NewTxBlockDesc(ringBlockBytes, ringBlockHeight)
+	}
+	return ringBlockDescs
+}
+
+func (gen *demoChainGenerator) serialNumber(coin *DemoFixtureCoin) (core.Bytes, error) {
+	sep := strings.LastIndex(coin.ID, ":")
+	index, err := strconv.Atoi(coin.ID[sep+1:])
+	if err != nil {
+		return nil, fmt.Errorf("malformed coin id %s", coin.ID)
+	}
+	coinID := core.NewCoinID(core.MakeBytesFromHexString(coin.ID[:sep]), uint8(index))
+	serialNumbers, err := core.DecodeCoinSerialNumbers(
+		[]*core.CoinID{coinID},
+		[]*core.CryptoKey{gen.accounts[coin.Owner].SerialNoSecretKey.CryptoKey()},
+		gen.ringBlockDescs(coin.Height))
+	if err != nil {
+		return nil, err
+	}
+	return serialNumbers[0], nil
+}
+
+// spendCoinbase spends the coinbase coin of block coinHeight to the next account in a tx to be included at height.
+func (gen *demoChainGenerator) spendCoinbase(coinHeight int64, height int64) (*wire.MsgTxAbe, error) {
+	coinbaseTxHash := gen.fixture.Blocks[coinHeight].Block.TxHashes[0]
+	coin := gen.coins[fmt.Sprintf("%s:0", coinbaseTxHash)]
+	sender := gen.accounts[coin.Owner]
+	receiver := gen.spec.Accounts[(coinHeight+1)%int64(len(gen.spec.Accounts))]
+
+	txInDesc := &core.TxInDesc{
+		TxOutData:  gen.txOuts[coin.ID],
+		CoinValue:  coin.Value,
+		Owner:      sender.ShortAbelAddress,
+		Height:     coin.Height,
+		TxHash:     core.MakeBytesFromHexString(coinbaseTxHash),
+		TxOutIndex: 0,
+	}
+	txOutDesc := core.NewTxOutDesc(receiver.AbelAddress, coin.Value-gen.spec.TxFee)
+	ringBlockDescs := gen.ringBlockDescs(coin.Height)
+	txDesc := core.NewTxDesc([]*core.TxInDesc{txInDesc}, []*core.TxOutDesc{txOutDesc}, gen.spec.TxFee, ringBlockDescs)
+	unsignedRawTx, err := core.GenerateUnsignedRawTx(txDesc)
+	if err != nil {
+		return nil, err
+	}
+	signerKeys := []*core.CryptoKeysAndAddress{{
+		SpendSecretKey:    *sender.SpendSecretKey.CryptoKey(),
+		SerialNoSecretKey: *sender.SerialNoSecretKey.CryptoKey(),
+		ViewSecretKey:     *sender.ViewSecretKey.CryptoKey(),
+		CryptoAddress:     *sender.CryptoAddress,
+	}}
+	signedRawTx, err := core.GenerateSignedRawTx(unsignedRawTx, signerKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	msgTx := &wire.MsgTxAbe{}
+	err = msgTx.DeserializeFull(bytes.NewReader(signedRawTx.Bytes))
+	if err != nil {
+		return nil, err
+	}
+
+	// The serial number revealed by the spend must be the one the owner can compute in advance.
+	serialNumber, err := gen.serialNumber(coin)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(msgTx.TxIns[0].SerialNumber, serialNumber) {
+		return nil, fmt.Errorf("serial number mismatch for coin %s: %x in tx, %s decoded", coin.ID, msgTx.TxIns[0].SerialNumber, serialNumber.HexString())
+	}
+	coin.SerialNumber = serialNumber.HexString()
+	coin.SpentTxid = msgTx.TxHash().String()
+	coin.SpentHeight = height
+
+	return msgTx, nil
+}
+
+func (gen *demoChainGenerator) appendBlock(height int64, txs []*wire.MsgTxAbe) error {
+	blockTime := gen.spec.GenesisTime + height*gen.spec.BlockInterval
+	fixtureBlock, blockTxs, err := newDemoFixtureBlock(gen.fixture, blockTime, txs)
+	if err != nil {
+		return err
+	}
+
+	for i, tx := range blockTxs {
+		if i == 0 {
+			// The fee field of a coinbase tx carries the block reward, which is not a fee.
+			tx.Fee = 0
+		}
+		gen.fixture.Txs[tx.TxID] = &DemoFixtureTx{Tx: tx, Hex: tx.Hex}
+
+		// Record the coins paid to known accounts.
+		for _, vout := range tx.Vout {
+			txOutData, coinAddress, err := DecodeDemoTxOut(vout.Script)
+			if err != nil {
+				return err
+			}
+			for _, account := range gen.spec.Accounts {
+				if !bytes.Equal(coinAddress.Fingerprint(), account.Fingerprint) {
+					continue
+				}
+				value, err := core.DecodeValueFromTxOutData(txOutData, account.ViewSecretKey.CryptoKey())
+				if err != nil {
+					return err
+				}
+				coin := &DemoFixtureCoin{ID: fmt.Sprintf("%s:%d", tx.TxID, vout.N), Owner: account.SerialNo, Value: value, Height: height}
+				gen.coins[coin.ID] = coin
+				gen.txOuts[coin.ID] = txOutData
+				gen.fixture.Coins = append(gen.fixture.Coins, coin)
+			}
+		}
+	}
+
+	gen.fixture.Blocks = append(gen.fixture.Blocks, fixtureBlock)
+	return nil
+}
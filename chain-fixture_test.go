@@ -0,0 +1,89 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	oqsrand "github.com/cryptosuite/liboqs-go/oqs/rand"
+)
+
+// TestGenerateDemoChainFixtureDeterministic generates a short chain with spends twice from the same spec,
+// and checks that the fixtures are identical, down to the serialized blocks.
+func TestGenerateDemoChainFixtureDeterministic(t *testing.T) {
+	spec := demoGoldenFixtureSpec(t)
+	spec.NumBlocks = 8
+
+	var generated [2][]byte
+	for i := range generated {
+		fixture, err := GenerateDemoChainFixture(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated[i], err = json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(generated[0]) != string(generated[1]) {
+		t.Errorf("fixtures generated from the same spec differ:\n%s", diffDemoGoldenOutput(generated[0], generated[1]))
+	}
+
+	other := *spec
+	other.GenesisTime++
+	fixture, err := GenerateDemoChainFixture(&other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &DemoChainFixture{}
+	if err := json.Unmarshal(generated[0], first); err != nil {
+		t.Fatal(err)
+	}
+	if fixture.Blocks[0].Block.TxHashes[0] == first.Blocks[0].Block.TxHashes[0] {
+		t.Errorf("fixtures generated from different specs share the coinbase tx %s", first.Blocks[0].Block.TxHashes[0])
+	}
+}
+
+// TestWithDemoSeededRandomness checks that the seeded randomness is repeatable, and that the system randomness
+// is restored afterwards, even if fn fails.
+func TestWithDemoSeededRandomness(t *testing.T) {
+	systemReader := rand.Reader
+	draw := func() []byte {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		return append(b, oqsrand.RandomBytes(32)...)
+	}
+
+	var drawn [2][]byte
+	for i := range drawn {
+		err := withDemoSeededRandomness([]byte("seed"), func() error {
+			drawn[i] = draw()
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(drawn[0], drawn[1]) {
+		t.Errorf("randomness drawn with the same seed differs")
+	}
+
+	fnErr := errors.New("failed")
+	if err := withDemoSeededRandomness([]byte("seed"), func() error { return fnErr }); err != fnErr {
+		t.Errorf("got error %v, expecting the error of fn", err)
+	}
+	if rand.Reader != systemReader {
+		t.Errorf("crypto/rand reader is not restored")
+	}
+	if bytes.Equal(draw(), drawn[0]) || bytes.Equal(draw(), draw()) {
+		t.Errorf("randomness is still seeded")
+	}
+}
@@ -0,0 +1,168 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define the registry of the chains the demos can run against.
+//
+// Every registered chain has a bundled config in resources/config-chain-<id>.json, which demoInit
+// bootstraps the demo config from, and may have bundled demo accounts in resources/accounts-chain-<id>.json.
+const (
+	DemoChainMainnet int8 = 0
+	DemoChainTestnet int8 = 2
+	DemoChainSimnet  int8 = 3
+)
+
+type DemoChain struct {
+	ID          int8
+	Name        string
+	Description string
+	// Endpoints are the default abec RPC endpoints of the chain, the first one being the primary one and
+	// the others abec.rpc.endpoint.N. The endpoint has to be configured for a chain without any.
+	Endpoints []string
+	// RPCPort is the default RPC port of an abec node of the chain.
+	RPCPort int
+	// RingSize is the maximum number of coins in a ring, taken from RingBlockNum consecutive blocks.
+	RingSize     int
+	RingBlockNum int
+}
+
+var demoChains = []*DemoChain{
+	{
+		ID:           DemoChainMainnet,
+		Name:         "mainnet",
+		Description:  "Abelian mainnet, the RPC endpoint and credentials have to be set.",
+		RPCPort:      8667,
+		RingSize:     7,
+		RingBlockNum: 3,
+	},
+	{
+		ID:           DemoChainTestnet,
+		Name:         "testnet",
+		Description:  "Abelian testnet, with the public testnet RPC endpoint.",
+		Endpoints:    []string{"https://testnet-rpc-00.abelian.info"},
+		RPCPort:      18667,
+		RingSize:     7,
+		RingBlockNum: 3,
+	},
+	{
+		ID:           DemoChainSimnet,
+		Name:         "simnet",
+		Description:  "A local abec node run with --simnet --notls.",
+		Endpoints:    []string{"http://127.0.0.1:18889"},
+		RPCPort:      18889,
+		RingSize:     7,
+		RingBlockNum: 3,
+	},
+}
+
+func findDemoChain(id int8) *DemoChain {
+	for _, chain := range demoChains {
+		if chain.ID == id {
+			return chain
+		}
+	}
+	return nil
+}
+
+func getDemoChainNames() []string {
+	names := make([]string, len(demoChains))
+	for i, chain := range demoChains {
+		names[i] = fmt.Sprintf("%s (%d)", chain.Name, chain.ID)
+	}
+	return names
+}
+
+// getDemoRingChain returns the registered chain whose ring parameters apply to the given chain ID. An
+// unregistered chain, e.g. a private one, runs the consensus of the testnet.
+func getDemoRingChain(id int8) *DemoChain {
+	if chain := findDemoChain(id); chain != nil {
+		return chain
+	}
+	return findDemoChain(DemoChainTestnet)
+}
+
+// parseDemoChainID parses a chain ID of the config, returning the chain if it is registered.
+func parseDemoChainID(value string) (int8, *DemoChain, error) {
+	id, err := strconv.ParseInt(value, 10, 8)
+	if err != nil || id < 0 || id > 15 {
+		return 0, nil, newDemoError(DemoErrorValidation, "chain ID %q is not in range 0 ~ 15", value)
+	}
+	return int8(id), findDemoChain(int8(id)), nil
+}
+
+func (chain *DemoChain) ConfigResource() string {
+	return fmt.Sprintf("resources/config-chain-%d.json", chain.ID)
+}
+
+// Profile returns the config profile of the chain, made of its bundled config and its default endpoints.
+func (chain *DemoChain) Profile() *DemoConfigProfile {
+	values := make(map[string]string)
+	for i, endpoint := range chain.Endpoints {
+		if i == 0 {
+			values["abec.rpc.endpoint"] = endpoint
+		} else {
+			values[fmt.Sprintf("abec.rpc.endpoint.%d", i)] = endpoint
+		}
+	}
+	return &DemoConfigProfile{
+		Name:        chain.Name,
+		Description: chain.Description,
+		Resource:    chain.ConfigResource(),
+		Values:      values,
+	}
+}
+
+// RingBlockHeights returns the heights of the ring blocks of a coin created at the given height, which are the
+// RingBlockNum consecutive blocks of its ring group.
+func (chain *DemoChain) RingBlockHeights(height int64) []int64 {
+	ringBlockNum := int64(chain.RingBlockNum)
+	firstRingBlockHeight := height - height%ringBlockNum
+	ringBlockHeights := make([]int64, ringBlockNum)
+	for i := range ringBlockHeights {
+		ringBlockHeights[i] = firstRingBlockHeight + int64(i)
+	}
+	return ringBlockHeights
+}
+
+// LastRingBlockHeight returns the height of the last ring block of a coin created at the given height, from
+// which on the serial number of the coin can be calculated.
+func (chain *DemoChain) LastRingBlockHeight(height int64) int64 {
+	ringBlockHeights := chain.RingBlockHeights(height)
+	return ringBlockHeights[len(ringBlockHeights)-1]
+}
+
+// AbelAddressPrefix returns the first byte of the abel addresses of the chain, which is the chain ID.
+func (chain *DemoChain) AbelAddressPrefix() core.Bytes {
+	return core.Bytes{byte(chain.ID)}
+}
+
+// ShortAbelAddressPrefix returns the first two bytes of the short abel addresses of the chain, 0xab and 0xe1 + chain ID.
+func (chain *DemoChain) ShortAbelAddressPrefix() core.Bytes {
+	return core.Bytes{0xab, 0xe1 + byte(chain.ID)}
+}
+
+// CheckAbelAddress checks that an abel address belongs to the chain.
+func (chain *DemoChain) CheckAbelAddress(address *core.AbelAddress) error {
+	if address.GetChainID() != chain.ID {
+		return newDemoError(DemoErrorValidation, "abel address of chain %d is not a %s address", address.GetChainID(), chain.Name)
+	}
+	return nil
+}
+
+// CheckShortAbelAddress checks that a short abel address belongs to the chain.
+func (chain *DemoChain) CheckShortAbelAddress(address *core.ShortAbelAddress) error {
+	if !bytes.HasPrefix(address.Data(), chain.ShortAbelAddressPrefix()) {
+		return newDemoError(DemoErrorValidation, "short abel address of chain %d is not a %s address", address.GetChainID(), chain.Name)
+	}
+	return nil
+}
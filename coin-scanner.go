@@ -0,0 +1,175 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"sort"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for scanning blocks for the coins of tracked accounts.
+type DemoScannedCoin struct {
+	// AccountIndex is the index of the owner in the accounts of the scanner.
+	AccountIndex int
+	Account      *DemoAccount
+	Coin         *core.Coin
+}
+
+// DemoScannedSpend is a serial number consumed by a tx, which may or may not be one of a tracked coin.
+type DemoScannedSpend struct {
+	SerialNumber string
+	Txid         string
+}
+
+type DemoScannedBlock struct {
+	Height int64
+	Hash   string
+	Coins  []*DemoScannedCoin
+	Spends []*DemoScannedSpend
+}
+
+type DemoCoinScanner struct {
+	client   DemoAbecRPCClient
+	accounts []*DemoAccount
+}
+
+func NewDemoCoinScanner(client DemoAbecRPCClient, accounts []*DemoAccount) *DemoCoinScanner {
+	return &DemoCoinScanner{client: client, accounts: accounts}
+}
+
+// Accounts returns the tracked accounts.
+func (scanner *DemoCoinScanner) Accounts() []*DemoAccount {
+	return scanner.accounts
+}
+
+// AddAccount starts tracking an account in the blocks scanned from now on.
+func (scanner *DemoCoinScanner) AddAccount(account *DemoAccount) {
+	scanner.accounts = append(scanner.accounts, account)
+}
+
+// ScanBlock returns the coins of the tracked accounts created in a block, without their serial numbers, and
+// the serial numbers consumed by the txs of the block.
+func (scanner *DemoCoinScanner) ScanBlock(height int64) (*DemoScannedBlock, error) {
+	_, blockHash, err := scanner.client.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	_, block, err := scanner.client.GetBlock(*blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	scanned := &DemoScannedBlock{Height: height, Hash: *blockHash}
+	for _, txHash := range block.TxHashes {
+		coins, spends, err := scanner.ScanTx(txHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, scannedCoin := range coins {
+			scannedCoin.Coin.BlockHash = core.MakeBytesFromHexString(*blockHash)
+			scannedCoin.Coin.BlockHeight = height
+		}
+		scanned.Coins = append(scanned.Coins, coins...)
+		scanned.Spends = append(scanned.Spends, spends...)
+	}
+
+	return scanned, nil
+}
+
+// ScanTx returns the coins of the tracked accounts created by a tx, without their serial numbers and blocks,
+// and the serial numbers consumed by the tx. It also scans the txs of the mempool.
+func (scanner *DemoCoinScanner) ScanTx(txHash string) ([]*DemoScannedCoin, []*DemoScannedSpend, error) {
+	_, tx, err := scanner.client.GetRawTx(txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	coins := make([]*DemoScannedCoin, 0)
+	spends := make([]*DemoScannedSpend, 0, len(tx.Vin))
+	for _, vin := range tx.Vin {
+		spends = append(spends, &DemoScannedSpend{SerialNumber: vin.SerialNumber, Txid: txHash})
+	}
+
+	for txIndex, vout := range tx.Vout {
+		voutData, coinAddress, err := DecodeDemoTxOut(vout.Script)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i, account := range scanner.accounts {
+			if !bytes.Equal(coinAddress.Fingerprint(), account.Fingerprint) {
+				continue
+			}
+			txoValue, err := core.DecodeValueFromTxOutData(voutData, account.ViewSecretKey.CryptoKey())
+			if err != nil {
+				return nil, nil, err
+			}
+			coin := &core.Coin{
+				ID:                core.CoinID{TxHash: core.MakeBytesFromHexString(txHash), Index: uint8(txIndex)},
+				OwnerShortAddress: account.ShortAbelAddress,
+				OwnerAddress:      account.AbelAddress,
+				Value:             txoValue,
+				SerialNumber:      nil,
+				TxVoutData:        voutData,
+				BlockHeight:       -1,
+			}
+			coins = append(coins, &DemoScannedCoin{AccountIndex: i, Account: account, Coin: coin})
+		}
+	}
+
+	return coins, spends, nil
+}
+
+// getDemoRingBlockHeights returns the heights of the ring blocks of coins of a chain created at the given heights,
+// in ascending order.
+func getDemoRingBlockHeights(chainID int8, heights []int64) []int64 {
+	chain := getDemoRingChain(chainID)
+	ringBlockHeights := make([]int64, 0, len(heights)*chain.RingBlockNum)
+	for _, height := range heights {
+		for _, ringBlockHeight := range chain.RingBlockHeights(height) {
+			if !contains(ringBlockHeights, ringBlockHeight) {
+				ringBlockHeights = append(ringBlockHeights, ringBlockHeight)
+			}
+		}
+	}
+	sort.Slice(ringBlockHeights, func(i, j int) bool { return ringBlockHeights[i] < ringBlockHeights[j] })
+	return ringBlockHeights
+}
+
+// FetchRingBlocks returns the blocks at the given heights, as needed to calculate serial numbers or to spend coins.
+func (scanner *DemoCoinScanner) FetchRingBlocks(heights []int64) (map[int64]*core.TxBlockDesc, error) {
+	ringBlocks := make(map[int64]*core.TxBlockDesc)
+	for _, height := range heights {
+		ringBlockBytes, err := scanner.client.GetBlockBytesByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		ringBlocks[height] = core.NewTxBlockDesc(ringBlockBytes, height)
+	}
+	return ringBlocks, nil
+}
+
+// DecodeDemoSerialNumbers calculates the serial numbers of coins in a batch, given the ring blocks of all of them.
+func DecodeDemoSerialNumbers(coins []*DemoScannedCoin, ringBlocks map[int64]*core.TxBlockDesc) error {
+	if len(coins) == 0 {
+		return nil
+	}
+	coinIDs := make([]*core.CoinID, len(coins))
+	serialNoSecretKeys := make([]*core.CryptoKey, len(coins))
+	for i, scannedCoin := range coins {
+		coinIDs[i] = &scannedCoin.Coin.ID
+		serialNoSecretKeys[i] = scannedCoin.Account.SerialNoSecretKey.CryptoKey()
+	}
+	serialNumbers, err := core.DecodeCoinSerialNumbers(coinIDs, serialNoSecretKeys, ringBlocks)
+	if err != nil {
+		return err
+	}
+	for i, scannedCoin := range coins {
+		scannedCoin.Coin.SerialNumber = core.AsBytes(serialNumbers[i])
+	}
+	return nil
+}
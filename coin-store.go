@@ -0,0 +1,240 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for the local coin store.
+type DemoCoinStatus string
+
+const (
+	DemoCoinUnspent DemoCoinStatus = "unspent"
+	DemoCoinPending DemoCoinStatus = "pending"
+	DemoCoinSpent   DemoCoinStatus = "spent"
+)
+
+type DemoCoinRecord struct {
+	ID           string         `json:"id"`
+	AccountSeqNo int            `json:"account"`
+	Fingerprint  string         `json:"fingerprint"`
+	TxHash       string         `json:"txHash"`
+	TxOutIndex   uint8          `json:"txOutIndex"`
+	Value        int64          `json:"value"`
+	BlockHash    string         `json:"blockHash"`
+	BlockHeight  int64          `json:"blockHeight"`
+	SerialNumber string         `json:"serialNumber,omitempty"`
+	Status       DemoCoinStatus `json:"status"`
+	PendingTxid  string         `json:"pendingTxid,omitempty"`
+	SpentTxid    string         `json:"spentTxid,omitempty"`
+	SpentHeight  int64          `json:"spentHeight,omitempty"`
+}
+
+type DemoCoinStore struct {
+	path string
+	// ScannedHeight is the last block scanned by the sync loop of the wallet server, -1 if none.
+	ScannedHeight int64 `json:"scannedHeight"`
+	// ScanFromHeight is the first block scanned by the sync loop, -1 if none. The coins of earlier blocks are
+	// not in the store.
+	ScanFromHeight int64                      `json:"scanFromHeight"`
+	Coins          map[string]*DemoCoinRecord `json:"coins"`
+}
+
+// DemoCoinBalance sums up the values of the coins of an account by status, in Neutrino.
+type DemoCoinBalance struct {
+	AccountSeqNo int   `json:"account"`
+	Unspent      int64 `json:"unspent"`
+	Pending      int64 `json:"pending"`
+	Spent        int64 `json:"spent"`
+	Coins        int   `json:"coins"`
+}
+
+func NewDemoCoinRecord(coin *core.Coin, accountSeqNo int) *DemoCoinRecord {
+	return &DemoCoinRecord{
+		ID:           coin.ID.String(),
+		AccountSeqNo: accountSeqNo,
+		Fingerprint:  coin.OwnerShortAddress.Fingerprint().HexString(),
+		TxHash:       coin.ID.TxHash.HexString(),
+		TxOutIndex:   coin.ID.Index,
+		Value:        coin.Value,
+		BlockHash:    coin.BlockHash.HexString(),
+		BlockHeight:  coin.BlockHeight,
+		SerialNumber: coin.SerialNumber.HexString(),
+		Status:       DemoCoinUnspent,
+	}
+}
+
+func LoadDemoCoinStore(path string) (*DemoCoinStore, error) {
+	store := &DemoCoinStore{path: path, ScannedHeight: -1, ScanFromHeight: -1, Coins: make(map[string]*DemoCoinRecord)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse coin store %s: %v", path, err)
+	}
+	if store.Coins == nil {
+		store.Coins = make(map[string]*DemoCoinRecord)
+	}
+
+	return store, nil
+}
+
+func (store *DemoCoinStore) Save() error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(store.path, data, 0644)
+}
+
+// Put adds a coin to the store. The status of a coin already in the store is kept.
+func (store *DemoCoinStore) Put(record *DemoCoinRecord) {
+	if existing, ok := store.Coins[record.ID]; ok {
+		record.Status = existing.Status
+		record.PendingTxid = existing.PendingTxid
+		record.SpentTxid = existing.SpentTxid
+		record.SpentHeight = existing.SpentHeight
+		if record.SerialNumber == "" {
+			record.SerialNumber = existing.SerialNumber
+		}
+	}
+	store.Coins[record.ID] = record
+}
+
+func (store *DemoCoinStore) FindBySerialNumber(serialNumber string) *DemoCoinRecord {
+	if serialNumber == "" {
+		return nil
+	}
+	for _, record := range store.Coins {
+		if record.SerialNumber == serialNumber {
+			return record
+		}
+	}
+	return nil
+}
+
+// MarkPending marks the coin with the given serial number as being spent by a tx not yet confirmed.
+// It returns the coin if its state changed, or nil otherwise.
+func (store *DemoCoinStore) MarkPending(serialNumber string, txid string) *DemoCoinRecord {
+	record := store.FindBySerialNumber(serialNumber)
+	if record == nil || record.Status != DemoCoinUnspent {
+		return nil
+	}
+	record.Status = DemoCoinPending
+	record.PendingTxid = txid
+	return record
+}
+
+// MarkSpent marks the coin with the given serial number as spent by a confirmed tx.
+// It returns the coin if its state changed, or nil otherwise.
+func (store *DemoCoinStore) MarkSpent(serialNumber string, txid string, height int64) *DemoCoinRecord {
+	record := store.FindBySerialNumber(serialNumber)
+	if record == nil || (record.Status == DemoCoinSpent && record.SpentTxid == txid) {
+		return nil
+	}
+	record.Status = DemoCoinSpent
+	record.PendingTxid = ""
+	record.SpentTxid = txid
+	record.SpentHeight = height
+	return record
+}
+
+// RevertSpent marks the coin with the given serial number, spent by a tx in a block replaced by a reorg, as being
+// spent by the given tx not yet confirmed. It returns the coin if its state changed, or nil otherwise.
+func (store *DemoCoinStore) RevertSpent(serialNumber string, spentTxid string, pendingTxid string) *DemoCoinRecord {
+	record := store.FindBySerialNumber(serialNumber)
+	if record == nil || record.Status != DemoCoinSpent || record.SpentTxid != spentTxid {
+		return nil
+	}
+	record.Status = DemoCoinPending
+	record.PendingTxid = pendingTxid
+	record.SpentTxid = ""
+	record.SpentHeight = 0
+	return record
+}
+
+// ReleasePending puts all coins pending on the given tx back to the unspent state.
+func (store *DemoCoinStore) ReleasePending(txid string) []*DemoCoinRecord {
+	released := make([]*DemoCoinRecord, 0)
+	for _, record := range store.SortedCoins() {
+		if record.Status == DemoCoinPending && record.PendingTxid == txid {
+			record.Status = DemoCoinUnspent
+			record.PendingTxid = ""
+			released = append(released, record)
+		}
+	}
+	return released
+}
+
+// SortedCoins returns all coins ordered by block height, tx hash and output index.
+func (store *DemoCoinStore) SortedCoins() []*DemoCoinRecord {
+	records := make([]*DemoCoinRecord, 0, len(store.Coins))
+	for _, record := range store.Coins {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].BlockHeight != records[j].BlockHeight {
+			return records[i].BlockHeight < records[j].BlockHeight
+		}
+		if records[i].TxHash != records[j].TxHash {
+			return records[i].TxHash < records[j].TxHash
+		}
+		return records[i].TxOutIndex < records[j].TxOutIndex
+	})
+	return records
+}
+
+// AccountCoins returns the coins of an account with the given status, or with any status if empty.
+func (store *DemoCoinStore) AccountCoins(accountSeqNo int, status DemoCoinStatus) []*DemoCoinRecord {
+	records := make([]*DemoCoinRecord, 0)
+	for _, record := range store.SortedCoins() {
+		if record.AccountSeqNo == accountSeqNo && (status == "" || record.Status == status) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Balances returns the balances of the given accounts, in the given order.
+func (store *DemoCoinStore) Balances(accountSeqNos []int) []*DemoCoinBalance {
+	balances := make([]*DemoCoinBalance, len(accountSeqNos))
+	for i, accountSeqNo := range accountSeqNos {
+		balance := &DemoCoinBalance{AccountSeqNo: accountSeqNo}
+		for _, record := range store.AccountCoins(accountSeqNo, "") {
+			switch record.Status {
+			case DemoCoinUnspent:
+				balance.Unspent += record.Value
+			case DemoCoinPending:
+				balance.Pending += record.Value
+			case DemoCoinSpent:
+				balance.Spent += record.Value
+			}
+			balance.Coins += 1
+		}
+		balances[i] = balance
+	}
+	return balances
+}
+
+func (ds *DemoSet) getDemoCoinStorePath() string {
+	return ds.getDemoFilePath(fmt.Sprintf("coins-chain-%d.json", ds.getDemoChainID()))
+}
+
+func (ds *DemoSet) getDemoCoinStore() (*DemoCoinStore, error) {
+	return LoadDemoCoinStore(ds.getDemoCoinStorePath())
+}
@@ -0,0 +1,763 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// Define data types and methods for the registered demo commands.
+//
+// Every demo is registered in demoCommands, which fixes the order of listings and of the golden
+// tests. A demo declares its flags on the given flag set and returns its body, which runs once the
+// args are parsed, so that `help <command>` and the shell completions can collect its flags without
+// running it. The body returns the error the demo ends on, which is printed at the single exit point
+// of the binary.
+type DemoCommand struct {
+	Name      string
+	Category  string
+	Summary   string
+	ArgsUsage string
+	NArgs     int // Number of positional args, -1 for any.
+	Examples  []string
+	Run       func(ds *DemoSet, flags *flag.FlagSet) func() error
+}
+
+const (
+	DemoCategoryBasic = "Basic"
+	DemoCategorySDK   = "SDK"
+)
+
+var demoCategories = []string{DemoCategoryBasic, DemoCategorySDK}
+
+var demoCommands []*DemoCommand
+
+// The commands are registered in init since the demos refer back to demoCommands when parsing args.
+func init() {
+	demoCommands = []*DemoCommand{
+		{
+			Name:      "ALOHA",
+			Category:  DemoCategoryBasic,
+			Summary:   "Say hi and show the demo config.",
+			ArgsUsage: "[name...]",
+			NArgs:     -1,
+			Examples:  []string{"ALOHA Abelian"},
+			Run:       (*DemoSet).DemoALOHA,
+		},
+		{
+			Name:     "BasicBytes",
+			Category: DemoCategoryBasic,
+			Summary:  "Create Bytes and convert them to other encodings.",
+			Run:      (*DemoSet).DemoBasicBytes,
+		},
+		{
+			Name:     "BasicAddress",
+			Category: DemoCategoryBasic,
+			Summary:  "Create and validate addresses and compare their fingerprints.",
+			Run:      (*DemoSet).DemoBasicAddress,
+		},
+		{
+			Name:     "BasicGenerateAccounts",
+			Category: DemoCategoryBasic,
+			Summary:  "Generate random accounts and save them to a file.",
+			Examples: []string{"BasicGenerateAccounts -count 2 -chainID 2"},
+			Run:      (*DemoSet).DemoBasicGenerateAccounts,
+		},
+		{
+			Name:     "BasicDemoAccounts",
+			Category: DemoCategoryBasic,
+			Summary:  "Load the demo accounts and dump their addresses.",
+			Run:      (*DemoSet).DemoBasicDemoAccounts,
+		},
+		{
+			Name:     "BasicChains",
+			Category: DemoCategoryBasic,
+			Summary:  "List the registered chains and check the demo account addresses against them.",
+			Run:      (*DemoSet).DemoBasicChains,
+		},
+		{
+			Name:     "BasicAbecRPCClient",
+			Category: DemoCategoryBasic,
+			Summary:  "Call the abec RPC client for chain info, blocks and txs.",
+			Run:      (*DemoSet).DemoBasicAbecRPCClient,
+		},
+		{
+			Name:     "BasicRPCEndpoints",
+			Category: DemoCategoryBasic,
+			Summary:  "Check the health of the configured RPC endpoints.",
+			Run:      (*DemoSet).DemoBasicRPCEndpoints,
+		},
+		{
+			Name:     "BasicRPCCache",
+			Category: DemoCategoryBasic,
+			Summary:  "Show or clear the cache of confirmed blocks and txs.",
+			Examples: []string{"BasicRPCCache -clear"},
+			Run:      (*DemoSet).DemoBasicRPCCache,
+		},
+		{
+			Name:     "BasicRPCCassette",
+			Category: DemoCategoryBasic,
+			Summary:  "Summarize a cassette of recorded RPC traffic.",
+			Examples: []string{"BasicRPCCassette -file rpc-cassette.jsonl"},
+			Run:      (*DemoSet).DemoBasicRPCCassette,
+		},
+		{
+			Name:     "BasicGenerateChainFixture",
+			Category: DemoCategoryBasic,
+			Summary:  "Generate a synthetic chain fixture paying the demo accounts.",
+			Examples: []string{"BasicGenerateChainFixture -blocks 30 -accounts 0,1,2,3"},
+			Run:      (*DemoSet).DemoBasicGenerateChainFixture,
+		},
+		{
+			Name:     "BasicMockAbecServer",
+			Category: DemoCategoryBasic,
+			Summary:  "Serve a chain fixture over the abec JSON-RPC interface.",
+			Examples: []string{
+				"BasicMockAbecServer -listen 127.0.0.1:18667",
+				"BasicMockAbecServer -record 100 -blockInterval 1m",
+			},
+			Run: (*DemoSet).DemoBasicMockAbecServer,
+		},
+		{
+			Name:     "SDKGetChainInfo",
+			Category: DemoCategorySDK,
+			Summary:  "Get the latest status of the chain.",
+			Run:      (*DemoSet).DemoSDKGetChainInfo,
+		},
+		{
+			Name:     "SDKGetMempool",
+			Category: DemoCategorySDK,
+			Summary:  "Get the txs in the mempool.",
+			Run:      (*DemoSet).DemoSDKGetMempool,
+		},
+		{
+			Name:     "SDKWatchMempool",
+			Category: DemoCategorySDK,
+			Summary:  "Watch the mempool for txs receiving or spending coins of the tracked accounts.",
+			Examples: []string{"SDKWatchMempool -accounts 0,1 -rounds 0 -interval 5s"},
+			Run:      (*DemoSet).DemoSDKWatchMempool,
+		},
+		{
+			Name:      "SDKGetBlockOrTx",
+			Category:  DemoCategorySDK,
+			Summary:   "Get a block by height or hash, or a tx by hash.",
+			ArgsUsage: "<height-or-hash>",
+			NArgs:     1,
+			Examples:  []string{"SDKGetBlockOrTx 1"},
+			Run:       (*DemoSet).DemoSDKGetBlockOrTx,
+		},
+		{
+			Name:      "SDKGetMemoInTx",
+			Category:  DemoCategorySDK,
+			Summary:   "Get the memo of a tx.",
+			ArgsUsage: "<transaction-hash>",
+			NArgs:     1,
+			Run:       (*DemoSet).DemoSDKGetMemoInTx,
+		},
+		{
+			Name:     "SDKGenerateCryptoKeysAndAddress",
+			Category: DemoCategorySDK,
+			Summary:  "Generate crypto keys and address from crypto seeds.",
+			Run:      (*DemoSet).DemoSDKGenerateCryptoKeysAndAddress,
+		},
+		{
+			Name:     "SDKGenerateAddresses",
+			Category: DemoCategorySDK,
+			Summary:  "Generate abel addresses and derive the other types of addresses.",
+			Examples: []string{"SDKGenerateAddresses -chainID 0"},
+			Run:      (*DemoSet).DemoSDKGenerateAddresses,
+		},
+		{
+			Name:     "SDKTrackCoins",
+			Category: DemoCategorySDK,
+			Summary:  "Scan blocks for coins of the tracked accounts and track their spending.",
+			Examples: []string{"SDKTrackCoins -accounts 0,1 -txosHeightRange 0,100 -trackHeightRange 0,200"},
+			Run:      (*DemoSet).DemoSDKTrackCoins,
+		},
+		{
+			Name:     "SDKMakeUnsignedRawTx",
+			Category: DemoCategorySDK,
+			Summary:  "Make an unsigned raw tx spending coins of the sender accounts.",
+			Examples: []string{"SDKMakeUnsignedRawTx -senders 1 -receivers 2,3 -outputFile unsigned-raw-tx.json"},
+			Run:      (*DemoSet).DemoSDKMakeUnsignedRawTx,
+		},
+		{
+			Name:     "SDKMakeUnsignedRawTxWithMemo",
+			Category: DemoCategorySDK,
+			Summary:  "Make an unsigned raw tx with a hex encoded memo.",
+			Examples: []string{"SDKMakeUnsignedRawTxWithMemo -senders 1 -receivers 2 -memo 68656c6c6f"},
+			Run:      (*DemoSet).DemoSDKMakeUnsignedRawTxWithMemo,
+		},
+		{
+			Name:     "SDKMakeSignedRawTx",
+			Category: DemoCategorySDK,
+			Summary:  "Sign an unsigned raw tx with the keys of the sender accounts.",
+			Examples: []string{"SDKMakeSignedRawTx -senders 1 -inputFile unsigned-raw-tx.json -outputFile signed-raw-tx.json"},
+			Run:      (*DemoSet).DemoSDKMakeSignedRawTx,
+		},
+		{
+			Name:     "SDKSubmitSignedRawTx",
+			Category: DemoCategorySDK,
+			Summary:  "Submit a signed raw tx and record it in the outbox.",
+			Examples: []string{"SDKSubmitSignedRawTx -inputFile signed-raw-tx.json -wait -confirmations 6"},
+			Run:      (*DemoSet).DemoSDKSubmitSignedRawTx,
+		},
+		{
+			Name:     "SDKListOutbox",
+			Category: DemoCategorySDK,
+			Summary:  "List the submitted txs in the outbox.",
+			Run:      (*DemoSet).DemoSDKListOutbox,
+		},
+		{
+			Name:     "SDKRebroadcastTxs",
+			Category: DemoCategorySDK,
+			Summary:  "Update the outbox from new blocks and rebroadcast the pending txs.",
+			Examples: []string{"SDKRebroadcastTxs -rounds 0 -interval 5m"},
+			Run:      (*DemoSet).DemoSDKRebroadcastTxs,
+		},
+		{
+			Name:      "SDKAbandonTx",
+			Category:  DemoCategorySDK,
+			Summary:   "Abandon a pending tx of the outbox and release its coins.",
+			ArgsUsage: "<transaction-hash>",
+			NArgs:     1,
+			Run:       (*DemoSet).DemoSDKAbandonTx,
+		},
+		{
+			Name:     "SDKAccountReport",
+			Category: DemoCategorySDK,
+			Summary:  "Report the balances of the accounts and the ledgers of their receives and spends.",
+			Examples: []string{"SDKAccountReport -accounts 3", "SDKAccountReport -ledger=false"},
+			Run:      (*DemoSet).DemoSDKAccountReport,
+		},
+		{
+			Name:     "SDKExportHistory",
+			Category: DemoCategorySDK,
+			Summary:  "Export the history of the accounts as CSV or OFX for accounting tools.",
+			Examples: []string{"SDKExportHistory -format csv -accounts 1,2 -from 2024-01-01 -to 2024-01-31", "SDKExportHistory -format ofx -fromHeight 5000 -toHeight 6000"},
+			Run:      (*DemoSet).DemoSDKExportHistory,
+		},
+		{
+			Name:     "SDKBalanceSnapshot",
+			Category: DemoCategorySDK,
+			Summary:  "Compute the balances of the accounts at a past height or time and sign the report.",
+			Examples: []string{"SDKBalanceSnapshot -height 5000", "SDKBalanceSnapshot -time 2024-01-01T00:00:00Z -accounts 1,2"},
+			Run:      (*DemoSet).DemoSDKBalanceSnapshot,
+		},
+		{
+			Name:      "SDKVerifySnapshot",
+			Category:  DemoCategorySDK,
+			Summary:   "Verify the signature of a balance snapshot.",
+			ArgsUsage: "<snapshot-file>",
+			NArgs:     1,
+			Examples:  []string{"SDKVerifySnapshot snapshot-5000.json"},
+			Run:       (*DemoSet).DemoSDKVerifySnapshot,
+		},
+		{
+			Name:      "SDKDepositAddress",
+			Category:  DemoCategorySDK,
+			Summary:   "Allocate the deposit address of a customer from the master mnemonic.",
+			ArgsUsage: "<customer-id>",
+			NArgs:     1,
+			Examples:  []string{"SDKDepositAddress customer-42"},
+			Run:       (*DemoSet).DemoSDKDepositAddress,
+		},
+		{
+			Name:     "SDKScanDeposits",
+			Category: DemoCategorySDK,
+			Summary:  "Scan blocks for deposits to the customer addresses and credit the confirmed ones.",
+			Examples: []string{"SDKScanDeposits -fromHeight 5100 -confirmations 6 -rounds 0 -interval 1m"},
+			Run:      (*DemoSet).DemoSDKScanDeposits,
+		},
+		{
+			Name:     "SDKListDeposits",
+			Category: DemoCategorySDK,
+			Summary:  "List the customers and their deposits.",
+			Examples: []string{"SDKListDeposits -customer customer-42"},
+			Run:      (*DemoSet).DemoSDKListDeposits,
+		},
+		{
+			Name:     "SDKRequestWithdrawal",
+			Category: DemoCategorySDK,
+			Summary:  "Queue a withdrawal, held for approval or rejected as the withdrawal policy decides.",
+			Examples: []string{"SDKRequestWithdrawal -account 2 -value 100000000"},
+			Run:      (*DemoSet).DemoSDKRequestWithdrawal,
+		},
+		{
+			Name:      "SDKApproveWithdrawal",
+			Category:  DemoCategorySDK,
+			Summary:   "Approve or reject a held withdrawal.",
+			ArgsUsage: "<withdrawal-id>",
+			NArgs:     1,
+			Examples:  []string{"SDKApproveWithdrawal w000001", "SDKApproveWithdrawal -reject -reason 'unknown customer' w000001"},
+			Run:       (*DemoSet).DemoSDKApproveWithdrawal,
+		},
+		{
+			Name:     "SDKProcessWithdrawals",
+			Category: DemoCategorySDK,
+			Summary:  "Pay the queued withdrawals from the hot accounts in batches checked by the withdrawal policy.",
+			Examples: []string{"SDKProcessWithdrawals -hotAccounts 0 -rounds 0 -interval 1m"},
+			Run:      (*DemoSet).DemoSDKProcessWithdrawals,
+		},
+		{
+			Name:     "SDKListWithdrawals",
+			Category: DemoCategorySDK,
+			Summary:  "List the withdrawals and their states.",
+			Examples: []string{"SDKListWithdrawals -state held"},
+			Run:      (*DemoSet).DemoSDKListWithdrawals,
+		},
+		{
+			Name:     "SDKDeliverWebhooks",
+			Category: DemoCategorySDK,
+			Summary:  "Deliver the queued webhook events and list the delivery log.",
+			Examples: []string{"SDKDeliverWebhooks -rounds 0 -interval 1m"},
+			Run:      (*DemoSet).DemoSDKDeliverWebhooks,
+		},
+		{
+			Name:     "SDKWalletServer",
+			Category: DemoCategorySDK,
+			Summary:  "Serve a wallet HTTP JSON API over the tracked accounts, syncing the coin store in the background.",
+			Examples: []string{
+				"SDKWalletServer -listen 127.0.0.1:18680 -fromHeight 0 -syncInterval 10s",
+				"SDKWalletServer -grpcListen 127.0.0.1:18681",
+			},
+			Run: (*DemoSet).DemoSDKWalletServer,
+		},
+		{
+			Name:     "SDKGenerateRandomMnemonic",
+			Category: DemoCategorySDK,
+			Summary:  "Generate a random mnemonic.",
+			Run:      (*DemoSet).DemoSDKGenerateRandomMnemonic,
+		},
+		{
+			Name:     "SDKGenerateCryptoSeedFromMnemonic",
+			Category: DemoCategorySDK,
+			Summary:  "Generate a crypto seed from a mnemonic and a sequence number.",
+			Run:      (*DemoSet).DemoSDKGenerateCryptoSeedFromMnemonic,
+		},
+	}
+}
+
+func FindDemoCommand(name string) *DemoCommand {
+	for _, command := range demoCommands {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+func GetAllDemoNames() []string {
+	names := make([]string, 0, len(demoCommands))
+	for _, command := range demoCommands {
+		names = append(names, command.Name)
+	}
+	return names
+}
+
+// RunDemo runs the demo of the given name, returning the error it ends on, a usage error if there is none.
+func RunDemo(name string, args []string) (err error) {
+	command := FindDemoCommand(name)
+	if command == nil {
+		return newDemoError(DemoErrorUsage, "unknown command %s", name)
+	}
+	ds.command = name
+	defer demoRecoverPanic(&err)
+	defer closeDemoRPCRecorders()
+	if err := ds.demoInit(); err != nil {
+		return err
+	}
+	if err := ds.demoLoadConfig(); err != nil {
+		return err
+	}
+	ds.demoBegin(name, args)
+	flags := ds.demoFlagSet(name)
+	run := command.Run(ds, flags)
+	if err := ds.demoParseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	ds.demoEnd(name)
+	return nil
+}
+
+// demoPrintedError is an error already printed along with the usage, which is only written in json mode at
+// the exit.
+type demoPrintedError struct {
+	err error
+}
+
+func (e *demoPrintedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *demoPrintedError) Unwrap() error {
+	return e.err
+}
+
+// demoRecoverPanic is deferred by the top-level handlers to turn a panic, e.g. a nil dereference, into an
+// internal error reported along with its stack trace.
+func demoRecoverPanic(err *error) {
+	if r := recover(); r != nil {
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s\n", r, debug.Stack())
+		*err = newDemoError(DemoErrorInternal, "%v", r)
+	}
+}
+
+// demoExit is the single exit point of the binary. It prints the error a command ends on, unless it was
+// printed along with the usage, and returns the exit code of its kind, 0 if none or if the help was asked.
+func (ds *DemoSet) demoExit(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	var printedErr *demoPrintedError
+	if !errors.As(err, &printedErr) {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Printf("\n")
+		fmt.Printf("🛑 Demo exits on error.\n")
+		fmt.Printf("================================================================================\n")
+	}
+	ds.demoWriteError(demoErrorCode(err), err)
+	return demoExitCode(err)
+}
+
+// demoProgramName is the name of the demo binary as invoked, for usage and completion scripts.
+func demoProgramName() string {
+	return filepath.Base(os.Args[0])
+}
+
+// demoFlagSet creates the flag set of a demo, printing the command help on -h.
+func (ds *DemoSet) demoFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.Usage = func() {
+		command := FindDemoCommand(name)
+		if command == nil {
+			command = &DemoCommand{Name: name, NArgs: -1}
+		}
+		printDemoCommandHelp(command, flags)
+	}
+	return flags
+}
+
+// demoParseFlags parses the args of a demo the same way for all demos: -h prints the command help
+// and ends it with flag.ErrHelp, exiting with 0, while bad flags or a wrong number of positional args
+// print the error and the command help and end it with a usage error.
+func (ds *DemoSet) demoParseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return err
+	}
+	if err != nil {
+		// The flag set already printed the error and the usage.
+		return &demoPrintedError{err: &DemoError{Kind: DemoErrorUsage, Err: err}}
+	}
+
+	command := FindDemoCommand(flags.Name())
+	if command != nil && command.NArgs >= 0 && flags.NArg() != command.NArgs {
+		err := fmt.Errorf("%s expects %d args, got %d", command.Name, command.NArgs, flags.NArg())
+		fmt.Printf("Error: %v\n", err)
+		flags.Usage()
+		return &demoPrintedError{err: &DemoError{Kind: DemoErrorUsage, Err: err}}
+	}
+	return nil
+}
+
+// getDemoAccountsBySeqnos returns the demo accounts given by a flag of comma separated seqnos, e.g. -senders.
+func (ds *DemoSet) getDemoAccountsBySeqnos(name string, value string) ([]*DemoAccount, error) {
+	seqnos, err := parseDemoSeqnos(name, value)
+	if err != nil {
+		return nil, err
+	}
+	demoAccounts, err := ds.getDemoAccounts()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]*DemoAccount, len(seqnos))
+	for i, seqno := range seqnos {
+		account, ok := demoAccounts[seqno]
+		if !ok {
+			return nil, newDemoError(DemoErrorValidation, "demo account %d of %s does not exist for chain %d", seqno, name, ds.getDemoChainID())
+		}
+		accounts[i] = account
+	}
+	return accounts, nil
+}
+
+// collectDemoFlags returns the flag set of a demo as declared by it, without running its body.
+func collectDemoFlags(command *DemoCommand) *flag.FlagSet {
+	ds.collectingFlags = true
+	defer func() {
+		ds.collectingFlags = false
+	}()
+
+	flags := ds.demoFlagSet(command.Name)
+	command.Run(ds, flags)
+	return flags
+}
+
+func printDemoCommandHelp(command *DemoCommand, flags *flag.FlagSet) {
+	hasFlags := false
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+
+	usage := fmt.Sprintf("%s %s", demoProgramName(), command.Name)
+	if hasFlags {
+		usage += " [flags]"
+	}
+	if command.ArgsUsage != "" {
+		usage += " " + command.ArgsUsage
+	}
+	fmt.Printf("Usage: %s\n", usage)
+	if command.Summary != "" {
+		fmt.Printf("\n%s\n", command.Summary)
+	}
+	if hasFlags {
+		fmt.Printf("\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if len(command.Examples) > 0 {
+		fmt.Printf("\nExamples:\n")
+		for _, example := range command.Examples {
+			fmt.Printf("  %s %s\n", demoProgramName(), example)
+		}
+	}
+}
+
+func printDemoUsage() {
+	fmt.Printf("Usage: %s [global flags] <command> [flags] [args...]\n", demoProgramName())
+	fmt.Printf("       %s help <command>\n", demoProgramName())
+	fmt.Printf("       %s completion bash|zsh|fish\n", demoProgramName())
+	fmt.Printf("       %s config show|set|validate\n", demoProgramName())
+
+	width := 0
+	for _, command := range demoCommands {
+		if len(command.Name) > width {
+			width = len(command.Name)
+		}
+	}
+	for _, category := range demoCategories {
+		fmt.Printf("\n%s commands:\n", category)
+		for _, command := range demoCommands {
+			if command.Category == category {
+				fmt.Printf("  %-*s  %s\n", width, command.Name, command.Summary)
+			}
+		}
+	}
+	fmt.Printf("\nGlobal flags:\n")
+	fmt.Printf("  --output text|json  Print a single json result object to stdout instead of the text output,\n")
+	fmt.Printf("                      defaults to $ABELSDK_DEMO_OUTPUT or text.\n")
+	fmt.Printf("  --profile name      Apply a config profile (%s), defaults to $ABELSDK_PROFILE.\n", strings.Join(getDemoConfigProfileNames(), ", "))
+	fmt.Printf("  --set key=value     Override a config value, may be repeated.\n")
+	fmt.Printf("  --reveal-secrets    Print mnemonics, seeds, secret keys and RPC credentials instead of <redacted>.\n")
+	fmt.Printf("\nRun '%s help <command>' for the flags and examples of a command.\n", demoProgramName())
+}
+
+// parseDemoGlobalFlags parses the global flags before the command name and returns the rest of the args.
+func parseDemoGlobalFlags(args []string) ([]string, error) {
+	format := os.Getenv("ABELSDK_DEMO_OUTPUT")
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if name == "reveal-secrets" && !hasValue {
+			demoRevealSecrets = true
+			args = args[1:]
+			continue
+		}
+		if name != "output" && name != "o" && name != "profile" && name != "set" {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag needs an argument: %s", args[0])
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		args = args[1:]
+
+		switch name {
+		case "output", "o":
+			format = value
+		case "profile":
+			demoConfigProfileFlag = value
+		case "set":
+			key, keyValue, ok := strings.Cut(value, "=")
+			if !ok {
+				return nil, fmt.Errorf("--set %s is not of the form key=value", value)
+			}
+			demoConfigSetFlags[key] = keyValue
+		}
+	}
+
+	if format == "" {
+		format = DemoOutputText
+	}
+	return args, ds.setDemoOutputFormat(format)
+}
+
+// DemoHelp prints the usage of the demo binary, or the help of the given command.
+func DemoHelp(args []string) error {
+	if len(args) == 0 {
+		printDemoUsage()
+		return nil
+	}
+
+	command := FindDemoCommand(args[0])
+	if command == nil {
+		fmt.Printf("Unknown command: %s\n\n", args[0])
+		printDemoUsage()
+		return &demoPrintedError{err: newDemoError(DemoErrorUsage, "unknown command %s", args[0])}
+	}
+	printDemoCommandHelp(command, collectDemoFlags(command))
+	return nil
+}
+
+// demoCommandFlags returns the flags of every command, for the completion scripts.
+func demoCommandFlags() map[string][]*flag.Flag {
+	commandFlags := make(map[string][]*flag.Flag)
+	for _, command := range demoCommands {
+		collectDemoFlags(command).VisitAll(func(f *flag.Flag) {
+			commandFlags[command.Name] = append(commandFlags[command.Name], f)
+		})
+	}
+	return commandFlags
+}
+
+// isDemoBoolFlag tells whether a flag takes no value, as the flag package does.
+func isDemoBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// DemoCompletion prints the completion script for the given shell.
+func DemoCompletion(args []string) error {
+	if len(args) != 1 {
+		fmt.Printf("Usage: %s completion bash|zsh|fish\n", demoProgramName())
+		return &demoPrintedError{err: newDemoError(DemoErrorUsage, "completion expects 1 arg, got %d", len(args))}
+	}
+
+	switch args[0] {
+	case "bash":
+		printDemoBashCompletion()
+	case "zsh":
+		printDemoZshCompletion()
+	case "fish":
+		printDemoFishCompletion()
+	default:
+		fmt.Printf("Unsupported shell: %s, expected bash, zsh or fish\n", args[0])
+		return &demoPrintedError{err: newDemoError(DemoErrorUsage, "unsupported shell %s", args[0])}
+	}
+	return nil
+}
+
+func printDemoBashCompletion() {
+	program := demoProgramName()
+	function := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(program)
+	commandFlags := demoCommandFlags()
+	names := strings.Join(GetAllDemoNames(), " ")
+
+	fmt.Printf("# bash completion for %s, load it with: source <(%s completion bash)\n", program, program)
+	fmt.Printf("%s() {\n", function)
+	fmt.Printf("  local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Printf("  if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Printf("    COMPREPLY=($(compgen -W \"help completion config %s\" -- \"$cur\"))\n", names)
+	fmt.Printf("    return\n")
+	fmt.Printf("  fi\n")
+	fmt.Printf("  case \"${COMP_WORDS[1]}\" in\n")
+	fmt.Printf("    help) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", names)
+	fmt.Printf("    completion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")) ;;\n")
+	fmt.Printf("    config) COMPREPLY=($(compgen -W \"show set validate\" -- \"$cur\")) ;;\n")
+	for _, command := range demoCommands {
+		var words []string
+		for _, f := range commandFlags[command.Name] {
+			words = append(words, "-"+f.Name)
+		}
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Printf("    %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", command.Name, strings.Join(words, " "))
+	}
+	fmt.Printf("  esac\n")
+	fmt.Printf("}\n")
+	fmt.Printf("complete -o default -F %s %s\n", function, program)
+}
+
+func printDemoZshCompletion() {
+	program := demoProgramName()
+	function := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(program)
+	commandFlags := demoCommandFlags()
+	// Escape the characters with a special meaning in _describe and _arguments specs.
+	escape := strings.NewReplacer(`'`, `'\''`, `:`, `\:`, `[`, `\[`, `]`, `\]`)
+
+	fmt.Printf("#compdef %s\n", program)
+	fmt.Printf("# zsh completion for %s, load it with: source <(%s completion zsh)\n", program, program)
+	fmt.Printf("%s() {\n", function)
+	fmt.Printf("  local -a commands\n")
+	fmt.Printf("  commands=(\n")
+	fmt.Printf("    'help:Show the help of a command.'\n")
+	fmt.Printf("    'completion:Print a shell completion script.'\n")
+	fmt.Printf("    'config:Show, set or validate the demo config.'\n")
+	for _, command := range demoCommands {
+		fmt.Printf("    '%s:%s'\n", command.Name, escape.Replace(command.Summary))
+	}
+	fmt.Printf("  )\n")
+	fmt.Printf("  if (( CURRENT == 2 )); then\n")
+	fmt.Printf("    _describe 'command' commands\n")
+	fmt.Printf("    return\n")
+	fmt.Printf("  fi\n")
+	fmt.Printf("  case ${words[2]} in\n")
+	fmt.Printf("    help) _describe 'command' commands ;;\n")
+	fmt.Printf("    completion) _values 'shell' bash zsh fish ;;\n")
+	fmt.Printf("    config) _values 'config command' show set validate ;;\n")
+	for _, command := range demoCommands {
+		var specs []string
+		for _, f := range commandFlags[command.Name] {
+			spec := fmt.Sprintf("'-%s[%s]", f.Name, escape.Replace(f.Usage))
+			if !isDemoBoolFlag(f) {
+				spec += ":value:_files"
+			}
+			specs = append(specs, spec+"'")
+		}
+		if len(specs) == 0 {
+			continue
+		}
+		fmt.Printf("    %s) _arguments %s ;;\n", command.Name, strings.Join(specs, " "))
+	}
+	fmt.Printf("  esac\n")
+	fmt.Printf("}\n")
+	fmt.Printf("compdef %s %s\n", function, program)
+}
+
+func printDemoFishCompletion() {
+	program := demoProgramName()
+	commandFlags := demoCommandFlags()
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	fmt.Printf("# fish completion for %s, load it with: %s completion fish | source\n", program, program)
+	fmt.Printf("complete -c %s -f\n", program)
+	fmt.Printf("complete -c %s -n __fish_use_subcommand -a help -d 'Show the help of a command.'\n", program)
+	fmt.Printf("complete -c %s -n __fish_use_subcommand -a completion -d 'Print a shell completion script.'\n", program)
+	fmt.Printf("complete -c %s -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n", program)
+	fmt.Printf("complete -c %s -n __fish_use_subcommand -a config -d 'Show, set or validate the demo config.'\n", program)
+	fmt.Printf("complete -c %s -n '__fish_seen_subcommand_from config' -a 'show set validate'\n", program)
+	fmt.Printf("complete -c %s -n '__fish_seen_subcommand_from help' -a '%s'\n", program, strings.Join(GetAllDemoNames(), " "))
+	for _, command := range demoCommands {
+		fmt.Printf("complete -c %s -n __fish_use_subcommand -a %s -d '%s'\n", program, command.Name, escape.Replace(command.Summary))
+		for _, f := range commandFlags[command.Name] {
+			option := "-o " + f.Name
+			if !isDemoBoolFlag(f) {
+				option += " -r -F"
+			}
+			fmt.Printf("complete -c %s -n '__fish_seen_subcommand_from %s' %s -d '%s'\n", program, command.Name, option, escape.Replace(f.Usage))
+		}
+	}
+}
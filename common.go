@@ -1,3 +1,7 @@
+//go:build demo || test
+
+// +build: demo test
+
 package main
 
 /*
@@ -12,11 +16,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
-	"reflect"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unsafe"
 
 	core "abelian.info/sdk/core"
@@ -33,14 +37,6 @@ func contains[T string | byte | int | int32 | int64](slice []T, v T) bool {
 	return false
 }
 
-func atoi(s string) int {
-	i, err := strconv.Atoi(s)
-	if err != nil {
-		panic(err)
-	}
-	return i
-}
-
 // Define functions for packing output data.
 func packToOutData(data []byte, outData []byte) error {
 	size := len(data)
@@ -73,26 +69,28 @@ type DemoAccountResource struct {
 		Mnemonics     string `json:"mnemonic_list"`
 	} `json:"info"`
 
-	AccountInfos []struct {
-		SerialNo             int    `json:"No."`
-		CryptoSeedHex        string `json:"CryptoSeed"`
-		CryptoAddressHex     string `json:"CryptoAddress"`
-		SpendSecretKeyHex    string `json:"SerializedASksp"`
-		SerialNoSecretKeyHex string `json:"SerializedASksn"`
-		ViewSecretKeyHex     string `json:"SerializedVSk"`
-	} `json:"addresses"`
+	AccountInfos []*DemoAccountInfo `json:"addresses"`
+}
+
+type DemoAccountInfo struct {
+	SerialNo             int    `json:"No."`
+	CryptoSeedHex        string `json:"CryptoSeed"`
+	CryptoAddressHex     string `json:"CryptoAddress"`
+	SpendSecretKeyHex    string `json:"SerializedASksp"`
+	SerialNoSecretKeyHex string `json:"SerializedASksn"`
+	ViewSecretKeyHex     string `json:"SerializedVSk"`
 }
 
 type DemoAccount struct {
 	ChainID           int8
 	ChainName         string
 	CryptoVersion     int
-	Mnemonics         string
+	Mnemonics         DemoSecret
 	SerialNo          int
-	CryptoSeed        core.Bytes
-	SpendSecretKey    *core.CryptoKey
-	SerialNoSecretKey *core.CryptoKey
-	ViewSecretKey     *core.CryptoKey
+	CryptoSeed        DemoSecretBytes
+	SpendSecretKey    DemoSecretKey
+	SerialNoSecretKey DemoSecretKey
+	ViewSecretKey     DemoSecretKey
 	CryptoAddress     *core.CryptoAddress
 	CoinAddress       *core.CoinAddress
 	AbelAddress       *core.AbelAddress
@@ -101,81 +99,97 @@ type DemoAccount struct {
 }
 
 // Define data types and methods for demo.
-type DemoSet struct{}
+type DemoSet struct {
+	collectingFlags bool
 
-var ds *DemoSet = &DemoSet{}
-
-func GetAllDemoNames() []string {
-	var names []string
-	demoSet := reflect.TypeOf(ds)
-	for i := 0; i < demoSet.NumMethod(); i++ {
-		name := demoSet.Method(i).Name
-		if name[:4] == "Demo" {
-			names = append(names, name[4:])
-		}
-	}
-	return names
+	outputFormat string
+	stdout       *os.File
+	command      string
+	result       map[string]interface{}
 }
 
-func RunDemo(name string, args []string) {
-	demoFunc := reflect.ValueOf(ds).MethodByName("Demo" + name)
-	if !demoFunc.IsValid() {
-		panic(fmt.Errorf("demo %s is not found", name))
-	}
-	ds.demoInit()
-	ds.demoBegin(name, args)
-	demoFunc.Call([]reflect.Value{reflect.ValueOf(args)})
-	ds.demoEnd(name)
-}
+var ds *DemoSet = &DemoSet{}
+
+func (*DemoSet) demoInit() error {
+	ds.demoRedirectOutput()
 
-func (*DemoSet) demoInit() {
 	envDir := ds.getDemoEnvPath()
 	if _, err := os.Stat(envDir); os.IsNotExist(err) {
 		// Create the demo env directory.
 		err = os.MkdirAll(envDir, 0755)
-		ds.demoExitOnError(err)
+		if err != nil {
+			return err
+		}
 
 		// Copy the the resources dir to the demo env directory.
 		srcDir := "resources"
 		dstDir := fmt.Sprintf("%s/.%s", envDir, srcDir)
 		err = os.Mkdir(dstDir, 0755)
-		ds.demoCheck(err)
+		if err != nil {
+			return err
+		}
 		entries, err := demoResources.ReadDir(srcDir)
-		ds.demoCheck(err)
+		if err != nil {
+			return err
+		}
 		for _, entry := range entries {
 			srcPath := fmt.Sprintf("%s/%s", srcDir, entry.Name())
 			srcFile, err := demoResources.Open(srcPath)
-			ds.demoCheck(err)
+			if err != nil {
+				return err
+			}
 			defer srcFile.Close()
 
 			dstPath := fmt.Sprintf("%s/%s", dstDir, entry.Name())
 			dstFile, err := os.Create(dstPath)
-			ds.demoCheck(err)
+			if err != nil {
+				return err
+			}
 			defer dstFile.Close()
 
 			_, err = io.Copy(dstFile, srcFile)
-			ds.demoCheck(err)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	// Create the demo config file if it does not exist.
+	// Create the demo config file if it does not exist, from the bundled config of the chain selected by
+	// the profile, ABELSDK_CHAINID or --set chainID=<id>, and of the testnet otherwise.
 	configPath := ds.getDemoConfigPath()
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		srcPath := fmt.Sprintf("%s/.%s/config-chain-2.json", envDir, "resources")
-		srcFile, err := os.Open(srcPath)
-		ds.demoExitOnError(err)
-		defer srcFile.Close()
-
-		dstFile, err := os.Create(configPath)
-		ds.demoExitOnError(err)
-		defer dstFile.Close()
+		config, _, err := ds.loadDemoConfig()
+		if err != nil {
+			return err
+		}
+		chainConfig, err := loadDemoBundledChainConfig(config)
+		if err != nil {
+			return err
+		}
+		return writeDemoConfigFile(configPath, chainConfig)
+	}
+	return nil
+}
 
-		_, err = io.Copy(dstFile, srcFile)
-		ds.demoExitOnError(err)
+// loadDemoBundledChainConfig returns the bundled config of the chain given by the config, the testnet if none.
+func loadDemoBundledChainConfig(config map[string]string) (map[string]string, error) {
+	chain := findDemoChain(DemoChainTestnet)
+	if config["chainID"] != "" {
+		chainID, registered, err := parseDemoChainID(config["chainID"])
+		if err != nil {
+			return nil, err
+		}
+		if registered == nil {
+			return nil, newDemoError(DemoErrorValidation, "no bundled config for chain %d, expecting one of %s",
+				chainID, strings.Join(getDemoChainNames(), ", "))
+		}
+		chain = registered
 	}
+	return chain.Profile().LoadValues()
 }
 
 func (*DemoSet) demoBegin(name string, args []string) {
+	ds.command = name
 	fmt.Printf("================================================================================\n")
 	fmt.Printf("📀 Demo%s begins.\n", name)
 	fmt.Printf("chainID: %v, args: %v\n", ds.getDemoChainID(), args)
@@ -185,6 +199,9 @@ func (*DemoSet) demoEnd(name string) {
 	fmt.Printf("\n")
 	fmt.Printf("📀 Demo%s ends.\n", name)
 	fmt.Printf("================================================================================\n")
+	if ds.isDemoJSONOutput() {
+		ds.demoWriteOutput(&DemoOutput{Command: name, OK: true, Result: ds.result})
+	}
 }
 
 func (*DemoSet) demoCase(format string, v ...interface{}) {
@@ -195,20 +212,168 @@ func (*DemoSet) demoBadCase(format string, v ...interface{}) {
 	fmt.Printf("\n❌ "+format+"\n", v...)
 }
 
-func (*DemoSet) demoCheck(err error) {
-	if err != nil {
-		panic(err)
+// Define typed errors of demos, each kind exiting with its own exit code.
+type DemoErrorKind int
+
+const (
+	DemoErrorGeneric DemoErrorKind = iota
+	DemoErrorUsage
+	DemoErrorRPC
+	DemoErrorInsufficientFunds
+	DemoErrorValidation
+	DemoErrorInternal
+)
+
+func (kind DemoErrorKind) String() string {
+	switch kind {
+	case DemoErrorUsage:
+		return "usage"
+	case DemoErrorRPC:
+		return "rpc"
+	case DemoErrorInsufficientFunds:
+		return "insufficient_funds"
+	case DemoErrorValidation:
+		return "validation"
+	case DemoErrorInternal:
+		return "internal"
+	default:
+		return "error"
 	}
 }
 
-func (ds *DemoSet) demoExitOnError(err error) {
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		fmt.Printf("\n")
-		fmt.Printf("🛑 Demo exits on error.\n")
-		fmt.Printf("================================================================================\n")
-		os.Exit(1)
+func (kind DemoErrorKind) ExitCode() int {
+	switch kind {
+	case DemoErrorUsage:
+		return 2
+	case DemoErrorRPC:
+		return 3
+	case DemoErrorInsufficientFunds:
+		return 4
+	case DemoErrorValidation:
+		return 5
+	default:
+		return 1
+	}
+}
+
+type DemoError struct {
+	Kind DemoErrorKind
+	Err  error
+}
+
+func newDemoError(kind DemoErrorKind, format string, v ...interface{}) *DemoError {
+	return &DemoError{Kind: kind, Err: fmt.Errorf(format, v...)}
+}
+
+func (e *DemoError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DemoError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DemoError) DemoErrorKind() DemoErrorKind {
+	return e.Kind
+}
+
+func (e *DemoError) DemoErrorCode() string {
+	return e.Kind.String()
+}
+
+// demoErrorKinder is implemented by errors of a known kind, e.g. DemoError and DemoRPCError.
+type demoErrorKinder interface {
+	DemoErrorKind() DemoErrorKind
+}
+
+// demoExitCode returns the exit code of the demo binary for an error.
+func demoExitCode(err error) int {
+	var kinder demoErrorKinder
+	if errors.As(err, &kinder) {
+		return kinder.DemoErrorKind().ExitCode()
+	}
+	return DemoErrorGeneric.ExitCode()
+}
+
+// Define the output formats of demos.
+//
+// The text format is the human readable output printed along the way. In the json format, the same
+// output goes to stderr and each demo prints a single DemoOutput object to stdout when it ends,
+// holding the values it reported with demoResult, or the error it exits on.
+const (
+	DemoOutputText = "text"
+	DemoOutputJSON = "json"
+)
+
+type DemoOutput struct {
+	Command string                 `json:"command"`
+	OK      bool                   `json:"ok"`
+	Result  map[string]interface{} `json:"result,omitempty"`
+	Error   *DemoOutputError       `json:"error,omitempty"`
+}
+
+type DemoOutputError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// demoErrorCoder is implemented by errors which carry their own code for the json output.
+type demoErrorCoder interface {
+	DemoErrorCode() string
+}
+
+// demoErrorCode returns the code of an error for the json output.
+func demoErrorCode(err error) string {
+	var coder demoErrorCoder
+	if errors.As(err, &coder) {
+		return coder.DemoErrorCode()
+	}
+	return DemoErrorGeneric.String()
+}
+
+func (ds *DemoSet) setDemoOutputFormat(format string) error {
+	if format != DemoOutputText && format != DemoOutputJSON {
+		return fmt.Errorf("unknown output format %q, expecting %s or %s", format, DemoOutputText, DemoOutputJSON)
+	}
+	ds.outputFormat = format
+	return nil
+}
+
+func (ds *DemoSet) isDemoJSONOutput() bool {
+	return ds.outputFormat == DemoOutputJSON
+}
+
+// demoRedirectOutput sends the text output to stderr in json mode, leaving stdout to the json output.
+func (ds *DemoSet) demoRedirectOutput() {
+	if ds.isDemoJSONOutput() && ds.stdout == nil {
+		ds.stdout = os.Stdout
+		os.Stdout = os.Stderr
+	}
+}
+
+// demoResult sets a value of the result object printed in json mode.
+func (ds *DemoSet) demoResult(key string, value interface{}) {
+	if ds.result == nil {
+		ds.result = make(map[string]interface{})
+	}
+	ds.result[key] = value
+}
+
+// demoWriteError prints the error object in json mode.
+func (ds *DemoSet) demoWriteError(code string, err error) {
+	if ds.isDemoJSONOutput() {
+		ds.demoWriteOutput(&DemoOutput{Command: ds.command, OK: false, Error: &DemoOutputError{Code: code, Message: err.Error()}})
+	}
+}
+
+func (ds *DemoSet) demoWriteOutput(output *DemoOutput) {
+	stdout := ds.stdout
+	if stdout == nil {
+		stdout = os.Stdout
 	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(output)
 }
 
 func (ds *DemoSet) getDemoEnvPath() string {
@@ -229,14 +394,37 @@ func (ds *DemoSet) getDemoConfigPath() string {
 
 var demoConfig map[string]string
 
+// demoLoadConfig loads the config the demos read their values from, and checks it before running a demo.
+func (ds *DemoSet) demoLoadConfig() error {
+	config, sources, err := ds.loadDemoConfig()
+	if err != nil {
+		return err
+	}
+	if err := checkDemoConfig(config); err != nil {
+		return err
+	}
+	demoConfig, demoConfigSources = config, sources
+	return nil
+}
+
+// getDemoConfig returns the config loaded before running the demo. Collecting the flags of a demo for its
+// help should not fail on a bad config, nor create the config file, so the config is then loaded as is,
+// with the bundled config the file would be created from standing in for a missing file, and without any
+// value if it fails to load.
 func (ds *DemoSet) getDemoConfig() map[string]string {
 	if demoConfig == nil {
-		configPath := ds.getDemoConfigPath()
-		data, err := os.ReadFile(configPath)
-		ds.demoCheck(err)
-
-		err = json.Unmarshal(data, &demoConfig)
-		ds.demoCheck(err)
+		config, sources, err := ds.loadDemoConfig()
+		if err != nil {
+			config, sources = make(map[string]string), make(map[string]string)
+		} else if _, err := os.Stat(ds.getDemoConfigPath()); os.IsNotExist(err) {
+			chainConfig, _ := loadDemoBundledChainConfig(config)
+			for key, value := range chainConfig {
+				if source := sources[key]; source == "" || source == "default" {
+					config[key], sources[key] = value, "file"
+				}
+			}
+		}
+		demoConfig, demoConfigSources = config, sources
 	}
 
 	return demoConfig
@@ -247,73 +435,124 @@ func (ds *DemoSet) getDemoConfigStringValue(key string) string {
 	return config[key]
 }
 
-func (ds *DemoSet) getDemoConfigNumericValue(key string) int64 {
-	stringValue := ds.getDemoConfigStringValue(key)
-	numericValue, err := strconv.ParseInt(stringValue, 10, 64)
-	ds.demoCheck(err)
-	return numericValue
+// getDemoConfigTypedValue returns the value of a config key checked against its type in the schema. An unset key
+// falls back to its default, since the config may have failed to load while the flags of a demo are collected.
+func (ds *DemoSet) getDemoConfigTypedValue(key string) (string, error) {
+	value := ds.getDemoConfigStringValue(key)
+	if field := findDemoConfigField(key); value == "" && field != nil && field.Key == key {
+		value = field.Default
+	}
+	return value, validateDemoConfigValue(key, value)
 }
 
-func (ds *DemoSet) getDemoChainID() int8 {
-	return int8(ds.getDemoConfigNumericValue("chainID"))
+// getDemoConfigIntValue returns an int config value, 0 if unset without a default.
+func (ds *DemoSet) getDemoConfigIntValue(key string) (int64, error) {
+	value, err := ds.getDemoConfigTypedValue(key)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
 }
 
-func (ds *DemoSet) DemoALOHA(args []string) {
-	if len(args) == 0 {
-		args = []string{"World"}
+// getDemoConfigFloatValue returns a float config value, 0 if unset without a default.
+func (ds *DemoSet) getDemoConfigFloatValue(key string) (float64, error) {
+	value, err := ds.getDemoConfigTypedValue(key)
+	if err != nil || value == "" {
+		return 0, err
 	}
+	return strconv.ParseFloat(value, 64)
+}
 
-	ds.demoCase("Say hi.")
-	fmt.Printf("Aloha, %v!\n", strings.Join(args, " "))
-
-	ds.demoCase("Show demo config.")
-	fmt.Printf("Demo env path: %s\n", ds.getDemoEnvPath())
-	fmt.Printf("Demo config path: %s\n", ds.getDemoConfigPath())
-	config := ds.getDemoConfig()
+// getDemoConfigBoolValue returns a bool config value, false if unset without a default.
+func (ds *DemoSet) getDemoConfigBoolValue(key string) (bool, error) {
+	value, err := ds.getDemoConfigTypedValue(key)
+	if err != nil || value == "" {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
 
-	// Sort by key.
-	keys := make([]string, 0, len(config))
-	for key := range config {
-		keys = append(keys, key)
+// getDemoConfigDurationValue returns a duration config value, 0 if unset without a default.
+func (ds *DemoSet) getDemoConfigDurationValue(key string) (time.Duration, error) {
+	value, err := ds.getDemoConfigTypedValue(key)
+	if err != nil || value == "" {
+		return 0, err
 	}
-	sort.Strings(keys)
+	return time.ParseDuration(value)
+}
 
-	// Print config.
-	for _, key := range keys {
-		value := config[key]
-		fmt.Printf("%s: %s\n", key, value)
+// getDemoConfigURLValue returns a URL config value, nil if unset without a default.
+func (ds *DemoSet) getDemoConfigURLValue(key string) (*url.URL, error) {
+	value, err := ds.getDemoConfigTypedValue(key)
+	if err != nil || value == "" {
+		return nil, err
 	}
+	return url.Parse(value)
 }
 
-func (ds *DemoSet) getDemoAbecRPCClient() *core.AbecRPCClient {
-	endpoint := ds.getDemoConfigStringValue("abec.rpc.endpoint")
-	username := ds.getDemoConfigStringValue("abec.rpc.username")
-	password := ds.getDemoConfigStringValue("abec.rpc.password")
-	return core.NewAbecRPCClient(endpoint, username, password)
+// getDemoChainID returns the chain ID of the config, which is checked to be in range when it is loaded.
+func (ds *DemoSet) getDemoChainID() int8 {
+	chainID, _ := strconv.ParseInt(ds.getDemoConfigStringValue("chainID"), 10, 8)
+	return int8(chainID)
 }
 
-func (ds *DemoSet) getDemoAccounts() map[int]*DemoAccount {
+// DemoAbecRPCClient is the set of abec RPC calls used by the demos.
+// It is implemented by core.AbecRPCClient and by the wrappers around it.
+type DemoAbecRPCClient interface {
+	GetChainInfo() (core.Bytes, *core.AbecChainInfo, error)
+	GetMempool() (core.Bytes, *core.AbecMempool, error)
+	GetBlockHash(height int64) (core.Bytes, *string, error)
+	GetBlock(hash string) (core.Bytes, *core.AbecBlock, error)
+	GetBlockBytes(hash string) (core.Bytes, error)
+	GetRawTx(hash string) (core.Bytes, *core.AbecTx, error)
+	GetBlockBytesByHeight(height int64) (core.Bytes, error)
+	GetEstimatedTxFee() int64
+	SendRawTx(txStr string) (core.Bytes, *string, error)
+}
+
+var demoLog = core.NewLogger("abelsdk-demo")
+
+func (ds *DemoSet) getDemoAccounts() (map[int]*DemoAccount, error) {
+	// Use the bundled accounts of the chain if any, and those generated by BasicGenerateAccounts otherwise.
 	chainID := ds.getDemoChainID()
 	resourceFile := fmt.Sprintf("resources/accounts-chain-%d.json", chainID)
 	data, err := demoResources.ReadFile(resourceFile)
-	ds.demoCheck(err)
+	if err != nil {
+		resourceFile = ds.getDemoFilePath(fmt.Sprintf("accounts-chain-%d.json", chainID))
+		data, err = os.ReadFile(resourceFile)
+	}
+	if err != nil {
+		return nil, newDemoError(DemoErrorValidation, "no demo accounts for chain %d, generate them with `BasicGenerateAccounts -chainID %d`",
+			chainID, chainID)
+	}
 
 	var demoAccountResource DemoAccountResource
 	err = json.Unmarshal(data, &demoAccountResource)
-	ds.demoCheck(err)
+	if err != nil {
+		return nil, err
+	}
+	if demoAccountResource.Info.ChainID != chainID {
+		return nil, newDemoError(DemoErrorValidation, "demo accounts in %s are of chain %d, not of chain %d",
+			resourceFile, demoAccountResource.Info.ChainID, chainID)
+	}
+
+	return NewDemoAccounts(&demoAccountResource)
+}
 
+// NewDemoAccounts creates the accounts of a resource, checking their keys and address against their crypto seeds.
+func NewDemoAccounts(demoAccountResource *DemoAccountResource) (map[int]*DemoAccount, error) {
 	demoAccounts := make(map[int]*DemoAccount)
 	for _, accountInfo := range demoAccountResource.AccountInfos {
 		demoAccount := &DemoAccount{
 			ChainID:           demoAccountResource.Info.ChainID,
 			ChainName:         demoAccountResource.Info.ChainName,
 			CryptoVersion:     demoAccountResource.Info.CryptoVersion,
-			Mnemonics:         demoAccountResource.Info.Mnemonics,
+			Mnemonics:         DemoSecret(demoAccountResource.Info.Mnemonics),
 			SerialNo:          accountInfo.SerialNo,
-			CryptoSeed:        core.MakeBytesFromHexString(accountInfo.CryptoSeedHex),
-			SpendSecretKey:    &core.CryptoKey{Bytes: core.MakeBytesFromHexString(accountInfo.SpendSecretKeyHex)},
-			SerialNoSecretKey: &core.CryptoKey{Bytes: core.MakeBytesFromHexString(accountInfo.SerialNoSecretKeyHex)},
-			ViewSecretKey:     &core.CryptoKey{Bytes: core.MakeBytesFromHexString(accountInfo.ViewSecretKeyHex)},
+			CryptoSeed:        DemoSecretBytes(core.MakeBytesFromHexString(accountInfo.CryptoSeedHex)),
+			SpendSecretKey:    NewDemoSecretKey(&core.CryptoKey{Bytes: core.MakeBytesFromHexString(accountInfo.SpendSecretKeyHex)}),
+			SerialNoSecretKey: NewDemoSecretKey(&core.CryptoKey{Bytes: core.MakeBytesFromHexString(accountInfo.SerialNoSecretKeyHex)}),
+			ViewSecretKey:     NewDemoSecretKey(&core.CryptoKey{Bytes: core.MakeBytesFromHexString(accountInfo.ViewSecretKeyHex)}),
 			CryptoAddress:     core.NewCryptoAddress(core.MakeBytesFromHexString(accountInfo.CryptoAddressHex)),
 		}
 		demoAccount.CoinAddress = demoAccount.CryptoAddress.GetCoinAddress()
@@ -322,34 +561,67 @@ func (ds *DemoSet) getDemoAccounts() map[int]*DemoAccount {
 		demoAccount.Fingerprint = demoAccount.ShortAbelAddress.Fingerprint()
 
 		// Re-generate crypto keys and address from crypto seed and check if they match the resource data.
-		ckaa, err := core.GenerateCryptoKeysAndAddress(demoAccount.CryptoSeed)
-		ds.demoCheck(err)
+		ckaa, err := core.GenerateCryptoKeysAndAddress(core.Bytes(demoAccount.CryptoSeed))
+		if err != nil {
+			return nil, err
+		}
 
 		// Check spend secret key.
-		if !bytes.Equal(ckaa.SpendSecretKey.Bytes, demoAccount.SpendSecretKey.Bytes) {
-			ds.demoExitOnError(fmt.Errorf("crypto seed and spend secret key mismatch"))
+		if !bytes.Equal(ckaa.SpendSecretKey.Bytes, demoAccount.SpendSecretKey.CryptoKey().Bytes) {
+			return nil, fmt.Errorf("crypto seed and spend secret key mismatch")
 		}
 
 		// Check serial No. secret key.
-		if !bytes.Equal(ckaa.SerialNoSecretKey.Bytes, demoAccount.SerialNoSecretKey.Bytes) {
-			ds.demoExitOnError(fmt.Errorf("crypto seed and serial No. secret key mismatch"))
+		if !bytes.Equal(ckaa.SerialNoSecretKey.Bytes, demoAccount.SerialNoSecretKey.CryptoKey().Bytes) {
+			return nil, fmt.Errorf("crypto seed and serial No. secret key mismatch")
 		}
 
 		// Check view secret key. Omit the last 32 bytes due to a known inconsistency issue.
 		vskA := ckaa.ViewSecretKey.Bytes.Slice()[0 : ckaa.ViewSecretKey.Len()-32]
-		vskB := demoAccount.ViewSecretKey.Bytes.Slice()[0 : demoAccount.ViewSecretKey.Len()-32]
+		vskB := demoAccount.ViewSecretKey.CryptoKey().Bytes.Slice()[0 : demoAccount.ViewSecretKey.CryptoKey().Len()-32]
 		if !bytes.Equal(vskA, vskB) {
-			ds.demoExitOnError(fmt.Errorf("crypto seed and view secret key mismatch"))
+			return nil, fmt.Errorf("crypto seed and view secret key mismatch")
 		}
 
 		// Check crypto address.
 		if !bytes.Equal(ckaa.CryptoAddress.Data(), demoAccount.CryptoAddress.Data()) {
-			ds.demoExitOnError(fmt.Errorf("crypto seed and crypto address mismatch"))
+			return nil, fmt.Errorf("crypto seed and crypto address mismatch")
 		}
 
 		// All checks passed.
 		demoAccounts[demoAccount.SerialNo] = demoAccount
 	}
 
-	return demoAccounts
+	return demoAccounts, nil
+}
+
+// NewDemoAccountFromMnemonic derives the account of a mnemonic at a sequence number, which is also its seqno.
+func NewDemoAccountFromMnemonic(mnemonic []string, sequenceNumber uint64, chainID int8) (*DemoAccount, error) {
+	cryptoSeed, err := core.GenerateCryptoSeedFromMnemonic(mnemonic, sequenceNumber)
+	if err != nil {
+		return nil, err
+	}
+	ckaa, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	demoAccount := &DemoAccount{
+		ChainID:           chainID,
+		Mnemonics:         DemoSecret(strings.Join(mnemonic, ",")),
+		SerialNo:          int(sequenceNumber),
+		CryptoSeed:        DemoSecretBytes(cryptoSeed),
+		SpendSecretKey:    NewDemoSecretKey(&ckaa.SpendSecretKey),
+		SerialNoSecretKey: NewDemoSecretKey(&ckaa.SerialNoSecretKey),
+		ViewSecretKey:     NewDemoSecretKey(&ckaa.ViewSecretKey),
+		CryptoAddress:     &ckaa.CryptoAddress,
+	}
+	if chain := findDemoChain(chainID); chain != nil {
+		demoAccount.ChainName = chain.Name
+	}
+	demoAccount.CoinAddress = demoAccount.CryptoAddress.GetCoinAddress()
+	demoAccount.AbelAddress = core.NewAbelAddressFromCryptoAddress(demoAccount.CryptoAddress, demoAccount.ChainID)
+	demoAccount.ShortAbelAddress = demoAccount.AbelAddress.GetShortAbelAddress()
+	demoAccount.Fingerprint = demoAccount.ShortAbelAddress.Fingerprint()
+	return demoAccount, nil
 }
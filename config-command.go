@@ -0,0 +1,182 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DemoConfig runs the config command, to show, set or validate the layered demo config. In json mode, the usage
+// goes to stderr along with the text output, and a usage error is written as the json output.
+func DemoConfig(args []string) error {
+	ds.command = "config"
+	ds.demoRedirectOutput()
+	if len(args) == 0 {
+		printDemoConfigUsage()
+		return &demoPrintedError{err: newDemoError(DemoErrorUsage, "config expects a command")}
+	}
+
+	if err := ds.demoInit(); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "show":
+		return ds.demoConfigShow(args[1:])
+	case "set":
+		if len(args) != 3 {
+			printDemoConfigUsage()
+			return &demoPrintedError{err: newDemoError(DemoErrorUsage, "config set expects 2 args, got %d", len(args)-1)}
+		}
+		return ds.demoConfigSet(args[1], args[2])
+	case "validate":
+		return ds.demoConfigValidate()
+	default:
+		fmt.Printf("Unknown config command: %s\n\n", args[0])
+		printDemoConfigUsage()
+		return &demoPrintedError{err: newDemoError(DemoErrorUsage, "unknown config command %s", args[0])}
+	}
+}
+
+func printDemoConfigUsage() {
+	program := demoProgramName()
+	fmt.Printf("Usage: %s [--profile name] config show [key...]\n", program)
+	fmt.Printf("       %s [--profile name] config set <key> <value>\n", program)
+	fmt.Printf("       %s [--profile name] config validate\n", program)
+	fmt.Printf("\n")
+	fmt.Printf("The config is layered from the defaults, the config file, the profile, the ABELSDK_* env vars\n")
+	fmt.Printf("and the --set global flags, each one overriding the previous ones. set writes to the config\n")
+	fmt.Printf("file of the selected profile if any, and to the config file otherwise, an empty value removes the key.\n")
+	fmt.Printf("\nKeys:\n")
+	for _, field := range demoConfigSchema {
+		fmt.Printf("  %-38s %-11s %s\n", field.Key, field.Type, field.Description)
+	}
+	fmt.Printf("\nProfiles:\n")
+	for _, profile := range demoConfigProfiles {
+		fmt.Printf("  %-8s %s\n", profile.Name, profile.Description)
+	}
+}
+
+// demoConfigShow prints the values of the config along with their sources, without validating them.
+func (ds *DemoSet) demoConfigShow(keys []string) error {
+	config, sources, err := ds.loadDemoConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		for key := range config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	fmt.Printf("Config file: %s\n", ds.getDemoConfigPath())
+	if config["profile"] != "" {
+		fmt.Printf("Profile: %s (%s)\n", config["profile"], ds.getDemoConfigProfilePath(config["profile"]))
+	}
+	values := make(map[string]string)
+	for _, key := range keys {
+		value, ok := config[key]
+		if !ok {
+			if findDemoConfigField(key) == nil {
+				return validateDemoConfigValue(key, "")
+			}
+			fmt.Printf("%s is not set\n", key)
+			continue
+		}
+		value = redactDemoConfigValue(key, value)
+		fmt.Printf("%s = %s (%s)\n", key, value, sources[key])
+		values[key] = value
+	}
+
+	if ds.isDemoJSONOutput() {
+		ds.demoWriteOutput(&DemoOutput{Command: ds.command, OK: true, Result: map[string]interface{}{
+			"file":    ds.getDemoConfigPath(),
+			"profile": config["profile"],
+			"config":  values,
+			"sources": sources,
+		}})
+	}
+	return nil
+}
+
+// demoConfigSet sets a value in the config file of the selected profile, or in the config file.
+func (ds *DemoSet) demoConfigSet(key string, value string) error {
+	if err := validateDemoConfigValue(key, value); err != nil {
+		return err
+	}
+
+	path := ds.getDemoConfigPath()
+	fileConfig, err := readDemoConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if profile, _ := ds.getDemoConfigProfileName(fileConfig); profile != "" && key != "profile" {
+		if findDemoConfigProfile(profile) == nil {
+			return validateDemoConfigValue("profile", profile)
+		}
+		path = ds.getDemoConfigProfilePath(profile)
+		fileConfig, err = readDemoConfigFile(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if value == "" {
+		delete(fileConfig, key)
+		fmt.Printf("Removed %s from %s.\n", key, path)
+	} else {
+		fileConfig[key] = value
+		fmt.Printf("Set %s = %s in %s.\n", key, redactDemoConfigValue(key, value), path)
+	}
+	if err := writeDemoConfigFile(path, fileConfig); err != nil {
+		return err
+	}
+
+	// Tell when the value is still overridden by a higher layer.
+	_, sources, err := ds.loadDemoConfig()
+	if err != nil {
+		return err
+	}
+	if source := sources[key]; strings.HasPrefix(source, "env:") || source == "flag" {
+		fmt.Printf("Note: %s is overridden by %s.\n", key, source)
+	}
+
+	if ds.isDemoJSONOutput() {
+		ds.demoWriteOutput(&DemoOutput{Command: ds.command, OK: true, Result: map[string]interface{}{
+			"file":  path,
+			"key":   key,
+			"value": redactDemoConfigValue(key, value),
+		}})
+	}
+	return nil
+}
+
+// demoConfigValidate checks the config, returning a validation error if it is invalid.
+func (ds *DemoSet) demoConfigValidate() error {
+	config, _, err := ds.loadDemoConfig()
+	if err != nil {
+		return err
+	}
+
+	errs := validateDemoConfig(config)
+	for _, err := range errs {
+		fmt.Printf("❌ %v\n", err)
+	}
+	if len(errs) > 0 {
+		return newDemoError(DemoErrorValidation, "%d problems found in the config", len(errs))
+	}
+	fmt.Printf("✅ The config is valid.\n")
+
+	if ds.isDemoJSONOutput() {
+		ds.demoWriteOutput(&DemoOutput{Command: ds.command, OK: true, Result: map[string]interface{}{
+			"valid": true,
+		}})
+	}
+	return nil
+}
@@ -0,0 +1,519 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Define the schema and the layers of the demo config.
+//
+// The config is a flat map of string values. It is layered from the defaults of the schema, the
+// config file, the selected profile, the ABELSDK_* env vars and the --set global flags, each one
+// overriding the previous ones. The flags of a demo default to the config values, so they override
+// them all.
+type DemoConfigType string
+
+const (
+	DemoConfigString      DemoConfigType = "string"
+	DemoConfigInt         DemoConfigType = "int"
+	DemoConfigFloat       DemoConfigType = "float"
+	DemoConfigBool        DemoConfigType = "bool"
+	DemoConfigDuration    DemoConfigType = "duration"
+	DemoConfigURL         DemoConfigType = "url"
+	DemoConfigHeightRange DemoConfigType = "heightRange"
+	DemoConfigSeqnos      DemoConfigType = "seqnos"
+)
+
+type DemoConfigField struct {
+	Key     string
+	Type    DemoConfigType
+	Default string
+	// Indexed fields also accept the keys Key.N for N >= 1, e.g. the failover RPC endpoints.
+	Indexed bool
+	// Secret fields are redacted when shown, unless the --reveal-secrets global flag is given.
+	Secret      bool
+	Description string
+}
+
+var demoConfigSchema = []*DemoConfigField{
+	{Key: "profile", Type: DemoConfigString, Description: "Profile to apply over the config file, one of mainnet, testnet, simnet or local."},
+	{Key: "chainID", Type: DemoConfigInt, Description: "Chain ID (0 ~ 15)."},
+
+	{Key: "abec.rpc.endpoint", Type: DemoConfigURL, Indexed: true, Description: "URL of the abec RPC endpoint, abec.rpc.endpoint.N for failover endpoints."},
+	{Key: "abec.rpc.username", Type: DemoConfigString, Indexed: true, Secret: true, Description: "Username of the abec RPC endpoint."},
+	{Key: "abec.rpc.password", Type: DemoConfigString, Indexed: true, Secret: true, Description: "Password of the abec RPC endpoint."},
+	{Key: "abec.rpc.timeout", Type: DemoConfigDuration, Default: "60s", Description: "Timeout of an RPC call."},
+	{Key: "abec.rpc.maxRetries", Type: DemoConfigInt, Default: "3", Description: "Number of retries of a failed RPC call."},
+	{Key: "abec.rpc.retryBaseDelay", Type: DemoConfigDuration, Default: "500ms", Description: "Delay before the first retry, doubled for every retry."},
+	{Key: "abec.rpc.retryMaxDelay", Type: DemoConfigDuration, Default: "10s", Description: "Maximum delay between retries."},
+	{Key: "abec.rpc.requestsPerSecond", Type: DemoConfigFloat, Description: "Rate limit of RPC calls across endpoints, unlimited if unset."},
+	{Key: "abec.rpc.maxLag", Type: DemoConfigInt, Default: "2", Description: "Number of blocks an endpoint may lag behind the best one and still be used."},
+	{Key: "abec.rpc.healthCheckInterval", Type: DemoConfigDuration, Default: "30s", Description: "Interval between health checks of the endpoints."},
+	{Key: "abec.rpc.cache", Type: DemoConfigBool, Default: "true", Description: "Cache confirmed blocks and txs on disk."},
+	{Key: "abec.rpc.cacheFinalityDepth", Type: DemoConfigInt, Default: "10", Description: "Number of confirmations for a block to be cached."},
+	{Key: "abec.rpc.record", Type: DemoConfigString, Description: "Cassette file to record the RPC traffic to."},
+	{Key: "abec.rpc.replay", Type: DemoConfigString, Description: "Cassette file to replay the RPC traffic from, instead of calling a node."},
+
+	{Key: "SDKTrackCoins.accounts", Type: DemoConfigSeqnos, Description: "Default of SDKTrackCoins -accounts."},
+	{Key: "SDKTrackCoins.scanHeightRange", Type: DemoConfigHeightRange, Description: "Default of SDKTrackCoins -txosHeightRange."},
+	{Key: "SDKTrackCoins.trackHeightRange", Type: DemoConfigHeightRange, Description: "Default of SDKTrackCoins -trackHeightRange."},
+
+	{Key: "SDKMakeUnsignedRawTx.scanHeightRange", Type: DemoConfigHeightRange, Description: "Default of SDKMakeUnsignedRawTx -txosHeightRange."},
+	{Key: "SDKMakeUnsignedRawTx.senders", Type: DemoConfigSeqnos, Description: "Default of SDKMakeUnsignedRawTx -senders."},
+	{Key: "SDKMakeUnsignedRawTx.receivers", Type: DemoConfigSeqnos, Description: "Default of SDKMakeUnsignedRawTx -receivers."},
+	{Key: "SDKMakeUnsignedRawTx.outputFile", Type: DemoConfigString, Description: "Default of SDKMakeUnsignedRawTx -outputFile."},
+
+	{Key: "SDKMakeSignedRawTx.senders", Type: DemoConfigSeqnos, Description: "Default of SDKMakeSignedRawTx -senders."},
+	{Key: "SDKMakeSignedRawTx.inputFile", Type: DemoConfigString, Description: "Default of SDKMakeSignedRawTx -inputFile."},
+	{Key: "SDKMakeSignedRawTx.outputFile", Type: DemoConfigString, Description: "Default of SDKMakeSignedRawTx -outputFile."},
+
+	{Key: "SDKSubmitSignedRawTx.inputFile", Type: DemoConfigString, Description: "Default of SDKSubmitSignedRawTx -inputFile."},
+
+	{Key: "webhook.url", Type: DemoConfigURL, Indexed: true, Description: "URL the coin events are posted to, webhook.url.N for more URLs."},
+	{Key: "webhook.secret", Type: DemoConfigString, Secret: true, Description: "Secret of the HMAC-SHA256 signatures of the webhook payloads."},
+	{Key: "webhook.confirmations", Type: DemoConfigInt, Default: "6", Description: "Number of confirmations for a coin.confirmed event."},
+	{Key: "webhook.maxAttempts", Type: DemoConfigInt, Default: "10", Description: "Number of attempts to deliver an event before giving up."},
+	{Key: "webhook.retryBaseDelay", Type: DemoConfigDuration, Default: "30s", Description: "Delay before the first retry of a delivery, doubled for every retry."},
+	{Key: "webhook.retryMaxDelay", Type: DemoConfigDuration, Default: "1h", Description: "Maximum delay between retries of a delivery."},
+	{Key: "webhook.timeout", Type: DemoConfigDuration, Default: "10s", Description: "Timeout of a delivery."},
+	{Key: "webhook.logSize", Type: DemoConfigInt, Default: "1000", Description: "Number of delivery attempts kept in the delivery log."},
+	{Key: "webhook.dedupeWindow", Type: DemoConfigDuration, Default: "720h", Description: "How long the IDs of the delivered events are kept to avoid queuing them again."},
+
+	{Key: "deposit.mnemonic", Type: DemoConfigString, Secret: true, Description: "Comma separated master mnemonic the deposit addresses of the customers are derived from."},
+	{Key: "deposit.confirmations", Type: DemoConfigInt, Default: "6", Description: "Number of confirmations to credit a deposit."},
+	{Key: "deposit.scanFromHeight", Type: DemoConfigInt, Default: "0", Description: "Height SDKScanDeposits starts scanning blocks from."},
+
+	{Key: "withdrawal.hotAccounts", Type: DemoConfigSeqnos, Description: "Seqnos of the demo accounts paying the withdrawals."},
+	{Key: "withdrawal.batchSize", Type: DemoConfigInt, Default: "10", Description: "Maximum number of withdrawals paid by a tx."},
+	{Key: "withdrawal.maxTxValue", Type: DemoConfigInt, Default: "0", Description: "Maximum value in Neutrino paid by a withdrawal tx, unlimited if 0."},
+	{Key: "withdrawal.dailyLimit", Type: DemoConfigInt, Default: "0", Description: "Maximum value in Neutrino of the withdrawals submitted in 24 hours, unlimited if 0."},
+	{Key: "withdrawal.approvalThreshold", Type: DemoConfigInt, Default: "0", Description: "Value in Neutrino from which a withdrawal is held for approval, none is if 0."},
+	{Key: "withdrawal.allowList", Type: DemoConfigString, Description: "Comma separated fingerprints of the only addresses withdrawals may go to, any if unset."},
+	{Key: "withdrawal.denyList", Type: DemoConfigString, Description: "Comma separated fingerprints of the addresses withdrawals may not go to."},
+
+	{Key: "mempool.pollInterval", Type: DemoConfigDuration, Default: "10s", Description: "Interval between polls of the mempool by SDKWatchMempool."},
+
+	{Key: "snapshot.signingKey", Type: DemoConfigString, Secret: true, Description: "Hex encoded 32 bytes seed of the ed25519 key signing the balance snapshots."},
+	{Key: "snapshot.trustedKey", Type: DemoConfigString, Description: "Hex encoded ed25519 public key SDKVerifySnapshot checks the snapshots against, unless -publicKey is given."},
+
+	{Key: "wallet.listen", Type: DemoConfigString, Default: "127.0.0.1:18680", Description: "Address the wallet API of SDKWalletServer listens on."},
+	{Key: "wallet.grpcListen", Type: DemoConfigString, Description: "Address the wallet gRPC service of SDKWalletServer listens on, disabled if unset."},
+	{Key: "wallet.apiKey", Type: DemoConfigString, Secret: true, Description: "API key required by the wallet API."},
+	{Key: "wallet.accounts", Type: DemoConfigSeqnos, Description: "Seqnos of the demo accounts tracked by the wallet, all of them if unset."},
+	{Key: "wallet.scanFromHeight", Type: DemoConfigInt, Default: "0", Description: "Height the wallet starts scanning blocks from."},
+	{Key: "wallet.syncInterval", Type: DemoConfigDuration, Default: "10s", Description: "Interval between syncs of the wallet once caught up with the chain."},
+}
+
+// findDemoConfigField returns the field of a config key, matching Key.N for indexed fields.
+func findDemoConfigField(key string) *DemoConfigField {
+	for _, field := range demoConfigSchema {
+		if field.Key == key {
+			return field
+		}
+		if field.Indexed && strings.HasPrefix(key, field.Key+".") {
+			if n, err := strconv.Atoi(key[len(field.Key)+1:]); err == nil && n >= 1 {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// DemoConfigProfile is a named set of values applied over the config file.
+type DemoConfigProfile struct {
+	Name        string
+	Description string
+	// Resource is an embedded config file the profile starts from, if any.
+	Resource string
+	Values   map[string]string
+}
+
+// demoConfigProfiles has a profile for every registered chain, and the local one.
+var demoConfigProfiles = append(getDemoChainProfiles(), []*DemoConfigProfile{
+	{
+		Name:        "local",
+		Description: "A mock abec node serving the chain fixture, as started by BasicMockAbecServer.",
+		Resource:    "resources/config-chain-2.json",
+		Values: map[string]string{
+			"abec.rpc.endpoint": "http://127.0.0.1:18667",
+			"abec.rpc.cache":    "false",
+		},
+	},
+}...)
+
+func getDemoChainProfiles() []*DemoConfigProfile {
+	profiles := make([]*DemoConfigProfile, len(demoChains))
+	for i, chain := range demoChains {
+		profiles[i] = chain.Profile()
+	}
+	return profiles
+}
+
+func findDemoConfigProfile(name string) *DemoConfigProfile {
+	for _, profile := range demoConfigProfiles {
+		if profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+func getDemoConfigProfileNames() []string {
+	names := make([]string, len(demoConfigProfiles))
+	for i, profile := range demoConfigProfiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+// LoadValues returns the values of the profile, including those of its resource.
+func (profile *DemoConfigProfile) LoadValues() (map[string]string, error) {
+	values := make(map[string]string)
+	if profile.Resource != "" {
+		data, err := demoResources.ReadFile(profile.Resource)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &values)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s: %v", profile.Resource, err)
+		}
+	}
+	for key, value := range profile.Values {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// Define the config sources given on the command line, set by parseDemoGlobalFlags.
+var (
+	demoConfigProfileFlag string
+	demoConfigSetFlags    = make(map[string]string)
+)
+
+// demoConfigSources records the source of every value of the loaded config, e.g. file or env:ABELSDK_CHAINID.
+var demoConfigSources map[string]string
+
+// getDemoConfigEnvName returns the env var overriding a config key, e.g. ABELSDK_ABEC_RPC_ENDPOINT.
+func getDemoConfigEnvName(key string) string {
+	return "ABELSDK_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func (ds *DemoSet) getDemoConfigProfilePath(profile string) string {
+	return ds.getDemoFilePath(fmt.Sprintf(".config.%s.json", profile))
+}
+
+// readDemoConfigFile reads a config file, a missing file being an empty config.
+func readDemoConfigFile(path string) (map[string]string, error) {
+	config := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, newDemoError(DemoErrorValidation, "malformed config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+func writeDemoConfigFile(path string, config map[string]string) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeDemoSecretFile(path, append(data, '\n'))
+}
+
+// getDemoConfigProfileName returns the selected profile and its source, from the --profile flag,
+// ABELSDK_PROFILE or the profile key of the config file in this order.
+func (ds *DemoSet) getDemoConfigProfileName(fileConfig map[string]string) (string, string) {
+	if demoConfigProfileFlag != "" {
+		return demoConfigProfileFlag, "flag"
+	}
+	envName := getDemoConfigEnvName("profile")
+	if profile := os.Getenv(envName); profile != "" {
+		return profile, "env:" + envName
+	}
+	return fileConfig["profile"], "file"
+}
+
+// loadDemoConfig layers all config sources, returning the values along with their sources.
+func (ds *DemoSet) loadDemoConfig() (map[string]string, map[string]string, error) {
+	config := make(map[string]string)
+	sources := make(map[string]string)
+	set := func(values map[string]string, source string) {
+		for key, value := range values {
+			config[key] = value
+			sources[key] = source
+		}
+	}
+
+	for _, field := range demoConfigSchema {
+		if field.Default != "" {
+			set(map[string]string{field.Key: field.Default}, "default")
+		}
+	}
+
+	fileConfig, err := readDemoConfigFile(ds.getDemoConfigPath())
+	if err != nil {
+		return nil, nil, err
+	}
+	set(fileConfig, "file")
+
+	if profileName, profileSource := ds.getDemoConfigProfileName(fileConfig); profileName != "" {
+		profile := findDemoConfigProfile(profileName)
+		if profile == nil {
+			return nil, nil, newDemoError(DemoErrorValidation, "unknown profile %q, expecting one of %s",
+				profileName, strings.Join(getDemoConfigProfileNames(), ", "))
+		}
+		profileValues, err := profile.LoadValues()
+		if err != nil {
+			return nil, nil, err
+		}
+		set(profileValues, "profile:"+profileName)
+		profileFileConfig, err := readDemoConfigFile(ds.getDemoConfigProfilePath(profileName))
+		if err != nil {
+			return nil, nil, err
+		}
+		set(profileFileConfig, "profile-file:"+profileName)
+		set(map[string]string{"profile": profileName}, profileSource)
+	}
+
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, "ABELSDK_") {
+			continue
+		}
+		if key := findDemoConfigKeyByEnvName(name); key != "" {
+			set(map[string]string{key: value}, "env:"+name)
+		}
+	}
+
+	set(demoConfigSetFlags, "flag")
+
+	return config, sources, nil
+}
+
+// findDemoConfigKeyByEnvName returns the config key overridden by an env var, if any.
+func findDemoConfigKeyByEnvName(name string) string {
+	for _, field := range demoConfigSchema {
+		envName := getDemoConfigEnvName(field.Key)
+		if name == envName {
+			return field.Key
+		}
+		if field.Indexed && strings.HasPrefix(name, envName+"_") {
+			if n, err := strconv.Atoi(name[len(envName)+1:]); err == nil && n >= 1 {
+				return fmt.Sprintf("%s.%d", field.Key, n)
+			}
+		}
+	}
+	return ""
+}
+
+// validateDemoConfigValue checks a value against the type of its config key.
+func validateDemoConfigValue(key string, value string) error {
+	field := findDemoConfigField(key)
+	if field == nil {
+		message := fmt.Sprintf("unknown config key %s", key)
+		if suggestion := suggestDemoConfigKey(key); suggestion != "" {
+			message += fmt.Sprintf(", did you mean %s?", suggestion)
+		}
+		return newDemoError(DemoErrorValidation, "%s", message)
+	}
+	if value == "" {
+		return nil
+	}
+
+	name := "config " + key
+	var err error
+	switch field.Type {
+	case DemoConfigInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case DemoConfigFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case DemoConfigBool:
+		_, err = strconv.ParseBool(value)
+	case DemoConfigDuration:
+		_, err = time.ParseDuration(value)
+	case DemoConfigURL:
+		var parsed *url.URL
+		parsed, err = url.Parse(value)
+		if err == nil && (parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "") {
+			err = errors.New("expecting an http or https URL")
+		}
+	case DemoConfigHeightRange:
+		_, _, err = parseDemoHeightRange(name, value)
+		if err != nil {
+			return newDemoError(DemoErrorValidation, "%v", err)
+		}
+	case DemoConfigSeqnos:
+		_, err = parseDemoSeqnos(name, value)
+		if err != nil {
+			return newDemoError(DemoErrorValidation, "%v", err)
+		}
+	}
+	if err != nil {
+		return newDemoError(DemoErrorValidation, "%s %q is not a valid %s: %v", name, redactDemoConfigValue(key, value), field.Type, err)
+	}
+
+	if key == "profile" && findDemoConfigProfile(value) == nil {
+		return newDemoError(DemoErrorValidation, "unknown profile %q, expecting one of %s", value, strings.Join(getDemoConfigProfileNames(), ", "))
+	}
+	if key == "chainID" {
+		if chainID, _ := strconv.ParseInt(value, 10, 64); chainID < 0 || chainID > 15 {
+			return newDemoError(DemoErrorValidation, "%s %q is out of range 0 ~ 15", name, value)
+		}
+	}
+	return nil
+}
+
+// validateDemoConfig checks all values of a config, and that the required ones are set.
+func validateDemoConfig(config map[string]string) []error {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		if err := validateDemoConfigValue(key, config[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if config["chainID"] == "" {
+		errs = append(errs, newDemoError(DemoErrorValidation, "config chainID is not set"))
+	}
+	if config["abec.rpc.endpoint"] == "" && config["abec.rpc.replay"] == "" {
+		errs = append(errs, newDemoError(DemoErrorValidation, "config abec.rpc.endpoint is not set, set it with `config set abec.rpc.endpoint <url>` or %s",
+			getDemoConfigEnvName("abec.rpc.endpoint")))
+	}
+	return errs
+}
+
+// checkDemoConfig returns a single error listing all the problems of a config, if any.
+func checkDemoConfig(config map[string]string) error {
+	errs := validateDemoConfig(config)
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = "  " + err.Error()
+	}
+	return newDemoError(DemoErrorValidation, "invalid config:\n%s", strings.Join(messages, "\n"))
+}
+
+// suggestDemoConfigKey returns the known config key closest to a mistyped one, if any is close enough.
+func suggestDemoConfigKey(key string) string {
+	best, bestDistance := "", len(key)/3+1
+	for _, field := range demoConfigSchema {
+		distance := demoEditDistance(strings.ToLower(key), strings.ToLower(field.Key))
+		if distance < bestDistance {
+			best, bestDistance = field.Key, distance
+		}
+	}
+	return best
+}
+
+func demoEditDistance(a string, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current := row[j]
+			row[j] = min3(row[j]+1, row[j-1]+1, prev+cost)
+			prev = current
+		}
+	}
+	return row[len(b)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// parseDemoHeightRange parses a range of block heights given as <begin>,<end>, by a flag or a config key.
+func parseDemoHeightRange(name string, value string) (int64, int64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return 0, 0, newDemoError(DemoErrorUsage, "%s %q is not a range of block heights <begin>,<end>", name, value)
+	}
+	begin, errBegin := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	end, errEnd := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	if errBegin != nil || errEnd != nil {
+		return 0, 0, newDemoError(DemoErrorUsage, "%s %q is not a range of block heights <begin>,<end>", name, value)
+	}
+	if begin > end || begin < 0 {
+		return 0, 0, newDemoError(DemoErrorValidation, "invalid block range: [%v, %v]", begin, end)
+	}
+	return begin, end, nil
+}
+
+// parseDemoSeqnos parses a comma separated list of account seqnos, given by a flag or a config key.
+func parseDemoSeqnos(name string, value string) ([]int, error) {
+	if value == "" {
+		return nil, newDemoError(DemoErrorUsage, "%s is empty, expecting comma separated seqnos", name)
+	}
+	var seqnos []int
+	for _, part := range strings.Split(value, ",") {
+		seqno, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, newDemoError(DemoErrorUsage, "%s %q has a seqno %q which is not a number", name, value, part)
+		}
+		seqnos = append(seqnos, seqno)
+	}
+	return seqnos, nil
+}
+
+// redactDemoConfigValue redacts the value of a secret config key, and the password of a URL.
+func redactDemoConfigValue(key string, value string) string {
+	field := findDemoConfigField(key)
+	switch {
+	case field == nil || value == "":
+		return value
+	case field.Secret:
+		return DemoSecret(value).String()
+	case field.Type == DemoConfigURL:
+		return redactDemoURL(value)
+	}
+	return value
+}
+
+// redactDemoConfig returns a copy of the config with the secret values redacted.
+func redactDemoConfig(config map[string]string) map[string]string {
+	redacted := make(map[string]string, len(config))
+	for key, value := range config {
+		redacted[key] = redactDemoConfigValue(key, value)
+	}
+	return redacted
+}
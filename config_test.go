@@ -0,0 +1,55 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDemoConfigTypedValues(t *testing.T) {
+	savedConfig := demoConfig
+	defer func() { demoConfig = savedConfig }()
+	ds := &DemoSet{}
+
+	demoConfig = map[string]string{
+		"webhook.maxAttempts":    "5",
+		"webhook.timeout":        "3s",
+		"abec.rpc.cache":         "false",
+		"webhook.url":            "https://example.com/hooks",
+		"abec.rpc.maxRetries":    "three",
+		"webhook.retryBaseDelay": "30",
+	}
+	if value, err := ds.getDemoConfigIntValue("webhook.maxAttempts"); err != nil || value != 5 {
+		t.Errorf("got %v, %v for webhook.maxAttempts, expecting 5", value, err)
+	}
+	if value, err := ds.getDemoConfigDurationValue("webhook.timeout"); err != nil || value != 3*time.Second {
+		t.Errorf("got %v, %v for webhook.timeout, expecting 3s", value, err)
+	}
+	if value, err := ds.getDemoConfigBoolValue("abec.rpc.cache"); err != nil || value {
+		t.Errorf("got %v, %v for abec.rpc.cache, expecting false", value, err)
+	}
+	if value, err := ds.getDemoConfigURLValue("webhook.url"); err != nil || value.Host != "example.com" {
+		t.Errorf("got %v, %v for webhook.url, expecting the URL", value, err)
+	}
+
+	// The unset keys fall back to their defaults, or to the zero value without any.
+	if value, err := ds.getDemoConfigDurationValue("webhook.dedupeWindow"); err != nil || value != 720*time.Hour {
+		t.Errorf("got %v, %v for the unset webhook.dedupeWindow, expecting its default 720h", value, err)
+	}
+	if value, err := ds.getDemoConfigFloatValue("abec.rpc.requestsPerSecond"); err != nil || value != 0 {
+		t.Errorf("got %v, %v for the unset abec.rpc.requestsPerSecond, expecting 0", value, err)
+	}
+	if value, err := ds.getDemoConfigURLValue("webhook.url.1"); err != nil || value != nil {
+		t.Errorf("got %v, %v for the unset webhook.url.1, expecting nil", value, err)
+	}
+
+	if _, err := ds.getDemoConfigIntValue("abec.rpc.maxRetries"); demoExitCode(err) != DemoErrorValidation.ExitCode() {
+		t.Errorf("got %v for the bad abec.rpc.maxRetries, expecting a validation error", err)
+	}
+	if _, err := ds.getDemoConfigDurationValue("webhook.retryBaseDelay"); demoExitCode(err) != DemoErrorValidation.ExitCode() {
+		t.Errorf("got %v for the bad webhook.retryBaseDelay, expecting a validation error", err)
+	}
+}
@@ -0,0 +1,134 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	core "abelian.info/sdk/core"
+	"github.com/abesuite/abec/abecrypto/abecryptoparam"
+)
+
+// Define decoding helpers for data from users and from the node.
+//
+// The core constructors panic on malformed data, e.g. core.MakeBytesFromHexString on bad hex or
+// core.NewShortAbelAddress on short data, so data that is not known to be well-formed goes through
+// these helpers which return an error instead.
+
+// DemoAddress is the part of the core address types used to check and match addresses.
+type DemoAddress interface {
+	Type() core.AddressType
+	Data() core.Bytes
+	Fingerprint() core.Bytes
+	Validate() error
+}
+
+// recoverDemoDecodePanic turns a panic of the core library while decoding into an error.
+func recoverDemoDecodePanic(what string, err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed %s: %v", what, r)
+	}
+}
+
+// DecodeDemoHex decodes a hex string, with or without the 0x prefix.
+func DecodeDemoHex(hexString string) (core.Bytes, error) {
+	hexString = strings.TrimPrefix(hexString, "0x")
+	data, err := hex.DecodeString(hexString)
+	if err != nil {
+		return nil, err
+	}
+	return core.AsBytes(data), nil
+}
+
+func ParseDemoCryptoAddress(data core.Bytes) (cryptoAddress *core.CryptoAddress, err error) {
+	if data.Len() != core.CRYPTO_ADDRESS_LENGTH {
+		return nil, fmt.Errorf("crypto address data length is not %d", core.CRYPTO_ADDRESS_LENGTH)
+	}
+	defer recoverDemoDecodePanic("crypto address", &err)
+
+	cryptoAddress = core.NewCryptoAddress(data)
+	err = cryptoAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cryptoAddress, nil
+}
+
+func ParseDemoAbelAddress(data core.Bytes) (abelAddress *core.AbelAddress, err error) {
+	if data.Len() != core.ABEL_ADDRESS_LENGTH {
+		return nil, fmt.Errorf("abel address data length is not %d", core.ABEL_ADDRESS_LENGTH)
+	}
+	defer recoverDemoDecodePanic("abel address", &err)
+
+	abelAddress = core.NewAbelAddress(data)
+	err = abelAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return abelAddress, nil
+}
+
+func ParseDemoShortAbelAddress(data core.Bytes) (shortAbelAddress *core.ShortAbelAddress, err error) {
+	if data.Len() != core.SHORT_ABEL_ADDRESS_LENGTH {
+		return nil, fmt.Errorf("short abel address data length is not %d", core.SHORT_ABEL_ADDRESS_LENGTH)
+	}
+	defer recoverDemoDecodePanic("short abel address", &err)
+
+	shortAbelAddress = core.NewShortAbelAddress(data)
+	err = shortAbelAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return shortAbelAddress, nil
+}
+
+// ParseDemoAddress parses a hex encoded crypto, abel or short abel address, telling them apart by length.
+func ParseDemoAddress(hexString string) (DemoAddress, error) {
+	data, err := DecodeDemoHex(hexString)
+	if err != nil {
+		return nil, fmt.Errorf("address is not hex encoded: %v", err)
+	}
+
+	switch data.Len() {
+	case core.CRYPTO_ADDRESS_LENGTH:
+		return ParseDemoCryptoAddress(data)
+	case core.ABEL_ADDRESS_LENGTH:
+		return ParseDemoAbelAddress(data)
+	case core.SHORT_ABEL_ADDRESS_LENGTH:
+		return ParseDemoShortAbelAddress(data)
+	default:
+		return nil, fmt.Errorf("unknown address length %d", data.Len())
+	}
+}
+
+// DecodeDemoTxOut decodes the script of a vout returned by getrawtransaction into the tx out data
+// and the coin address it pays to.
+func DecodeDemoTxOut(script string) (txOutData core.Bytes, coinAddress *core.CoinAddress, err error) {
+	txOutData, err = DecodeDemoHex(script)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tx out script is not hex encoded: %v", err)
+	}
+	defer recoverDemoDecodePanic("tx out", &err)
+
+	coinAddress, err = core.DecodeCoinAddressFromTxOutData(txOutData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return txOutData, coinAddress, nil
+}
+
+// DecodeDemoMemo decodes a hex encoded tx memo, rejecting memos the node would not accept.
+func DecodeDemoMemo(memoHex string) (core.Bytes, error) {
+	memo, err := DecodeDemoHex(memoHex)
+	if err != nil {
+		return nil, fmt.Errorf("memo is not hex encoded: %v", err)
+	}
+	if uint32(memo.Len()) > abecryptoparam.MaxAllowedTxMemoSize {
+		return nil, fmt.Errorf("memo length %d exceeds %d bytes", memo.Len(), abecryptoparam.MaxAllowedTxMemoSize)
+	}
+	return memo, nil
+}
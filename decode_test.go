@@ -0,0 +1,272 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	core "abelian.info/sdk/core"
+	"github.com/abesuite/abec/abecrypto/abecryptoparam"
+)
+
+// Fuzz targets for decoding data from users and from the node. The seed corpus is taken from the
+// bundled demo accounts and from the chain fixture of the golden tests, or a small generated chain
+// if there is none. Run e.g. `go test -tags test -run '^$' -fuzz FuzzParseDemoAddress` to fuzz one target.
+
+// demoFuzzAddressSeeds returns the hex encoded addresses of the bundled demo accounts.
+func demoFuzzAddressSeeds(f *testing.F) []string {
+	data, err := demoResources.ReadFile("resources/accounts-chain-2.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	var demoAccountResource DemoAccountResource
+	err = json.Unmarshal(data, &demoAccountResource)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	var seeds []string
+	for _, accountInfo := range demoAccountResource.AccountInfos {
+		cryptoAddress := core.NewCryptoAddress(core.MakeBytesFromHexString(accountInfo.CryptoAddressHex))
+		abelAddress := core.NewAbelAddressFromCryptoAddress(cryptoAddress, demoAccountResource.Info.ChainID)
+		seeds = append(seeds,
+			cryptoAddress.HexString(),
+			abelAddress.HexString(),
+			abelAddress.GetShortAbelAddress().HexString(),
+		)
+	}
+	return seeds
+}
+
+// demoFuzzFixture returns the chain fixture of the golden tests, or generates a chain of two blocks.
+func demoFuzzFixture(f *testing.F) *DemoChainFixture {
+	fixture, err := LoadDemoChainFixture(demoGoldenFixtureDir)
+	if err == nil {
+		return fixture
+	}
+
+	spec := demoGoldenFixtureSpec(f)
+	spec.NumBlocks = 2
+	fixture, err = GenerateDemoChainFixture(spec)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return fixture
+}
+
+// demoFuzzCorruptions returns the seed along with truncated and bit flipped variants of it.
+func demoFuzzCorruptions(seed string) []string {
+	variants := []string{seed, "0x" + seed, strings.ToUpper(seed)}
+	if len(seed) >= 4 {
+		variants = append(variants, seed[:len(seed)-2], seed[:len(seed)-1], seed[2:])
+		flipped := []byte(seed)
+		flipped[len(flipped)/2] ^= 0x01
+		variants = append(variants, string(flipped))
+	}
+	return variants
+}
+
+func FuzzDecodeDemoHex(f *testing.F) {
+	for _, seed := range []string{"", "00", "0x", "0xabcdef", "ABCDEF", "abc", "zz", "0x0x00"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, hexString string) {
+		data, err := DecodeDemoHex(hexString)
+		if err != nil {
+			return
+		}
+		if hex.EncodeToString(data) != strings.ToLower(strings.TrimPrefix(hexString, "0x")) {
+			t.Errorf("decoded %q into %x which does not encode back", hexString, data.Slice())
+		}
+	})
+}
+
+func FuzzParseDemoAddress(f *testing.F) {
+	for _, seed := range demoFuzzAddressSeeds(f) {
+		for _, variant := range demoFuzzCorruptions(seed) {
+			f.Add(variant)
+		}
+	}
+	f.Add("")
+	f.Add("ab")
+
+	f.Fuzz(func(t *testing.T, hexString string) {
+		address, err := ParseDemoAddress(hexString)
+		if err != nil {
+			return
+		}
+		if err := address.Validate(); err != nil {
+			t.Errorf("parsed %v which does not validate: %v", address.Type(), err)
+		}
+		if address.Fingerprint().Len() != 32 {
+			t.Errorf("parsed %v with fingerprint of length %d", address.Type(), address.Fingerprint().Len())
+		}
+		if !strings.EqualFold(address.Data().HexString(), strings.TrimPrefix(hexString, "0x")) {
+			t.Errorf("parsed %v with data differing from the input", address.Type())
+		}
+	})
+}
+
+// FuzzParseDemoShortAbelAddress covers the raw data of short abel addresses, as used to match deposits.
+func FuzzParseDemoShortAbelAddress(f *testing.F) {
+	for _, seed := range demoFuzzAddressSeeds(f) {
+		data, _ := DecodeDemoHex(seed)
+		if data.Len() == core.SHORT_ABEL_ADDRESS_LENGTH {
+			f.Add(data.Slice())
+			flipped := append([]byte(nil), data.Slice()...)
+			flipped[0] ^= 0x01
+			f.Add(flipped)
+		}
+	}
+	f.Add(make([]byte, core.SHORT_ABEL_ADDRESS_LENGTH))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		shortAbelAddress, err := ParseDemoShortAbelAddress(core.AsBytes(data))
+		if err != nil {
+			return
+		}
+		if err := shortAbelAddress.Validate(); err != nil {
+			t.Errorf("parsed a short abel address which does not validate: %v", err)
+		}
+		if !bytes.Equal(shortAbelAddress.Data().Slice(), data) {
+			t.Errorf("parsed a short abel address with data differing from the input")
+		}
+	})
+}
+
+func FuzzDecodeDemoTxOut(f *testing.F) {
+	fixture := demoFuzzFixture(f)
+	for _, fixtureTx := range fixture.Txs {
+		for _, vout := range fixtureTx.Tx.Vout {
+			for _, variant := range demoFuzzCorruptions(vout.Script) {
+				f.Add(variant)
+			}
+		}
+	}
+	f.Add("")
+	f.Add("00")
+
+	f.Fuzz(func(t *testing.T, script string) {
+		txOutData, coinAddress, err := DecodeDemoTxOut(script)
+		if err != nil {
+			return
+		}
+		if txOutData.Len() == 0 {
+			t.Errorf("decoded empty tx out data from %q", script)
+		}
+		if coinAddress.Fingerprint().Len() == 0 {
+			t.Errorf("decoded coin address without fingerprint from %q", script)
+		}
+	})
+}
+
+func FuzzDecodeDemoMemo(f *testing.F) {
+	fixture := demoFuzzFixture(f)
+	for _, fixtureTx := range fixture.Txs {
+		f.Add(fixtureTx.Tx.Memo)
+	}
+	f.Add("")
+	f.Add(hex.EncodeToString([]byte("golden")))
+	f.Add(strings.Repeat("00", int(abecryptoparam.MaxAllowedTxMemoSize)))
+	f.Add(strings.Repeat("00", int(abecryptoparam.MaxAllowedTxMemoSize)+1))
+
+	f.Fuzz(func(t *testing.T, memoHex string) {
+		memo, err := DecodeDemoMemo(memoHex)
+		if err != nil {
+			return
+		}
+		if uint32(memo.Len()) > abecryptoparam.MaxAllowedTxMemoSize {
+			t.Errorf("decoded memo of length %d", memo.Len())
+		}
+	})
+}
+
+// FuzzDecodeDemoAbecTx covers the serialized tx envelope, as submitted with sendrawtransactionabe.
+func FuzzDecodeDemoAbecTx(f *testing.F) {
+	fixture := demoFuzzFixture(f)
+	for _, fixtureTx := range fixture.Txs {
+		txBytes, err := hex.DecodeString(fixtureTx.Hex)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(txBytes)
+		f.Add(txBytes[:len(txBytes)/2])
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, txBytes []byte) {
+		tx, err := DecodeDemoAbecTx(txBytes)
+		if err != nil {
+			return
+		}
+		if tx.Hex != hex.EncodeToString(txBytes) {
+			t.Errorf("decoded tx %v with hex differing from the input", tx.TxID)
+		}
+		if len(tx.TxID) != 64 {
+			t.Errorf("decoded tx with txid %q", tx.TxID)
+		}
+	})
+}
+
+// FuzzDemoMockAbecRequest covers the JSON-RPC envelope, as parsed by the mock abec node.
+func FuzzDemoMockAbecRequest(f *testing.F) {
+	fixture := demoFuzzFixture(f)
+	tipHash := fixture.Blocks[fixture.Tip()].Block.BlockHash
+	fixtureData, err := json.Marshal(fixture)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, seed := range []string{
+		`{"jsonrpc":"1.0","method":"getinfo","params":[],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getblockhash","params":[0],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getblockhash","params":[-1],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getblockabe","params":["` + tipHash + `",1],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getblockabe","params":["` + tipHash + `",0],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getblockabe","params":[1,"x"],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getrawtransaction","params":["00",true],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"getrawmempool","params":[true],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"sendrawtransactionabe","params":["zz"],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"generate","params":[2],"id":"1"}`,
+		`{"jsonrpc":"1.0","method":"unknown","params":null,"id":"1"}`,
+		`{"method":"getinfo"}`,
+		`[]`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		// Use a fresh fixture in every iteration since generate and sendrawtransactionabe change it.
+		fixture := &DemoChainFixture{}
+		if err := json.Unmarshal(fixtureData, fixture); err != nil {
+			t.Fatal(err)
+		}
+		server := NewDemoMockAbecServer(fixture, "user", "pass")
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.SetBasicAuth("user", "pass")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("unexpected status %d", recorder.Code)
+			}
+			return
+		}
+		resp := &core.AbecJSONRPCResponse{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), resp); err != nil {
+			t.Errorf("response is not a JSON-RPC envelope: %v", err)
+		}
+	})
+}
@@ -10,217 +10,662 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	core "abelian.info/sdk/core"
 )
 
-func (ds *DemoSet) DemoBasicBytes(args []string) {
-	ds.demoCase("Create Bytes by MakeBytes(length).")
-	bytes := core.MakeBytes(4)
-	fmt.Printf("bytes: %v\n", bytes)
-	fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
-
-	ds.demoCase("Create Bytes by NewBytes(data).")
-	data := []byte{0, 1, 2, 3}
-	bytes = core.AsBytes(data)
-	fmt.Printf("data: %v\n", data)
-	fmt.Printf("bytes: %v\n", bytes)
-	fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
-
-	ds.demoCase("Modifying data will change Bytes.")
-	copy(data, []byte{4, 5, 6, 7})
-	fmt.Printf("bytes: %v\n", bytes)
-	fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
-
-	ds.demoCase("Modifying bytes.Slice() will change Bytes.")
-	copy(bytes.Slice(), []byte{8, 9, 10, 11})
-	fmt.Printf("bytes: %v\n", bytes)
-	fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
-
-	ds.demoCase("Demo random Bytes.")
-	bytes = core.MakeRandomBytes(16)
-	fmt.Printf("bytes: %v\n", bytes)
-	fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
-	fmt.Printf("bytes.Len(): %v\n", bytes.Len())
-	fmt.Printf("bytes.HexString(): %v\n", bytes.HexString())
-	fmt.Printf("bytes.Base64String(): %v\n", bytes.Base64String())
-	fmt.Printf("bytes.Md5(): %v\n", bytes.Md5().HexString())
-	fmt.Printf("bytes.Sha256(): %v\n", bytes.Sha256().HexString())
-
-	ds.demoCase("Demo empty Bytes.")
-	bytes = core.MakeBytes(0)
-	fmt.Printf("bytes: %v\n", bytes)
-	fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
-	fmt.Printf("bytes.Len(): %v\n", bytes.Len())
-	fmt.Printf("bytes.HexString(): %v\n", bytes.HexString())
-	fmt.Printf("bytes.Base64String(): %v\n", bytes.Base64String())
-	fmt.Printf("bytes.Md5(): %v\n", bytes.Md5().HexString())
-	fmt.Printf("bytes.Sha256(): %v\n", bytes.Sha256().HexString())
+func (ds *DemoSet) DemoALOHA(flag *flag.FlagSet) func() error {
+	return func() error {
+		names := flag.Args()
+		if len(names) == 0 {
+			names = []string{"World"}
+		}
+
+		ds.demoCase("Say hi.")
+		fmt.Printf("Aloha, %v!\n", strings.Join(names, " "))
+		ds.demoResult("greeting", fmt.Sprintf("Aloha, %v!", strings.Join(names, " ")))
+
+		ds.demoCase("Show demo config.")
+		fmt.Printf("Demo env path: %s\n", ds.getDemoEnvPath())
+		fmt.Printf("Demo config path: %s\n", ds.getDemoConfigPath())
+		config := ds.getDemoConfig()
+
+		// Sort by key.
+		keys := make([]string, 0, len(config))
+		for key := range config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// Print config.
+		for _, key := range keys {
+			value := redactDemoConfigValue(key, config[key])
+			fmt.Printf("%s: %s\n", key, value)
+		}
+		ds.demoResult("envPath", ds.getDemoEnvPath())
+		ds.demoResult("configPath", ds.getDemoConfigPath())
+		ds.demoResult("config", redactDemoConfig(config))
+		return nil
+	}
 }
 
-func (ds *DemoSet) DemoBasicAddress(args []string) {
-	ds.demoBadCase("Create a random address with invalid data (nil).")
-	address := core.NewAddress(nil, core.ANY_ADDRESS_TYPE, core.MakeRandomBytes(32))
-	fmt.Printf("address: %v\n", address)
-	fmt.Printf("address.Validate(): %v\n", address.Validate())
-
-	ds.demoBadCase("Create a random address with invalid fingerprint.")
-	address = core.NewAddress(core.MakeRandomBytes(32), core.ANY_ADDRESS_TYPE, nil)
-	fmt.Printf("address: %v\n", address)
-	fmt.Printf("address.Validate(): %v\n", address.Validate())
-
-	ds.demoBadCase("Create a random coin address with invalid data length.")
-	coinAddress := core.NewCoinAddress(core.MakeRandomBytes(32))
-	fmt.Printf("coinAddress: %v\n", coinAddress)
-	fmt.Printf("coinAddress.Validate(): %v\n", coinAddress.Validate())
-
-	ds.demoCase("Create a valid random coin address.")
-	coinAddress = core.NewCoinAddress(core.MakeRandomBytes(core.COIN_ADDRESS_LENGTH))
-	fmt.Printf("coinAddress: %v\n", coinAddress)
-
-	ds.demoCase("Create a valid random crypto address and derive other addresses.")
-	cryptoAddressData := core.MakeRandomBytes(core.CRYPTO_ADDRESS_LENGTH)
-	copy(cryptoAddressData, []byte{0x00, 0x00, 0x00, 0x00})
-	cryptoAddress := core.NewCryptoAddress(cryptoAddressData)
-	fmt.Printf("cryptoAddress: %v\n", cryptoAddress)
-	coinAddress = cryptoAddress.GetCoinAddress()
-	fmt.Printf("coinAddress: %v\n", coinAddress)
-	chainID := int8(3)
-	abelAddress := core.NewAbelAddressFromCryptoAddress(cryptoAddress, chainID)
-	fmt.Printf("abelAddress: %v\n", abelAddress)
-	shortAbelAddress := abelAddress.GetShortAbelAddress()
-	fmt.Printf("shortAbelAddress: %v\n", shortAbelAddress)
-
-	ds.demoCase("Check if the fingerprints of the above addresses are identical.")
-	fmt.Printf("cryptoAddress.fingerprint: %v\n", cryptoAddress.Fingerprint())
-	fmt.Printf("coinAddress.fingerprint: %v\n", coinAddress.Fingerprint())
-	fmt.Printf("abelAddress.fingerprint: %v\n", abelAddress.Fingerprint())
-	fmt.Printf("shortAbelAddress.fingerprint: %v\n", shortAbelAddress.Fingerprint())
-	isIdentical := bytes.Equal(cryptoAddress.Fingerprint(), coinAddress.Fingerprint()) &&
-		bytes.Equal(cryptoAddress.Fingerprint(), abelAddress.Fingerprint()) &&
-		bytes.Equal(cryptoAddress.Fingerprint(), shortAbelAddress.Fingerprint())
-	fmt.Printf("isIdentical: %v\n", isIdentical)
-	if !isIdentical {
-		panic("fingerprint mismatch")
+func (ds *DemoSet) DemoBasicBytes(flag *flag.FlagSet) func() error {
+	return func() error {
+		ds.demoCase("Create Bytes by MakeBytes(length).")
+		bytes := core.MakeBytes(4)
+		fmt.Printf("bytes: %v\n", bytes)
+		fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
+
+		ds.demoCase("Create Bytes by NewBytes(data).")
+		data := []byte{0, 1, 2, 3}
+		bytes = core.AsBytes(data)
+		fmt.Printf("data: %v\n", data)
+		fmt.Printf("bytes: %v\n", bytes)
+		fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
+
+		ds.demoCase("Modifying data will change Bytes.")
+		copy(data, []byte{4, 5, 6, 7})
+		fmt.Printf("bytes: %v\n", bytes)
+		fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
+
+		ds.demoCase("Modifying bytes.Slice() will change Bytes.")
+		copy(bytes.Slice(), []byte{8, 9, 10, 11})
+		fmt.Printf("bytes: %v\n", bytes)
+		fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
+
+		ds.demoCase("Demo random Bytes.")
+		bytes = core.MakeRandomBytes(16)
+		fmt.Printf("bytes: %v\n", bytes)
+		fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
+		fmt.Printf("bytes.Len(): %v\n", bytes.Len())
+		fmt.Printf("bytes.HexString(): %v\n", bytes.HexString())
+		fmt.Printf("bytes.Base64String(): %v\n", bytes.Base64String())
+		fmt.Printf("bytes.Md5(): %v\n", bytes.Md5().HexString())
+		fmt.Printf("bytes.Sha256(): %v\n", bytes.Sha256().HexString())
+
+		ds.demoCase("Demo empty Bytes.")
+		bytes = core.MakeBytes(0)
+		fmt.Printf("bytes: %v\n", bytes)
+		fmt.Printf("bytes.Slice(): %v\n", bytes.Slice())
+		fmt.Printf("bytes.Len(): %v\n", bytes.Len())
+		fmt.Printf("bytes.HexString(): %v\n", bytes.HexString())
+		fmt.Printf("bytes.Base64String(): %v\n", bytes.Base64String())
+		fmt.Printf("bytes.Md5(): %v\n", bytes.Md5().HexString())
+		fmt.Printf("bytes.Sha256(): %v\n", bytes.Sha256().HexString())
+		ds.demoResult("emptySha256", bytes.Sha256().HexString())
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoBasicAddress(flag *flag.FlagSet) func() error {
+	return func() error {
+		ds.demoBadCase("Create a random address with invalid data (nil).")
+		address := core.NewAddress(nil, core.ANY_ADDRESS_TYPE, core.MakeRandomBytes(32))
+		fmt.Printf("address: %v\n", address)
+		fmt.Printf("address.Validate(): %v\n", address.Validate())
+
+		ds.demoBadCase("Create a random address with invalid fingerprint.")
+		address = core.NewAddress(core.MakeRandomBytes(32), core.ANY_ADDRESS_TYPE, nil)
+		fmt.Printf("address: %v\n", address)
+		fmt.Printf("address.Validate(): %v\n", address.Validate())
+
+		ds.demoBadCase("Create a random coin address with invalid data length.")
+		coinAddress := core.NewCoinAddress(core.MakeRandomBytes(32))
+		fmt.Printf("coinAddress: %v\n", coinAddress)
+		fmt.Printf("coinAddress.Validate(): %v\n", coinAddress.Validate())
+
+		ds.demoCase("Create a valid random coin address.")
+		coinAddress = core.NewCoinAddress(core.MakeRandomBytes(core.COIN_ADDRESS_LENGTH))
+		fmt.Printf("coinAddress: %v\n", coinAddress)
+
+		ds.demoCase("Create a valid random crypto address and derive other addresses.")
+		cryptoAddressData := core.MakeRandomBytes(core.CRYPTO_ADDRESS_LENGTH)
+		copy(cryptoAddressData, []byte{0x00, 0x00, 0x00, 0x00})
+		cryptoAddress := core.NewCryptoAddress(cryptoAddressData)
+		fmt.Printf("cryptoAddress: %v\n", cryptoAddress)
+		coinAddress = cryptoAddress.GetCoinAddress()
+		fmt.Printf("coinAddress: %v\n", coinAddress)
+		chainID := int8(3)
+		abelAddress := core.NewAbelAddressFromCryptoAddress(cryptoAddress, chainID)
+		fmt.Printf("abelAddress: %v\n", abelAddress)
+		shortAbelAddress := abelAddress.GetShortAbelAddress()
+		fmt.Printf("shortAbelAddress: %v\n", shortAbelAddress)
+
+		ds.demoCase("Check if the fingerprints of the above addresses are identical.")
+		fmt.Printf("cryptoAddress.fingerprint: %v\n", cryptoAddress.Fingerprint())
+		fmt.Printf("coinAddress.fingerprint: %v\n", coinAddress.Fingerprint())
+		fmt.Printf("abelAddress.fingerprint: %v\n", abelAddress.Fingerprint())
+		fmt.Printf("shortAbelAddress.fingerprint: %v\n", shortAbelAddress.Fingerprint())
+		isIdentical := bytes.Equal(cryptoAddress.Fingerprint(), coinAddress.Fingerprint()) &&
+			bytes.Equal(cryptoAddress.Fingerprint(), abelAddress.Fingerprint()) &&
+			bytes.Equal(cryptoAddress.Fingerprint(), shortAbelAddress.Fingerprint())
+		fmt.Printf("isIdentical: %v\n", isIdentical)
+		ds.demoResult("fingerprint", cryptoAddress.Fingerprint().HexString())
+		ds.demoResult("isIdentical", isIdentical)
+		if !isIdentical {
+			return fmt.Errorf("fingerprint mismatch")
+		}
+		return nil
 	}
 }
 
-func (ds *DemoSet) DemoBasicGenerateAccounts(args []string) {
-	// Parse demo args.
-	flag := flag.NewFlagSet("GenerateAccounts", flag.ContinueOnError)
+func (ds *DemoSet) DemoBasicGenerateAccounts(flag *flag.FlagSet) func() error {
+	// Declare demo args.
 	chainID := flag.Int("chainID", int(ds.getDemoChainID()), "Chain ID (0 ~ 15).")
 	count := flag.Int("count", 10, "Number of accounts to generate.")
-	ds.demoExitOnError(flag.Parse(args))
-
-	type Account struct {
-		SeqNo             int    `json:"No."`
-		CryptoSeed        string `json:"CryptoSeed"`
-		SpendSecretKey    string `json:"SerializedASksp"`
-		SerialNoSecretKey string `json:"SerializedASksn"`
-		ViewSecretKey     string `json:"SerializedVSk"`
-		CryptoAddress     string `json:"CryptoAddress"`
+	return func() error {
+		type Account struct {
+			SeqNo             int    `json:"No."`
+			CryptoSeed        string `json:"CryptoSeed"`
+			SpendSecretKey    string `json:"SerializedASksp"`
+			SerialNoSecretKey string `json:"SerializedASksn"`
+			ViewSecretKey     string `json:"SerializedVSk"`
+			CryptoAddress     string `json:"CryptoAddress"`
+		}
+
+		type AccountsFileData struct {
+			Info struct {
+				CryptoVersion int    `json:"crypto_version"`
+				Mnemonics     string `json:"mnemonic_list"`
+				Network       string `json:"network"`
+				NetID         int    `json:"netID"`
+			} `json:"info"`
+			Accounts []*Account `json:"addresses"`
+		}
+
+		ds.demoCase("Generate %d accounts for chain %d.", *count, *chainID)
+		accounts := make([]*Account, *count)
+		for i := 0; i < *count; i++ {
+			accounts[i] = &Account{SeqNo: i}
+
+			cryptoSeed, err := core.GenerateSafeCryptoSeed()
+			if err != nil {
+				return err
+			}
+			accounts[i].CryptoSeed = cryptoSeed.HexString()
+
+			keysAndAddress, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
+			if err != nil {
+				return err
+			}
+			accounts[i].SpendSecretKey = keysAndAddress.SpendSecretKey.HexString()
+			accounts[i].SerialNoSecretKey = keysAndAddress.SerialNoSecretKey.HexString()
+			accounts[i].ViewSecretKey = keysAndAddress.ViewSecretKey.HexString()
+			accounts[i].CryptoAddress = keysAndAddress.CryptoAddress.HexString()
+		}
+
+		outputPath := ds.getDemoFilePath(fmt.Sprintf("accounts-chain-%d.json", *chainID))
+		ds.demoCase("Save the accounts to %s.", outputPath)
+		// Create data to save.
+		accountsFileData := &AccountsFileData{}
+		accountsFileData.Info.CryptoVersion = 0
+		accountsFileData.Info.Mnemonics = "none"
+		accountsFileData.Info.Network = "unknown"
+		if chain := findDemoChain(int8(*chainID)); chain != nil {
+			accountsFileData.Info.Network = chain.Name
+		}
+		accountsFileData.Info.NetID = *chainID
+		accountsFileData.Accounts = accounts
+		// Save data to file.
+		accountsFileDataBytes, err := json.MarshalIndent(accountsFileData, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = writeDemoSecretFile(outputPath, accountsFileDataBytes)
+		if err != nil {
+			return err
+		}
+		ds.demoResult("chainID", *chainID)
+		ds.demoResult("count", *count)
+		ds.demoResult("outputFile", outputPath)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoBasicDemoAccounts(flag *flag.FlagSet) func() error {
+	return func() error {
+		chainID := ds.getDemoChainID()
+		ds.demoCase("Load all demo accounts for chain %d.", chainID)
+		accounts, err := ds.getDemoAccounts()
+		if err != nil {
+			return err
+		}
+		// List the accounts by seqno, the order of the map is random.
+		seqnos := make([]int, 0, len(accounts))
+		for i := range accounts {
+			seqnos = append(seqnos, i)
+		}
+		sort.Ints(seqnos)
+		accountResults := make([]map[string]interface{}, 0, len(accounts))
+		for _, i := range seqnos {
+			fmt.Printf("accounts[%d]: seqno=%v, shortAddress=%v\n", i, accounts[i].SerialNo, accounts[i].ShortAbelAddress)
+			accountResults = append(accountResults, map[string]interface{}{
+				"seqno":            accounts[i].SerialNo,
+				"shortAbelAddress": accounts[i].ShortAbelAddress.HexString(),
+			})
+		}
+
+		ds.demoCase("Dump the addresses of demo accounts.")
+		outputDir := ds.getDemoFilePath("accounts")
+		os.MkdirAll(outputDir, 0755)
+		for _, i := range seqnos {
+			outputPath := ds.getDemoFilePath(fmt.Sprintf("accounts/chain-%d-account-%d.abeladdress", chainID, i))
+			content := fmt.Sprintf("%s\n%s\n", accounts[i].AbelAddress.HexString(), accounts[i].ShortAbelAddress.HexString())
+			file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = file.WriteString(content)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Dumped %d addresses to %s.\n", len(accounts), outputDir)
+		ds.demoResult("chainID", chainID)
+		ds.demoResult("accounts", accountResults)
+		ds.demoResult("outputDir", outputDir)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoBasicChains(flag *flag.FlagSet) func() error {
+	return func() error {
+		ds.demoCase("List the registered chains.")
+		chainResults := make([]map[string]interface{}, 0, len(demoChains))
+		for _, chain := range demoChains {
+			endpoints := make([]string, len(chain.Endpoints))
+			for i, endpoint := range chain.Endpoints {
+				endpoints[i] = redactDemoURL(endpoint)
+			}
+			fmt.Printf("chain %d: %s, %s\n", chain.ID, chain.Name, chain.Description)
+			fmt.Printf("  endpoints: %v, rpcPort: %d\n", endpoints, chain.RPCPort)
+			fmt.Printf("  abelAddressPrefix: %s, shortAbelAddressPrefix: %s\n", chain.AbelAddressPrefix().HexString(), chain.ShortAbelAddressPrefix().HexString())
+			fmt.Printf("  ringSize: %d, ringBlockNum: %d\n", chain.RingSize, chain.RingBlockNum)
+			chainResults = append(chainResults, map[string]interface{}{
+				"id":                     chain.ID,
+				"name":                   chain.Name,
+				"endpoints":              endpoints,
+				"rpcPort":                chain.RPCPort,
+				"abelAddressPrefix":      chain.AbelAddressPrefix().HexString(),
+				"shortAbelAddressPrefix": chain.ShortAbelAddressPrefix().HexString(),
+				"ringSize":               chain.RingSize,
+				"ringBlockNum":           chain.RingBlockNum,
+			})
+		}
+		ds.demoResult("chains", chainResults)
+
+		chainID := ds.getDemoChainID()
+		chain := findDemoChain(chainID)
+		if chain == nil {
+			ds.demoBadCase("The configured chain %d is not registered.", chainID)
+			ds.demoResult("chainID", chainID)
+			return nil
+		}
+
+		ds.demoCase("Check the addresses of the first demo account against the configured chain %s.", chain.Name)
+		accounts, err := ds.getDemoAccounts()
+		if err != nil {
+			return err
+		}
+		account, ok := accounts[0]
+		if !ok {
+			return newDemoError(DemoErrorValidation, "demo account 0 does not exist for chain %d, generate it with `BasicGenerateAccounts -chainID %d`", chainID, chainID)
+		}
+		fmt.Printf("abelAddress: %v\n", account.AbelAddress)
+		fmt.Printf("abelAddress.CheckAbelAddress(): %v\n", chain.CheckAbelAddress(account.AbelAddress))
+		fmt.Printf("shortAbelAddress: %v\n", account.ShortAbelAddress)
+		fmt.Printf("shortAbelAddress.CheckShortAbelAddress(): %v\n", chain.CheckShortAbelAddress(account.ShortAbelAddress))
+		if err := chain.CheckAbelAddress(account.AbelAddress); err != nil {
+			return err
+		}
+		if err := chain.CheckShortAbelAddress(account.ShortAbelAddress); err != nil {
+			return err
+		}
+
+		for _, other := range demoChains {
+			if other == chain {
+				continue
+			}
+			ds.demoBadCase("Check the same addresses against the %s.", other.Name)
+			fmt.Printf("abelAddress.CheckAbelAddress(): %v\n", other.CheckAbelAddress(account.AbelAddress))
+			fmt.Printf("shortAbelAddress.CheckShortAbelAddress(): %v\n", other.CheckShortAbelAddress(account.ShortAbelAddress))
+		}
+		ds.demoResult("chainID", chainID)
+		ds.demoResult("abelAddress", account.AbelAddress.HexString())
+		return nil
 	}
+}
+
+func (ds *DemoSet) DemoBasicAbecRPCClient(flag *flag.FlagSet) func() error {
+	return func() error {
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Call GetChainInfo().")
+		respBytes, chainInfo, err := client.GetChainInfo()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Response bytes: %s\n", respBytes)
+		fmt.Printf("Response value: %+v\n", *chainInfo)
+
+		ds.demoCase("Call GetBlockHash(0).")
+		respBytes, blockHash0, err := client.GetBlockHash(0)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Response bytes: %s\n", respBytes)
+		fmt.Printf("Response value: %+v\n", *blockHash0)
 
-	type AccountsFileData struct {
-		Info struct {
-			CryptoVersion int    `json:"crypto_version"`
-			Mnemonics     string `json:"mnemonic_list"`
-			Network       string `json:"network"`
-			NetID         int    `json:"netID"`
-		} `json:"info"`
-		Accounts []*Account `json:"addresses"`
+		ds.demoCase("Call GetBlockHash(1).")
+		respBytes, blockHash1, err := client.GetBlockHash(1)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Response bytes: %s\n", respBytes)
+		fmt.Printf("Response value: %+v\n", *blockHash1)
+
+		ds.demoCase("Call GetBlock(%v).", blockHash1)
+		respBytes, block, err := client.GetBlock(*blockHash1)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("TxHashes: %v\n", block.TxHashes)
+
+		ds.demoCase("Call GetRawTx(%s).", block.TxHashes[0])
+		respBytes, rawTx, err := client.GetRawTx(block.TxHashes[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Response bytes: %s|| ... omitted ... ||%s\n", respBytes.Slice()[:1024], respBytes.Slice()[respBytes.Len()-1024:])
+		fmt.Printf("Response value: %+v\n", *rawTx)
+		ds.demoResult("chainInfo", chainInfo)
+		ds.demoResult("blockHashes", []string{*blockHash0, *blockHash1})
+		ds.demoResult("txid", block.TxHashes[0])
+		return nil
 	}
+}
+
+func (ds *DemoSet) DemoBasicRPCEndpoints(flag *flag.FlagSet) func() error {
+	return func() error {
+		client, err := ds.getDemoAbecRPCEndpointClient()
+		if err != nil {
+			return err
+		}
+		pool, ok := client.(*DemoRPCPool)
+		if !ok {
+			ds.demoCase("Only one endpoint is configured, add abec.rpc.endpoint.N to the demo config for failover.")
+			endpoint := redactDemoURL(ds.getDemoConfigStringValue("abec.rpc.endpoint"))
+			fmt.Printf("endpoint: %s\n", endpoint)
+			ds.demoResult("endpoints", []string{endpoint})
+			return nil
+		}
 
-	ds.demoCase("Generate %d accounts for chain %d.", *count, *chainID)
-	accounts := make([]*Account, *count)
-	for i := 0; i < *count; i++ {
-		accounts[i] = &Account{SeqNo: i}
-
-		cryptoSeed, err := core.GenerateSafeCryptoSeed()
-		ds.demoCheck(err)
-		accounts[i].CryptoSeed = cryptoSeed.HexString()
-
-		keysAndAddress, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
-		ds.demoCheck(err)
-		accounts[i].SpendSecretKey = keysAndAddress.SpendSecretKey.HexString()
-		accounts[i].SerialNoSecretKey = keysAndAddress.SerialNoSecretKey.HexString()
-		accounts[i].ViewSecretKey = keysAndAddress.ViewSecretKey.HexString()
-		accounts[i].CryptoAddress = keysAndAddress.CryptoAddress.HexString()
+		ds.demoCase("Check the health of all configured endpoints.")
+		endpointResults := make([]map[string]interface{}, 0)
+		for _, endpoint := range pool.CheckHealth() {
+			endpointResult := map[string]interface{}{
+				"endpoint":  redactDemoURL(endpoint.Endpoint),
+				"healthy":   endpoint.Healthy,
+				"tip":       endpoint.Tip,
+				"latencyMs": endpoint.Latency.Milliseconds(),
+			}
+			if endpoint.LastError != nil {
+				endpointResult["error"] = endpoint.LastError.Error()
+			}
+			endpointResults = append(endpointResults, endpointResult)
+			fmt.Printf("endpoint: %s, healthy: %v, tip: %d, latency: %v", redactDemoURL(endpoint.Endpoint), endpoint.Healthy, endpoint.Tip, endpoint.Latency)
+			if endpoint.LastError != nil {
+				fmt.Printf(", error: %v", endpoint.LastError)
+			}
+			fmt.Printf("\n")
+		}
+
+		ds.demoCase("Call GetChainInfo() through the pool.")
+		_, chainInfo, err := pool.GetChainInfo()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Response value: %+v\n", *chainInfo)
+		ds.demoResult("endpoints", endpointResults)
+		ds.demoResult("chainInfo", chainInfo)
+		return nil
 	}
+}
 
-	outputPath := ds.getDemoFilePath(fmt.Sprintf("accounts-chain-%d.json", *chainID))
-	ds.demoCase("Save the accounts to %s.", outputPath)
-	// Create data to save.
-	accountsFileData := &AccountsFileData{}
-	accountsFileData.Info.CryptoVersion = 0
-	accountsFileData.Info.Mnemonics = "none"
-	if *chainID == 0 {
-		accountsFileData.Info.Network = "mainnet"
-	} else if *chainID == 3 {
-		accountsFileData.Info.Network = "simnet"
-	} else {
-		accountsFileData.Info.Network = "unknown"
+func (ds *DemoSet) DemoBasicRPCCache(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	clearArg := flag.Bool("clear", false, "Remove all cached blocks and txs.")
+	return func() error {
+		cacheDir := ds.getDemoRPCCacheDir()
+		if *clearArg {
+			ds.demoCase("Clear the RPC cache in %s.", cacheDir)
+			if err := os.RemoveAll(cacheDir); err != nil {
+				return err
+			}
+		}
+
+		ds.demoCase("Show the content of the RPC cache in %s.", cacheDir)
+		entries := make(map[string]int)
+		for _, kind := range []string{"heights", "blocks", "blockbytes", "txs"} {
+			count, size := 0, int64(0)
+			filepath.Walk(filepath.Join(cacheDir, kind), func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					count += 1
+					size += info.Size()
+				}
+				return nil
+			})
+			fmt.Printf("%s: %d entries, %d bytes\n", kind, count, size)
+			entries[kind] = count
+		}
+
+		ds.demoCase("Fetch block 0 twice through the cache.")
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 2; i++ {
+			_, blockHash, err := client.GetBlockHash(0)
+			if err != nil {
+				return err
+			}
+			_, block, err := client.GetBlock(*blockHash)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("block %d: hash=%v, confirmations=%d\n", block.Height, block.BlockHash, block.Confirmations)
+		}
+		if cache, ok := client.(*DemoRPCCache); ok {
+			fmt.Printf("cache hits: %d, misses: %d\n", cache.Hits, cache.Misses)
+			ds.demoResult("hits", cache.Hits)
+			ds.demoResult("misses", cache.Misses)
+		}
+		ds.demoResult("cacheDir", cacheDir)
+		ds.demoResult("entries", entries)
+		return nil
 	}
-	accountsFileData.Info.NetID = *chainID
-	accountsFileData.Accounts = accounts
-	// Save data to file.
-	accountsFileDataBytes, err := json.MarshalIndent(accountsFileData, "", "  ")
-	ds.demoCheck(err)
-	err = os.WriteFile(outputPath, accountsFileDataBytes, 0644)
-	ds.demoCheck(err)
 }
 
-func (ds *DemoSet) DemoBasicDemoAccounts(args []string) {
-	chainID := ds.getDemoChainID()
-	ds.demoCase("Load all demo accounts for chain %d.", chainID)
-	accounts := ds.getDemoAccounts()
-	for i := range accounts {
-		fmt.Printf("accounts[%d]: seqno=%v, shortAddress=%v\n", i, accounts[i].SerialNo, accounts[i].ShortAbelAddress)
+func (ds *DemoSet) DemoBasicRPCCassette(flag *flag.FlagSet) func() error {
+	// Load default args from demo config.
+	defaultFile := ds.getDemoConfigStringValue("abec.rpc.replay")
+	if defaultFile == "" {
+		defaultFile = ds.getDemoConfigStringValue("abec.rpc.record")
 	}
 
-	ds.demoCase("Dump the addresses of demo accounts.")
-	outputDir := ds.getDemoFilePath("accounts")
-	os.MkdirAll(outputDir, 0755)
-	for i := range accounts {
-		outputPath := ds.getDemoFilePath(fmt.Sprintf("accounts/chain-%d-account-%d.abeladdress", chainID, i))
-		content := fmt.Sprintf("%s\n%s\n", accounts[i].AbelAddress.HexString(), accounts[i].ShortAbelAddress.HexString())
-		file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-		defer file.Close()
-		_, err = file.WriteString(content)
-		ds.demoCheck(err)
+	// Declare demo args.
+	fileArg := flag.String("file", defaultFile, "Cassette file to show.")
+	return func() error {
+		if *fileArg == "" {
+			return newDemoError(DemoErrorUsage, "no cassette, set abec.rpc.record in the demo config to record one")
+		}
+
+		ds.demoCase("Load the cassette %s.", ds.getDemoCassettePath(*fileArg))
+		interactions, err := LoadDemoRPCCassette(ds.getDemoCassettePath(*fileArg))
+		if err != nil {
+			return err
+		}
+		ds.demoResult("file", ds.getDemoCassettePath(*fileArg))
+		ds.demoResult("interactions", len(interactions))
+		if len(interactions) == 0 {
+			fmt.Printf("The cassette is empty.\n")
+			return nil
+		}
+		fmt.Printf("interactions: %d, recorded from %v to %v\n", len(interactions), interactions[0].Time, interactions[len(interactions)-1].Time)
+
+		ds.demoCase("Count the interactions by method.")
+		counts := make(map[string]int)
+		errorCounts := make(map[string]int)
+		var methods []string
+		for _, interaction := range interactions {
+			if counts[interaction.Method] == 0 {
+				methods = append(methods, interaction.Method)
+			}
+			counts[interaction.Method] += 1
+			if interaction.Error != "" {
+				errorCounts[interaction.Method] += 1
+			}
+		}
+		for _, method := range methods {
+			fmt.Printf("%s: %d calls, %d errors\n", method, counts[method], errorCounts[method])
+		}
+		ds.demoResult("calls", counts)
+		ds.demoResult("errors", errorCounts)
+
+		ds.demoCase("Replay the first getinfo call.")
+		replayer := NewDemoRPCReplayer(interactions)
+		_, chainInfo, err := replayer.GetChainInfo()
+		if err != nil {
+			fmt.Printf("getinfo: %v\n", err)
+		} else {
+			fmt.Printf("getinfo: %+v\n", *chainInfo)
+		}
+		return nil
 	}
-	fmt.Printf("Dumped %d addresses to %s.\n", len(accounts), outputDir)
 }
 
-func (ds *DemoSet) DemoBasicAbecRPCClient(args []string) {
-	client := ds.getDemoAbecRPCClient()
-
-	ds.demoCase("Call GetChainInfo().")
-	respBytes, chainInfo, err := client.GetChainInfo()
-	ds.demoCheck(err)
-	fmt.Printf("Response bytes: %s\n", respBytes)
-	fmt.Printf("Response value: %+v\n", *chainInfo)
-
-	ds.demoCase("Call GetBlockHash(0).")
-	respBytes, blockHash0, err := client.GetBlockHash(0)
-	ds.demoCheck(err)
-	fmt.Printf("Response bytes: %s\n", respBytes)
-	fmt.Printf("Response value: %+v\n", *blockHash0)
-
-	ds.demoCase("Call GetBlockHash(1).")
-	respBytes, blockHash1, err := client.GetBlockHash(1)
-	ds.demoCheck(err)
-	fmt.Printf("Response bytes: %s\n", respBytes)
-	fmt.Printf("Response value: %+v\n", *blockHash1)
-
-	ds.demoCase("Call GetBlock(%v).", blockHash1)
-	respBytes, block, err := client.GetBlock(*blockHash1)
-	ds.demoCheck(err)
-	fmt.Printf("TxHashes: %v\n", block.TxHashes)
-
-	ds.demoCase("Call GetRawTx(%s).", block.TxHashes[0])
-	respBytes, rawTx, err := client.GetRawTx(block.TxHashes[0])
-	ds.demoCheck(err)
-	fmt.Printf("Response bytes: %s|| ... omitted ... ||%s\n", respBytes.Slice()[:1024], respBytes.Slice()[respBytes.Len()-1024:])
-	fmt.Printf("Response value: %+v\n", *rawTx)
+func (ds *DemoSet) DemoBasicGenerateChainFixture(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	outputDir := flag.String("outputDir", ds.getDemoFixturePath(), "Directory to save the chain fixture to.")
+	numBlocks := flag.Int64("blocks", 30, "Number of blocks to generate.")
+	accountsArg := flag.String("accounts", "0,1,2,3", "Seqnos of the accounts to pay.")
+	return func() error {
+		ds.demoCase("Process demo args.")
+		accounts, err := ds.getDemoAccountsBySeqnos("-accounts", *accountsArg)
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			fmt.Printf("account %v: %v\n", account.SerialNo, account.ShortAbelAddress)
+		}
+		fmt.Printf("blocks: %d, output dir: %s\n", *numBlocks, *outputDir)
+
+		ds.demoCase("Generate the chain.")
+		spec := &DemoChainFixtureSpec{
+			ChainID:       ds.getDemoChainID(),
+			NumBlocks:     *numBlocks,
+			Accounts:      accounts,
+			CoinbaseValue: core.AbelToNeutrino(256),
+			TxFee:         core.AbelToNeutrino(0.1),
+			GenesisTime:   1700000000,
+			BlockInterval: 256,
+		}
+		fixture, err := GenerateDemoChainFixture(spec)
+		if err != nil {
+			return err
+		}
+		for _, fixtureBlock := range fixture.Blocks {
+			fmt.Printf("block %d: hash=%s, txs=%d\n", fixtureBlock.Block.Height, fixtureBlock.Block.BlockHash, len(fixtureBlock.Block.TxHashes))
+		}
+
+		ds.demoCase("Show the coins paid to the accounts.")
+		for _, coin := range fixture.Coins {
+			fmt.Printf("coin %s: owner=%d, value=%v ABEL, height=%d", coin.ID, coin.Owner, core.NeutrinoToAbel(coin.Value), coin.Height)
+			if coin.SpentTxid != "" {
+				fmt.Printf(", 💰 spent in %s at height %d", coin.SpentTxid, coin.SpentHeight)
+			}
+			fmt.Printf("\n")
+		}
+
+		ds.demoCase("Save the chain fixture to %s.", *outputDir)
+		if err := fixture.Save(*outputDir); err != nil {
+			return err
+		}
+		ds.demoResult("outputDir", *outputDir)
+		ds.demoResult("tip", fixture.Tip())
+		ds.demoResult("txs", len(fixture.Txs))
+		ds.demoResult("coins", fixture.Coins)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoBasicMockAbecServer(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	fixtureDir := flag.String("fixture", ds.getDemoFixturePath(), "Directory of the chain fixture to serve.")
+	record := flag.Int64("record", 0, "Record the first N blocks from the configured node into the fixture before serving.")
+	listen := flag.String("listen", "127.0.0.1:18667", "Address to listen on.")
+	blockInterval := flag.Duration("blockInterval", 0, "Mine a block with the mempool txs at this interval, 0 to disable.")
+	return func() error {
+		if *record > 0 {
+			ds.demoCase("Record the first %d blocks from the configured node into %s.", *record, *fixtureDir)
+			client, err := ds.getDemoAbecRPCClient()
+			if err != nil {
+				return err
+			}
+			fixture, err := RecordDemoChainFixture(client, *record)
+			if err != nil {
+				return err
+			}
+			if err := fixture.Save(*fixtureDir); err != nil {
+				return err
+			}
+			fmt.Printf("Recorded %d blocks and %d txs.\n", len(fixture.Blocks), len(fixture.Txs))
+		}
+
+		ds.demoCase("Load the chain fixture in %s.", *fixtureDir)
+		fixture, err := LoadDemoChainFixture(*fixtureDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("tip: %d, txs: %d\n", fixture.Tip(), len(fixture.Txs))
+
+		ds.demoCase("Serve the chain fixture as a mock abec node.")
+		server := NewDemoMockAbecServer(fixture, ds.getDemoConfigStringValue("abec.rpc.username"), ds.getDemoConfigStringValue("abec.rpc.password"))
+		if err := server.Start(*listen); err != nil {
+			return err
+		}
+		defer server.Close()
+		fmt.Printf("Listening on %s, set abec.rpc.endpoint to this URL to use it. Press Ctrl-C to stop.\n", server.URL)
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		var ticker <-chan time.Time
+		if *blockInterval > 0 {
+			ticker = time.NewTicker(*blockInterval).C
+		}
+		for {
+			select {
+			case <-interrupt:
+				fmt.Printf("Served %d requests.\n", server.Requests())
+				ds.demoResult("url", server.URL)
+				ds.demoResult("requests", server.Requests())
+				return nil
+			case <-ticker:
+				hashes, err := server.Generate(1)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Mined block %s.\n", hashes[0])
+			}
+		}
+	}
 }
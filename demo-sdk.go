@@ -7,519 +7,723 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	core "abelian.info/sdk/core"
 )
 
-func (ds *DemoSet) DemoSDKGetChainInfo(args []string) {
-	client := ds.getDemoAbecRPCClient()
+func (ds *DemoSet) DemoSDKGetChainInfo(flag *flag.FlagSet) func() error {
+	return func() error {
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
 
-	ds.demoCase("Get the latest status of the chain.")
-	_, chainInfo, err := client.GetChainInfo()
-	ds.demoCheck(err)
-	fmt.Printf("Result: %+v\n", *chainInfo)
+		ds.demoCase("Get the latest status of the chain.")
+		_, chainInfo, err := client.GetChainInfo()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Result: %+v\n", *chainInfo)
+		ds.demoResult("chainInfo", chainInfo)
+		return nil
+	}
 }
 
-func (ds *DemoSet) DemoSDKGetMempool(args []string) {
-	client := ds.getDemoAbecRPCClient()
+func (ds *DemoSet) DemoSDKGetMempool(flag *flag.FlagSet) func() error {
+	return func() error {
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
 
-	ds.demoCase("Get the mempool of the chain.")
-	_, chainInfo, err := client.GetMempool()
-	ds.demoCheck(err)
-	fmt.Printf("Result: %+v\n", *chainInfo)
+		ds.demoCase("Get the mempool of the chain.")
+		_, chainInfo, err := client.GetMempool()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Result: %+v\n", *chainInfo)
+		ds.demoResult("mempool", chainInfo)
+		return nil
+	}
 }
 
-func (ds *DemoSet) DemoSDKGetBlockOrTx(args []string) {
-	// Parse demo args.
-	if len(args) != 1 {
-		args = []string{"-h"}
-	}
-	flag := flag.NewFlagSet("SDKGetBlockOrTx", flag.ContinueOnError)
-	flag.Usage = func() {
-		fmt.Printf("Usage: %s <height-or-hash>\n", flag.Name())
-	}
-	ds.demoExitOnError(flag.Parse(args))
+func (ds *DemoSet) DemoSDKWatchMempool(flag *flag.FlagSet) func() error {
+	// Load default args from demo config.
+	defaultAccounts := ds.getDemoConfigStringValue("wallet.accounts")
+	defaultInterval, intervalErr := ds.getDemoConfigDurationValue("mempool.pollInterval")
+
+	// Declare demo args.
+	accountsArg := flag.String("accounts", defaultAccounts, "Seqnos of the demo accounts to watch, all of them if empty.")
+	intervalArg := flag.Duration("interval", defaultInterval, "Interval between polls of the mempool.")
+	roundsArg := flag.Int("rounds", 1, "Number of polls, 0 to run forever.")
+	return func() error {
+		// The config is only checked before running the demo.
+		if intervalErr != nil {
+			return intervalErr
+		}
 
-	keyword := flag.Arg(0)
-	if strings.HasPrefix(keyword, "0x") {
-		keyword = keyword[2:]
-	}
+		var accounts []*DemoAccount
+		var err error
+		if *accountsArg == "" {
+			demoAccounts, err := ds.getDemoAccounts()
+			if err != nil {
+				return err
+			}
+			for _, account := range demoAccounts {
+				accounts = append(accounts, account)
+			}
+			sort.Slice(accounts, func(i, j int) bool { return accounts[i].SerialNo < accounts[j].SerialNo })
+		} else {
+			accounts, err = ds.getDemoAccountsBySeqnos("-accounts", *accountsArg)
+			if err != nil {
+				return err
+			}
+		}
 
-	isHeight := false
-	height, err := strconv.ParseInt(keyword, 10, 64)
-	if err == nil {
-		isHeight = true
-	}
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+		watcher := NewDemoMempoolWatcher(client, accounts)
+		webhooks, err := ds.getDemoWebhookQueue()
+		if err != nil {
+			return err
+		}
 
-	// Create RPC client.
-	client := ds.getDemoAbecRPCClient()
+		events := make([]*DemoMempoolEvent, 0)
+		for round := 1; *roundsArg == 0 || round <= *roundsArg; round++ {
+			if round > 1 {
+				time.Sleep(*intervalArg)
+			}
 
-	var hash string
-	if isHeight {
-		ds.demoCase("Get block hash for height %v.", height)
-		_, blockHash, err := client.GetBlockHash(height)
-		ds.demoCheck(err)
-		hash = *blockHash
-		fmt.Printf("Block hash: 0x%v\n", hash)
-	} else {
-		hash = keyword
+			ds.demoCase("Round %d: poll the mempool for txs of the watched accounts.", round)
+			// Reload the coin store, which may be synced by another process meanwhile.
+			coinStore, err := ds.getDemoCoinStore()
+			if err != nil {
+				return err
+			}
+			polled, pollErr := watcher.Poll(coinStore, func(event *DemoMempoolEvent) error {
+				switch event.Type {
+				case DemoWebhookCoinPendingReceive:
+					fmt.Printf("  ⏳ Account %d is receiving %v ABEL in tx %v.\n", event.Coin.AccountSeqNo, core.NeutrinoToAbel(event.Coin.Value), event.Txid)
+				case DemoWebhookCoinPendingSpend:
+					fmt.Printf("  ⏳ Coin %v of account %d is being spent by tx %v.\n", event.Coin.ID, event.Coin.AccountSeqNo, event.Txid)
+				}
+				_, err := webhooks.AddEvent(&DemoWebhookEvent{
+					ID:      fmt.Sprintf("%s:%s:%s", event.Type, event.Coin.ID, event.Txid),
+					Type:    event.Type,
+					ChainID: ds.getDemoChainID(),
+					Coin:    event.Coin,
+					Txid:    event.Txid,
+				})
+				return err
+			})
+			fmt.Printf("Found %d pending events.\n", len(polled))
+			events = append(events, polled...)
+
+			if webhooks.Enabled() {
+				if err := webhooks.Save(); err != nil {
+					return err
+				}
+				ds.demoResultWebhookAttempts(webhooks.Deliver())
+				if err := webhooks.Save(); err != nil {
+					return err
+				}
+			}
+			// A transient failure leaves the txs not inspected to the next poll, the last poll exits on any failure.
+			if IsDemoRPCTransient(pollErr) && (*roundsArg == 0 || round < *roundsArg) {
+				fmt.Printf("  failed to poll the mempool: %v\n", pollErr)
+			} else {
+				if pollErr != nil {
+					return pollErr
+				}
+			}
+			ds.demoResult("rounds", round)
+			ds.demoResult("events", events)
+		}
+		return nil
 	}
+}
 
-	ds.demoCase("Get block with hash 0x%v.", hash)
-	var isBlock bool
-	_, block, err := client.GetBlock(hash)
-	if err == nil {
-		isBlock = true
-		fmt.Printf("Result: %+v\n", *block)
-	} else {
-		isBlock = false
-		fmt.Printf("Failed to get block: %v\n", err)
-	}
+func (ds *DemoSet) DemoSDKGetBlockOrTx(flag *flag.FlagSet) func() error {
+	return func() error {
+		keyword := flag.Arg(0)
+		if strings.HasPrefix(keyword, "0x") {
+			keyword = keyword[2:]
+		}
+
+		isHeight := false
+		height, err := strconv.ParseInt(keyword, 10, 64)
+		if err == nil {
+			isHeight = true
+		}
+
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+
+		var hash string
+		if isHeight {
+			ds.demoCase("Get block hash for height %v.", height)
+			_, blockHash, err := client.GetBlockHash(height)
+			if err != nil {
+				return err
+			}
+			hash = *blockHash
+			fmt.Printf("Block hash: 0x%v\n", hash)
+		} else {
+			hash = keyword
+		}
 
-	if !isBlock {
-		ds.demoCase("Get transaction with hash 0x%v.", hash)
-		_, tx, err := client.GetRawTx(hash)
+		ds.demoCase("Get block with hash 0x%v.", hash)
+		var isBlock bool
+		_, block, err := client.GetBlock(hash)
 		if err == nil {
-			fmt.Printf("Result: %+v\n", *tx)
+			isBlock = true
+			fmt.Printf("Result: %+v\n", *block)
+			ds.demoResult("block", block)
 		} else {
-			fmt.Printf("Failed to get transaction: %v\n", err)
+			isBlock = false
+			fmt.Printf("Failed to get block: %v\n", err)
+		}
+
+		if !isBlock {
+			ds.demoCase("Get transaction with hash 0x%v.", hash)
+			_, tx, err := client.GetRawTx(hash)
+			if err == nil {
+				fmt.Printf("Result: %+v\n", *tx)
+				ds.demoResult("tx", tx)
+			} else {
+				fmt.Printf("Failed to get transaction: %v\n", err)
+			}
 		}
+		return nil
 	}
 }
 
-func (ds *DemoSet) DemoSDKGetMemoInTx(args []string) {
-	// Parse demo args.
-	if len(args) != 1 {
-		args = []string{"-h"}
-	}
-	flag := flag.NewFlagSet("SDKGetMemoInTx", flag.ContinueOnError)
-	flag.Usage = func() {
-		fmt.Printf("Usage: %s <transaction-hash>\n", flag.Name())
-	}
-	ds.demoExitOnError(flag.Parse(args))
+func (ds *DemoSet) DemoSDKGetMemoInTx(flag *flag.FlagSet) func() error {
+	return func() error {
+		txHash := flag.Arg(0)
+		if strings.HasPrefix(txHash, "0x") {
+			txHash = txHash[2:]
+		}
 
-	txHash := flag.Arg(0)
-	if strings.HasPrefix(txHash, "0x") {
-		txHash = txHash[2:]
-	}
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
 
-	// Create RPC client.
-	client := ds.getDemoAbecRPCClient()
+		ds.demoCase("Get transaction with hash 0x%v.", txHash)
+		_, tx, err := client.GetRawTx(txHash)
+		if err == nil {
+			memo, err := DecodeDemoMemo(tx.Memo)
+			if err != nil {
+				return err
+			}
+			txMemo := memo.Slice()
+			fmt.Printf("Memo with binary:%b\n", txMemo)
+			fmt.Printf("Memo with byte:%v\n", txMemo)
+			fmt.Printf("Memo with raw string:%s\n", txMemo)
+			ds.demoResult("txid", txHash)
+			ds.demoResult("memo", memo.HexString())
+			ds.demoResult("memoString", string(txMemo))
+		} else {
+			fmt.Printf("Failed to get transaction: %v\n", err)
+		}
 
-	ds.demoCase("Get transaction with hash 0x%v.", txHash)
-	_, tx, err := client.GetRawTx(txHash)
-	if err == nil {
-		txMemo, err := hex.DecodeString(tx.Memo)
-		ds.demoCheck(err)
-		fmt.Printf("Memo with binary:%b\n", txMemo)
-		fmt.Printf("Memo with byte:%v\n", txMemo)
-		fmt.Printf("Memo with raw string:%s\n", txMemo)
-	} else {
-		fmt.Printf("Failed to get transaction: %v\n", err)
+		return nil
 	}
-
 }
 
-func (ds *DemoSet) DemoSDKGenerateCryptoKeysAndAddress(args []string) {
-	doTask := func(cryptoSeed core.Bytes) {
-		fmt.Printf("CryptoSeed: %v\n", cryptoSeed)
-		keysAndAddress, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
-		ds.demoCheck(err)
-		fmt.Printf("SpendSecretKey: %v\n", keysAndAddress.SpendSecretKey)
-		fmt.Printf("SerialNoSecretKey: %v\n", keysAndAddress.SerialNoSecretKey)
-		fmt.Printf("ViewSecretKey: %v\n", keysAndAddress.ViewSecretKey)
-		fmt.Printf("CryptoAddress: %v\n", keysAndAddress.CryptoAddress)
-	}
+func (ds *DemoSet) DemoSDKGenerateCryptoKeysAndAddress(flag *flag.FlagSet) func() error {
+	return func() error {
+		doTask := func(cryptoSeed core.Bytes) error {
+			fmt.Printf("CryptoSeed: %v\n", DemoSecretBytes(cryptoSeed))
+			keysAndAddress, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("SpendSecretKey: %v\n", NewDemoSecretKey(&keysAndAddress.SpendSecretKey))
+			fmt.Printf("SerialNoSecretKey: %v\n", NewDemoSecretKey(&keysAndAddress.SerialNoSecretKey))
+			fmt.Printf("ViewSecretKey: %v\n", NewDemoSecretKey(&keysAndAddress.ViewSecretKey))
+			fmt.Printf("CryptoAddress: %v\n", keysAndAddress.CryptoAddress)
+			return nil
+		}
 
-	ds.demoCase("Generate a set of crypto keys and address.")
-	cryptoSeed, err := core.GenerateSafeCryptoSeed()
-	doTask(cryptoSeed)
+		ds.demoCase("Generate a set of crypto keys and address.")
+		cryptoSeed, err := core.GenerateSafeCryptoSeed()
+		if err != nil {
+			return err
+		}
+		if err := doTask(cryptoSeed); err != nil {
+			return err
+		}
 
-	ds.demoCase("Generate the same set of crypto keys and address from the same crypto seed.")
-	doTask(cryptoSeed)
+		ds.demoCase("Generate the same set of crypto keys and address from the same crypto seed.")
+		if err := doTask(cryptoSeed); err != nil {
+			return err
+		}
 
-	ds.demoCase("Generate another set of crypto keys and address from a different crypto seed.")
-	cryptoSeed, err = core.GenerateSafeCryptoSeed()
-	ds.demoCheck(err)
-	doTask(cryptoSeed)
+		ds.demoCase("Generate another set of crypto keys and address from a different crypto seed.")
+		cryptoSeed, err = core.GenerateSafeCryptoSeed()
+		if err != nil {
+			return err
+		}
+		if err := doTask(cryptoSeed); err != nil {
+			return err
+		}
 
-	ds.demoBadCase("Generate a set of crypto keys and address from an *UNSAFE* crypto seed.")
-	cryptoSeed = core.MakeBytes(2*64 + 4)
-	doTask(cryptoSeed)
+		ds.demoBadCase("Generate a set of crypto keys and address from an *UNSAFE* crypto seed.")
+		cryptoSeed = core.MakeBytes(2*64 + 4)
+		return doTask(cryptoSeed)
+	}
 }
 
-func (ds *DemoSet) DemoSDKGenerateAddresses(args []string) {
-	// Parse demo args.
-	flag := flag.NewFlagSet("SDKGenerateAddresses", flag.ContinueOnError)
+func (ds *DemoSet) DemoSDKGenerateAddresses(flag *flag.FlagSet) func() error {
+	// Declare demo args.
 	chainID := flag.Int("chainID", int(ds.getDemoChainID()), "Chain ID (0 ~ 15).")
-	ds.demoExitOnError(flag.Parse(args))
+	return func() error {
+		// Define repeatable demo task.
+		var addresses []map[string]interface{}
+		doTask := func() error {
+			cryptoSeed, err := core.GenerateSafeCryptoSeed()
+			if err != nil {
+				return err
+			}
+			keysAndAddress, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
+			if err != nil {
+				return err
+			}
 
-	// Define repeatable demo task.
-	doTask := func() {
-		cryptoSeed, err := core.GenerateSafeCryptoSeed()
-		ds.demoCheck(err)
-		keysAndAddress, err := core.GenerateCryptoKeysAndAddress(cryptoSeed)
-		ds.demoCheck(err)
+			cryptoAddress := keysAndAddress.CryptoAddress
+			fmt.Printf("CryptoAddress: %v\n", cryptoAddress)
 
-		cryptoAddress := keysAndAddress.CryptoAddress
-		fmt.Printf("CryptoAddress: %v\n", cryptoAddress)
+			coinAddress := cryptoAddress.GetCoinAddress()
+			fmt.Printf("CoinAddress: %v\n", coinAddress)
 
-		coinAddress := cryptoAddress.GetCoinAddress()
-		fmt.Printf("CoinAddress: %v\n", coinAddress)
+			abelAddress := core.NewAbelAddressFromCryptoAddress(&cryptoAddress, int8(*chainID))
+			fmt.Printf("AbelAddress: %v\n", abelAddress)
 
-		abelAddress := core.NewAbelAddressFromCryptoAddress(&cryptoAddress, int8(*chainID))
-		fmt.Printf("AbelAddress: %v\n", abelAddress)
+			shortAbelAddress := abelAddress.GetShortAbelAddress()
+			fmt.Printf("ShortAbelAddress: %v\n", shortAbelAddress)
 
-		shortAbelAddress := abelAddress.GetShortAbelAddress()
-		fmt.Printf("ShortAbelAddress: %v\n", shortAbelAddress)
-	}
+			addresses = append(addresses, map[string]interface{}{
+				"abelAddress":      abelAddress.HexString(),
+				"shortAbelAddress": shortAbelAddress.HexString(),
+				"fingerprint":      cryptoAddress.Fingerprint().HexString(),
+			})
+			return nil
+		}
 
-	rounds := 3
-	for i := 0; i < rounds; i++ {
-		ds.demoCase("Generate an abel address and derive other types of addresses (%d/%d).", i+1, rounds)
-		doTask()
+		rounds := 3
+		for i := 0; i < rounds; i++ {
+			ds.demoCase("Generate an abel address and derive other types of addresses (%d/%d).", i+1, rounds)
+			if err := doTask(); err != nil {
+				return err
+			}
+		}
+		ds.demoResult("chainID", *chainID)
+		ds.demoResult("addresses", addresses)
+		return nil
 	}
 }
 
-func (ds *DemoSet) DemoSDKTrackCoins(args []string) {
+func (ds *DemoSet) DemoSDKTrackCoins(flag *flag.FlagSet) func() error {
 	// Load default args from demo config.
 	defaultAccounts := ds.getDemoConfigStringValue("SDKTrackCoins.accounts")
 	defaultScanHeightRange := ds.getDemoConfigStringValue("SDKTrackCoins.scanHeightRange")
 	defaultTrackHeightRange := ds.getDemoConfigStringValue("SDKTrackCoins.trackHeightRange")
 
-	// Parse demo args.
-	flag := flag.NewFlagSet("SDKTrackCoins", flag.ContinueOnError)
+	// Declare demo args.
 	accountsArg := flag.String("accounts", defaultAccounts, "Seqnos of the tracked accounts.")
 	scanHeightRangeArg := flag.String("txosHeightRange", defaultScanHeightRange, "Range of block heights to scan coins.")
 	trackHeightRangeArg := flag.String("trackHeightRange", defaultTrackHeightRange, "Range of block heights to track coins.")
-	ds.demoExitOnError(flag.Parse(args))
-
-	// Create RPC client.
-	client := ds.getDemoAbecRPCClient()
-
-	ds.demoCase("Process demo args.")
-	accountSeqnos := strings.Split(*accountsArg, ",")
-	scanHeightRange := strings.Split(*scanHeightRangeArg, ",")
-	trackHeightRange := strings.Split(*trackHeightRangeArg, ",")
-	scanHeightBegin := int64(atoi(scanHeightRange[0]))
-	scanHeightEnd := int64(atoi(scanHeightRange[1]))
-	trackHeightBegin := int64(atoi(trackHeightRange[0]))
-	trackHeightEnd := int64(atoi(trackHeightRange[1]))
-
-	fmt.Printf("tracked account seqnos: %v\n", accountSeqnos)
-	fmt.Printf("block range to scan coins: [%v, %v]\n", scanHeightBegin, scanHeightEnd)
-	fmt.Printf("block range to track coins: [%v, %v]\n", trackHeightBegin, trackHeightEnd)
-
-	ds.demoCase("Load tracked accounts.")
-	demoAccounts := ds.getDemoAccounts()
-	trackedAccounts := make([]*DemoAccount, len(accountSeqnos))
-	for i, seqno := range accountSeqnos {
-		trackedAccount := demoAccounts[atoi(seqno)]
-		fmt.Printf("tracked account %v (seqno=%v): %v\n", i, seqno, trackedAccount.ShortAbelAddress)
-		trackedAccounts[i] = trackedAccount
-	}
-
-	ds.demoCase("Scan blocks %d to %d to search coins belong to the tracked accounts.", scanHeightBegin, scanHeightEnd)
-	accountCoins := make([][]*core.Coin, len(trackedAccounts))
-	for height := scanHeightBegin; height <= scanHeightEnd; height++ {
-		_, blockHash, err := client.GetBlockHash(height)
-		ds.demoCheck(err)
-		_, block, err := client.GetBlock(*blockHash)
-		ds.demoCheck(err)
-		fmt.Printf("Scanning block %v (hash=%v)...\n", height, *blockHash)
-		for _, txHash := range block.TxHashes {
-			_, tx, err := client.GetRawTx(txHash)
-			ds.demoCheck(err)
-
-			for txIndex, vout := range tx.Vout {
-				voutData := core.MakeBytesFromHexString(vout.Script)
-				coinAddress, err := core.DecodeCoinAddressFromTxOutData(voutData)
-				ds.demoCheck(err)
-
-				for i, trackedAccount := range trackedAccounts {
-					if bytes.Equal(coinAddress.Fingerprint(), trackedAccount.Fingerprint) {
-						txoValue, err := core.DecodeValueFromTxOutData(voutData, trackedAccount.ViewSecretKey)
-						ds.demoCheck(err)
-						fmt.Printf("  🔥 Found coin for tracked account %v: %v ABEL\n", i, core.NeutrinoToAbel(txoValue))
-						coin := &core.Coin{
-							ID:                core.CoinID{TxHash: core.MakeBytesFromHexString(txHash), Index: uint8(txIndex)},
-							OwnerShortAddress: trackedAccount.ShortAbelAddress,
-							OwnerAddress:      trackedAccount.AbelAddress,
-							Value:             txoValue,
-							SerialNumber:      nil,
-							TxVoutData:        voutData,
-							BlockHash:         core.MakeBytesFromHexString(*blockHash),
-							BlockHeight:       height,
-						}
-						accountCoins[i] = append(accountCoins[i], coin)
-					}
-				}
-			}
+	return func() error {
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
 		}
-	}
-	fmt.Printf("Coins found:\n")
-	totalCoins := 0
-	for i, coins := range accountCoins {
-		totalCoins += len(coins)
-		fmt.Printf("  tracked account %v: %d coins\n", i, len(coins))
-	}
-	fmt.Printf("💰 Total found: %d coins\n", totalCoins)
 
-	ds.demoCase("Prepare data for calculating coin serial numbers.")
-	// Prepare CoinIDs and SerialNoSecretKeys.
-	allCoins := make([]*core.Coin, 0, totalCoins)
-	coinIDs := make([]*core.CoinID, 0, totalCoins)
-	serialNoSecretKeys := make([]*core.CryptoKey, 0, totalCoins)
-	for i, coins := range accountCoins {
-		for _, coin := range coins {
-			allCoins = append(allCoins, coin)
-			coinIDs = append(coinIDs, &coin.ID)
-			serialNoSecretKeys = append(serialNoSecretKeys, trackedAccounts[i].SerialNoSecretKey)
+		ds.demoCase("Process demo args.")
+		accountSeqnos, err := parseDemoSeqnos("-accounts", *accountsArg)
+		if err != nil {
+			return err
 		}
-	}
-	// Prepare ring blocks data.
-	allRingBlockHeights := make([]int64, 0, len(allCoins)*3)
-	for _, coin := range allCoins {
-		if !contains(allRingBlockHeights, coin.BlockHeight) {
-			allRingBlockHeights = append(allRingBlockHeights, coin.BlockHeight)
-			coinRingBlockHeights := core.GetRingBlockHeights(coin.BlockHeight)
-			for _, coinRingBlockHeight := range coinRingBlockHeights {
-				if !contains(allRingBlockHeights, coinRingBlockHeight) {
-					allRingBlockHeights = append(allRingBlockHeights, coinRingBlockHeight)
-				}
-			}
+		scanHeightBegin, scanHeightEnd, err := parseDemoHeightRange("-txosHeightRange", *scanHeightRangeArg)
+		if err != nil {
+			return err
+		}
+		trackHeightBegin, trackHeightEnd, err := parseDemoHeightRange("-trackHeightRange", *trackHeightRangeArg)
+		if err != nil {
+			return err
 		}
-	}
-	fmt.Printf("Ring block heights: %v\n", allRingBlockHeights)
-	allRingBlocks := make(map[int64]*core.TxBlockDesc)
-	for _, ringBlockHeight := range allRingBlockHeights {
-		ringBlockBytes, err := client.GetBlockBytesByHeight(ringBlockHeight)
-		ds.demoCheck(err)
-		ringBlock := core.NewTxBlockDesc(ringBlockBytes, ringBlockHeight)
-		allRingBlocks[ringBlockHeight] = ringBlock
-		fmt.Printf("  ring block %d: %v\n", ringBlock.Height, ringBlock.BinData)
-	}
 
-	ds.demoCase("Calculate coin serial numbers for all coins found in a batch.")
-	// Calculate coin serial numbers.
-	coinSerialNumbers, err := core.DecodeCoinSerialNumbers(coinIDs, serialNoSecretKeys, allRingBlocks)
-	ds.demoCheck(err)
-	// Assign coin serial numbers to coins.
-	for i, coin := range allCoins {
-		coin.SerialNumber = core.AsBytes(coinSerialNumbers[i])
-	}
-	// Print result.
-	fmt.Printf("Coin serial numbers calculated:\n")
-	for i, coin := range allCoins {
-		fmt.Printf("  coin %d: %v\n", i, coin.SerialNumber)
-	}
+		fmt.Printf("tracked account seqnos: %v\n", accountSeqnos)
+		fmt.Printf("block range to scan coins: [%v, %v]\n", scanHeightBegin, scanHeightEnd)
+		fmt.Printf("block range to track coins: [%v, %v]\n", trackHeightBegin, trackHeightEnd)
+
+		ds.demoCase("Load tracked accounts.")
+		trackedAccounts, err := ds.getDemoAccountsBySeqnos("-accounts", *accountsArg)
+		if err != nil {
+			return err
+		}
+		for i, trackedAccount := range trackedAccounts {
+			fmt.Printf("tracked account %v (seqno=%v): %v\n", i, trackedAccount.SerialNo, trackedAccount.ShortAbelAddress)
+		}
+
+		ds.demoCase("Scan blocks %d to %d to search coins belong to the tracked accounts.", scanHeightBegin, scanHeightEnd)
+		scanner := NewDemoCoinScanner(client, trackedAccounts)
+		accountCoins := make([][]*DemoScannedCoin, len(trackedAccounts))
+		for height := scanHeightBegin; height <= scanHeightEnd; height++ {
+			scannedBlock, err := scanner.ScanBlock(height)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Scanning block %v (hash=%v)...\n", height, scannedBlock.Hash)
+			for _, scannedCoin := range scannedBlock.Coins {
+				fmt.Printf("  🔥 Found coin for tracked account %v: %v ABEL\n", scannedCoin.AccountIndex, core.NeutrinoToAbel(scannedCoin.Coin.Value))
+				accountCoins[scannedCoin.AccountIndex] = append(accountCoins[scannedCoin.AccountIndex], scannedCoin)
+			}
+		}
+		fmt.Printf("Coins found:\n")
+		totalCoins := 0
+		for i, coins := range accountCoins {
+			totalCoins += len(coins)
+			fmt.Printf("  tracked account %v: %d coins\n", i, len(coins))
+		}
+		fmt.Printf("💰 Total found: %d coins\n", totalCoins)
+
+		ds.demoCase("Prepare data for calculating coin serial numbers.")
+		allCoins := make([]*DemoScannedCoin, 0, totalCoins)
+		coinHeights := make([]int64, 0, totalCoins)
+		for _, coins := range accountCoins {
+			for _, coin := range coins {
+				allCoins = append(allCoins, coin)
+				coinHeights = append(coinHeights, coin.Coin.BlockHeight)
+			}
+		}
+		allRingBlockHeights := getDemoRingBlockHeights(ds.getDemoChainID(), coinHeights)
+		fmt.Printf("Ring block heights: %v\n", allRingBlockHeights)
+		allRingBlocks, err := scanner.FetchRingBlocks(allRingBlockHeights)
+		if err != nil {
+			return err
+		}
+		for _, ringBlockHeight := range allRingBlockHeights {
+			fmt.Printf("  ring block %d: %v\n", ringBlockHeight, allRingBlocks[ringBlockHeight].BinData)
+		}
 
-	ds.demoCase("Track if the above coins were spent in blocks %d to %d.", trackHeightBegin, trackHeightEnd)
-	totalSpentCoins := 0
-	for height := trackHeightBegin; height <= trackHeightEnd; height++ {
-		_, blockHash, err := client.GetBlockHash(height)
-		ds.demoCheck(err)
-		_, block, err := client.GetBlock(*blockHash)
-		ds.demoCheck(err)
-		fmt.Printf("Scanning block %v (hash=%v)...\n", height, *blockHash)
-		for _, txHash := range block.TxHashes {
-			_, tx, err := client.GetRawTx(txHash)
-			ds.demoCheck(err)
+		ds.demoCase("Calculate coin serial numbers for all coins found in a batch.")
+		if err := DecodeDemoSerialNumbers(allCoins, allRingBlocks); err != nil {
+			return err
+		}
+		// Print result.
+		fmt.Printf("Coin serial numbers calculated:\n")
+		for i, coin := range allCoins {
+			fmt.Printf("  coin %d: %v\n", i, coin.Coin.SerialNumber)
+		}
+		// Save coins to the local coin store.
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+		coinRecords := make([]*DemoCoinRecord, 0, totalCoins)
+		for _, coin := range allCoins {
+			coinRecord := NewDemoCoinRecord(coin.Coin, coin.Account.SerialNo)
+			coinStore.Put(coinRecord)
+			coinRecords = append(coinRecords, coinRecord)
+		}
 
-			for _, vin := range tx.Vin {
-				serialNumber := core.MakeBytesFromHexString(vin.SerialNumber)
+		ds.demoCase("Track if the above coins were spent in blocks %d to %d.", trackHeightBegin, trackHeightEnd)
+		totalSpentCoins := 0
+		for height := trackHeightBegin; height <= trackHeightEnd; height++ {
+			scannedBlock, err := scanner.ScanBlock(height)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Scanning block %v (hash=%v)...\n", height, scannedBlock.Hash)
+			for _, spend := range scannedBlock.Spends {
+				serialNumber := core.MakeBytesFromHexString(spend.SerialNumber)
 				fmt.Printf("  Found coin serial number: %v\n", serialNumber)
 				for _, coin := range allCoins {
-					if bytes.Equal(coin.SerialNumber, serialNumber) {
+					if bytes.Equal(coin.Coin.SerialNumber, serialNumber) {
 						totalSpentCoins += 1
-						fmt.Printf("  🔥 Coin %v was spent by tx %v.\n", coin.ID, txHash)
+						fmt.Printf("  🔥 Coin %v was spent by tx %v.\n", coin.Coin.ID, spend.Txid)
+						coinStore.MarkSpent(serialNumber.HexString(), spend.Txid, height)
+					}
+				}
+			}
+		}
+		fmt.Printf("💰 Total spent: %d coins\n", totalSpentCoins)
+		if err := coinStore.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Coin store updated: %v\n", ds.getDemoCoinStorePath())
+
+		webhooks, err := ds.getDemoWebhookQueue()
+		if err != nil {
+			return err
+		}
+		if webhooks.Enabled() {
+			ds.demoCase("Notify the coin events to the webhooks.")
+			// The confirmations are counted from the chain tip, whatever blocks were tracked.
+			_, chainInfo, err := client.GetChainInfo()
+			if err != nil {
+				return err
+			}
+			for _, record := range coinRecords {
+				queued, err := webhooks.Add(DemoWebhookCoinReceived, ds.getDemoChainID(), record, chainInfo.NumBlocks-record.BlockHeight+1)
+				if err != nil {
+					return err
+				}
+				if queued {
+					fmt.Printf("  queued %v event of coin %v\n", DemoWebhookCoinReceived, record.ID)
+				}
+				if record.BlockHeight+webhooks.Confirmations()-1 <= chainInfo.NumBlocks {
+					queued, err := webhooks.Add(DemoWebhookCoinConfirmed, ds.getDemoChainID(), record, webhooks.Confirmations())
+					if err != nil {
+						return err
+					}
+					if queued {
+						fmt.Printf("  queued %v event of coin %v\n", DemoWebhookCoinConfirmed, record.ID)
+					}
+				}
+				if record.Status == DemoCoinSpent {
+					queued, err := webhooks.Add(DemoWebhookCoinSpent, ds.getDemoChainID(), record, record.SpentHeight-record.BlockHeight+1)
+					if err != nil {
+						return err
+					}
+					if queued {
+						fmt.Printf("  queued %v event of coin %v\n", DemoWebhookCoinSpent, record.ID)
 					}
 				}
 			}
+			if err := webhooks.Save(); err != nil {
+				return err
+			}
+			ds.demoResultWebhookAttempts(webhooks.Deliver())
+			if err := webhooks.Save(); err != nil {
+				return err
+			}
 		}
+		ds.demoResult("coins", coinRecords)
+		ds.demoResult("totalFound", totalCoins)
+		ds.demoResult("totalSpent", totalSpentCoins)
+		ds.demoResult("coinStore", ds.getDemoCoinStorePath())
+		return nil
 	}
-	fmt.Printf("💰 Total spent: %d coins\n", totalSpentCoins)
 }
 
-func (ds *DemoSet) DemoSDKMakeUnsignedRawTx(args []string) {
+func (ds *DemoSet) DemoSDKMakeUnsignedRawTx(flag *flag.FlagSet) func() error {
 	// Load default args from demo config.
 	defaultScanHeightRange := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.scanHeightRange")
 	defaultSenders := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.senders")
 	defaultReceivers := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.receivers")
 	defaultOutputFile := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.outputFile")
 
-	// Parse demo args.
-	flag := flag.NewFlagSet("SDKMakeUnsignedRawTx", flag.ContinueOnError)
+	// Declare demo args.
 	scanHeightRangeArg := flag.String("txosHeightRange", defaultScanHeightRange, "Range of block heights to scan coins.")
 	sendersArg := flag.String("senders", defaultSenders, "Seqnos of the sender accounts.")
 	receiversArg := flag.String("receivers", defaultReceivers, "Seqnos of the receiver accounts.")
 	outputFileArg := flag.String("outputFile", defaultOutputFile, "Output file name.")
-	ds.demoExitOnError(flag.Parse(args))
-
-	// Create RPC client and get demo accounts.
-	client := ds.getDemoAbecRPCClient()
-	demoAccounts := ds.getDemoAccounts()
-
-	ds.demoCase("Process demo args.")
-	// Check block range.
-	scanHeightRange := strings.Split(*scanHeightRangeArg, ",")
-	scanHeightBegin := int64(atoi(scanHeightRange[0]))
-	scanHeightEnd := int64(atoi(scanHeightRange[1]))
-	fmt.Printf("block range to scan coins: [%v, %v]\n", scanHeightBegin, scanHeightEnd)
-	if scanHeightBegin > scanHeightEnd || scanHeightBegin < 0 {
-		ds.demoExitOnError(fmt.Errorf("invalid block range: [%v, %v]", scanHeightBegin, scanHeightEnd))
-	}
-	// Load sender accounts.
-	fmt.Printf("sender seqnos: %v\n", *sendersArg)
-	senderSeqnos := strings.Split(*sendersArg, ",")
-	senderAccounts := make([]*DemoAccount, len(senderSeqnos))
-	for i, seqno := range senderSeqnos {
-		senderAccounts[i] = demoAccounts[atoi(seqno)]
-		fmt.Printf("  sender account %v (seqno=%v): %v\n", i, seqno, senderAccounts[i].ShortAbelAddress)
-	}
-	// Load receiver accounts.
-	fmt.Printf("receiver seqnos: %v\n", *receiversArg)
-	receiverSeqnos := strings.Split(*receiversArg, ",")
-	receiverAccounts := make([]*DemoAccount, len(receiverSeqnos))
-	for i, seqno := range receiverSeqnos {
-		receiverAccounts[i] = demoAccounts[atoi(seqno)]
-		fmt.Printf("  receiver account %v (seqno=%v): %v\n", i, seqno, receiverAccounts[i].ShortAbelAddress)
-	}
-	// Check output file.
-	outputPath := ds.getDemoFilePath(*outputFileArg)
-	fmt.Printf("output file: %v\n", outputPath)
-	if _, err := os.Stat(outputPath); err == nil {
-		ds.demoExitOnError(fmt.Errorf("output file %v already exists", outputPath))
-	}
-
-	ds.demoCase("Find all coins in the block range owned by sender accounts.")
-	senderTxInDescs := make([][]*core.TxInDesc, len(senderAccounts))
-	for height := scanHeightBegin; height <= scanHeightEnd; height++ {
-		_, blockHash, err := client.GetBlockHash(height)
-		ds.demoCheck(err)
-		_, block, err := client.GetBlock(*blockHash)
-		ds.demoCheck(err)
-		fmt.Printf("Searching block %v (hash=%v)...\n", height, *blockHash)
-		for _, txHash := range block.TxHashes {
-			_, tx, err := client.GetRawTx(txHash)
-			ds.demoCheck(err)
-
-			for txIndex, vout := range tx.Vout {
-				voutData := core.MakeBytesFromHexString(vout.Script)
-				coinAddress, err := core.DecodeCoinAddressFromTxOutData(voutData)
-				ds.demoCheck(err)
-
-				for i, senderAccount := range senderAccounts {
-					if bytes.Equal(coinAddress.Fingerprint(), senderAccount.Fingerprint) {
-						txoValue, err := core.DecodeValueFromTxOutData(voutData, senderAccount.ViewSecretKey)
-						ds.demoCheck(err)
-						fmt.Printf("  🔥 Found coin owned by sender %v: %v ABEL\n", i, core.NeutrinoToAbel(txoValue))
-						txoDesc := &core.TxInDesc{
-							TxOutData:  voutData,
-							CoinValue:  txoValue,
-							Owner:      senderAccount.ShortAbelAddress,
-							Height:     height,
-							TxHash:     core.MakeBytesFromHexString(txHash),
-							TxOutIndex: uint8(txIndex),
+	return func() error {
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Process demo args.")
+		// Check block range.
+		scanHeightBegin, scanHeightEnd, err := parseDemoHeightRange("-txosHeightRange", *scanHeightRangeArg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("block range to scan coins: [%v, %v]\n", scanHeightBegin, scanHeightEnd)
+		// Load sender accounts.
+		fmt.Printf("sender seqnos: %v\n", *sendersArg)
+		senderAccounts, err := ds.getDemoAccountsBySeqnos("-senders", *sendersArg)
+		if err != nil {
+			return err
+		}
+		for i, senderAccount := range senderAccounts {
+			fmt.Printf("  sender account %v (seqno=%v): %v\n", i, senderAccount.SerialNo, senderAccount.ShortAbelAddress)
+		}
+		// Load receiver accounts.
+		fmt.Printf("receiver seqnos: %v\n", *receiversArg)
+		receiverAccounts, err := ds.getDemoAccountsBySeqnos("-receivers", *receiversArg)
+		if err != nil {
+			return err
+		}
+		for i, receiverAccount := range receiverAccounts {
+			fmt.Printf("  receiver account %v (seqno=%v): %v\n", i, receiverAccount.SerialNo, receiverAccount.ShortAbelAddress)
+		}
+		// Check output file.
+		outputPath := ds.getDemoFilePath(*outputFileArg)
+		fmt.Printf("output file: %v\n", outputPath)
+		if _, err := os.Stat(outputPath); err == nil {
+			return newDemoError(DemoErrorValidation, "output file %v already exists", outputPath)
+		}
+
+		ds.demoCase("Find all coins in the block range owned by sender accounts.")
+		senderTxInDescs := make([][]*core.TxInDesc, len(senderAccounts))
+		for height := scanHeightBegin; height <= scanHeightEnd; height++ {
+			_, blockHash, err := client.GetBlockHash(height)
+			if err != nil {
+				return err
+			}
+			_, block, err := client.GetBlock(*blockHash)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Searching block %v (hash=%v)...\n", height, *blockHash)
+			for _, txHash := range block.TxHashes {
+				_, tx, err := client.GetRawTx(txHash)
+				if err != nil {
+					return err
+				}
+
+				for txIndex, vout := range tx.Vout {
+					voutData, coinAddress, err := DecodeDemoTxOut(vout.Script)
+					if err != nil {
+						return err
+					}
+
+					for i, senderAccount := range senderAccounts {
+						if bytes.Equal(coinAddress.Fingerprint(), senderAccount.Fingerprint) {
+							txoValue, err := core.DecodeValueFromTxOutData(voutData, senderAccount.ViewSecretKey.CryptoKey())
+							if err != nil {
+								return err
+							}
+							fmt.Printf("  🔥 Found coin owned by sender %v: %v ABEL\n", i, core.NeutrinoToAbel(txoValue))
+							txoDesc := &core.TxInDesc{
+								TxOutData:  voutData,
+								CoinValue:  txoValue,
+								Owner:      senderAccount.ShortAbelAddress,
+								Height:     height,
+								TxHash:     core.MakeBytesFromHexString(txHash),
+								TxOutIndex: uint8(txIndex),
+							}
+							senderTxInDescs[i] = append(senderTxInDescs[i], txoDesc)
 						}
-						senderTxInDescs[i] = append(senderTxInDescs[i], txoDesc)
 					}
 				}
 			}
 		}
-	}
-	fmt.Printf("Coins found:\n")
-	for i, txInDescs := range senderTxInDescs {
-		fmt.Printf("  sender %v: %d coins\n", i, len(txInDescs))
-	}
+		fmt.Printf("Coins found:\n")
+		for i, txInDescs := range senderTxInDescs {
+			fmt.Printf("  sender %v: %d coins\n", i, len(txInDescs))
+		}
 
-	ds.demoCase("Pick a random coin to spend for each sender account.")
-	txInDescsToSpend := make([]*core.TxInDesc, 0, len(senderAccounts))
-	rand.Seed(time.Now().UnixNano())
-	for _, txInDescs := range senderTxInDescs {
-		if len(txInDescs) == 0 {
-			continue
+		ds.demoCase("Pick a random coin to spend for each sender account.")
+		txInDescsToSpend := make([]*core.TxInDesc, 0, len(senderAccounts))
+		rand.Seed(time.Now().UnixNano())
+		for _, txInDescs := range senderTxInDescs {
+			if len(txInDescs) == 0 {
+				continue
+			}
+			randIndex := rand.Intn(len(txInDescs))
+			txInDescsToSpend = append(txInDescsToSpend, txInDescs[randIndex])
+		}
+		if len(txInDescsToSpend) == 0 {
+			return newDemoError(DemoErrorInsufficientFunds, "failed to find any coin to spend")
+		}
+		fmt.Printf("TxInDescs:\n")
+		for i, txInDescs := range txInDescsToSpend {
+			fmt.Printf("  txInDesc[%v]: height: %d, value: %v ABEL, sender: %v, outpoint: (%s,%d)\n",
+				i, txInDescs.Height, core.NeutrinoToAbel(txInDescs.CoinValue), txInDescs.Owner, txInDescs.TxHash, txInDescs.TxOutIndex)
 		}
-		randIndex := rand.Intn(len(txInDescs))
-		txInDescsToSpend = append(txInDescsToSpend, txInDescs[randIndex])
-	}
-	if len(txInDescsToSpend) == 0 {
-		ds.demoExitOnError(fmt.Errorf("failed to find any coin to spend"))
-	}
-	fmt.Printf("TxInDescs:\n")
-	for i, txInDescs := range txInDescsToSpend {
-		fmt.Printf("  txInDesc[%v]: height: %d, value: %v ABEL, sender: %v, outpoint: (%s,%d)\n",
-			i, txInDescs.Height, core.NeutrinoToAbel(txInDescs.CoinValue), txInDescs.Owner, txInDescs.TxHash, txInDescs.TxOutIndex)
-	}
 
-	ds.demoCase("Get estimated TxFee.")
-	estimatedTxFee := client.GetEstimatedTxFee()
-	fmt.Printf("TxFee: %v ABEL\n", core.NeutrinoToAbel(estimatedTxFee))
+		ds.demoCase("Get estimated TxFee.")
+		estimatedTxFee := client.GetEstimatedTxFee()
+		fmt.Printf("TxFee: %v ABEL\n", core.NeutrinoToAbel(estimatedTxFee))
 
-	ds.demoCase("Calculate TxOutDescs for all receivers.")
-	// Calculate the value to spend by deducting TxFee from the total value in Txos.
-	totalCoinValue := int64(0)
-	for _, txInDesc := range txInDescsToSpend {
-		totalCoinValue += txInDesc.CoinValue
-	}
-	spendableCoinValue := totalCoinValue - estimatedTxFee
-	// Transfer all the value in sender Txos to the receivers, distribute the total value evenly.
-	coinValuePerReceiver := spendableCoinValue / int64(len(receiverAccounts))
-	// Create TxOutDescs for all receivers.
-	txOutDescs := make([]*core.TxOutDesc, len(receiverAccounts))
-	for i, receiverAccount := range receiverAccounts {
-		txOutDescs[i] = &core.TxOutDesc{
-			AbelAddress: receiverAccount.AbelAddress,
-			CoinValue:   coinValuePerReceiver,
+		ds.demoCase("Calculate TxOutDescs for all receivers.")
+		// Calculate the value to spend by deducting TxFee from the total value in Txos.
+		totalCoinValue := int64(0)
+		for _, txInDesc := range txInDescsToSpend {
+			totalCoinValue += txInDesc.CoinValue
 		}
-		if i == len(receiverAccounts)-1 {
-			// The last receiver gets the remaining value.
-			txOutDescs[i].CoinValue += spendableCoinValue % int64(len(receiverAccounts))
+		spendableCoinValue := totalCoinValue - estimatedTxFee
+		if spendableCoinValue < int64(len(receiverAccounts)) {
+			return newDemoError(DemoErrorInsufficientFunds, "coins of %v ABEL do not cover the tx fee of %v ABEL",
+				core.NeutrinoToAbel(totalCoinValue), core.NeutrinoToAbel(estimatedTxFee))
+		}
+		// Transfer all the value in sender Txos to the receivers, distribute the total value evenly.
+		coinValuePerReceiver := spendableCoinValue / int64(len(receiverAccounts))
+		// Create TxOutDescs for all receivers.
+		txOutDescs := make([]*core.TxOutDesc, len(receiverAccounts))
+		for i, receiverAccount := range receiverAccounts {
+			txOutDescs[i] = &core.TxOutDesc{
+				AbelAddress: receiverAccount.AbelAddress,
+				CoinValue:   coinValuePerReceiver,
+			}
+			if i == len(receiverAccounts)-1 {
+				// The last receiver gets the remaining value.
+				txOutDescs[i].CoinValue += spendableCoinValue % int64(len(receiverAccounts))
+			}
+		}
+		fmt.Printf("TxOutDescs:\n")
+		for i, txOutDesc := range txOutDescs {
+			fmt.Printf("  txOutDesc[%v]: value: %v ABEL, receiver: %v\n",
+				i, core.NeutrinoToAbel(txOutDesc.CoinValue), txOutDesc.AbelAddress.GetShortAbelAddress())
 		}
-	}
-	fmt.Printf("TxOutDescs:\n")
-	for i, txOutDesc := range txOutDescs {
-		fmt.Printf("  txOutDesc[%v]: value: %v ABEL, receiver: %v\n",
-			i, core.NeutrinoToAbel(txOutDesc.CoinValue), txOutDesc.AbelAddress.GetShortAbelAddress())
-	}
 
-	ds.demoCase("Get ring blocks for all TxInDescs.")
-	allRingBlockHeights := make([]int64, 0, len(txInDescsToSpend)*3)
-	for _, txInDesc := range txInDescsToSpend {
-		ringBlockHeights := core.GetRingBlockHeights(txInDesc.Height)
-		for _, ringBlockHeight := range ringBlockHeights {
-			if !contains(allRingBlockHeights, ringBlockHeight) {
-				allRingBlockHeights = append(allRingBlockHeights, ringBlockHeight)
+		ds.demoCase("Get ring blocks for all TxInDescs.")
+		ringChain := getDemoRingChain(ds.getDemoChainID())
+		allRingBlockHeights := make([]int64, 0, len(txInDescsToSpend)*ringChain.RingBlockNum)
+		for _, txInDesc := range txInDescsToSpend {
+			ringBlockHeights := ringChain.RingBlockHeights(txInDesc.Height)
+			for _, ringBlockHeight := range ringBlockHeights {
+				if !contains(allRingBlockHeights, ringBlockHeight) {
+					allRingBlockHeights = append(allRingBlockHeights, ringBlockHeight)
+				}
 			}
 		}
-	}
-	fmt.Printf("Ring block heights: %v\n", allRingBlockHeights)
-	allRingBlocks := make(map[int64]*core.TxBlockDesc)
-	for _, ringBlockHeight := range allRingBlockHeights {
-		ringBlockBytes, err := client.GetBlockBytesByHeight(ringBlockHeight)
-		ds.demoCheck(err)
-		ringBlock := core.NewTxBlockDesc(ringBlockBytes, ringBlockHeight)
-		allRingBlocks[ringBlockHeight] = ringBlock
-		fmt.Printf("  ring block %d: %v\n", ringBlock.Height, ringBlock.BinData)
-	}
+		fmt.Printf("Ring block heights: %v\n", allRingBlockHeights)
+		allRingBlocks := make(map[int64]*core.TxBlockDesc)
+		for _, ringBlockHeight := range allRingBlockHeights {
+			ringBlockBytes, err := client.GetBlockBytesByHeight(ringBlockHeight)
+			if err != nil {
+				return err
+			}
+			ringBlock := core.NewTxBlockDesc(ringBlockBytes, ringBlockHeight)
+			allRingBlocks[ringBlockHeight] = ringBlock
+			fmt.Printf("  ring block %d: %v\n", ringBlock.Height, ringBlock.BinData)
+		}
 
-	ds.demoCase("Generate an UnsignedRawTx and write it to output file.")
-	txDesc := core.NewTxDesc(txInDescsToSpend, txOutDescs, estimatedTxFee, allRingBlocks)
-	unsignedRawTx, err := core.GenerateUnsignedRawTx(txDesc)
-	ds.demoCheck(err)
-	err = os.WriteFile(outputPath, unsignedRawTx.Bytes, 0644)
-	ds.demoCheck(err)
-	fmt.Printf("UnsignedRawTx written to file: %v\n", outputPath)
+		ds.demoCase("Generate an UnsignedRawTx and write it to output file.")
+		txDesc := core.NewTxDesc(txInDescsToSpend, txOutDescs, estimatedTxFee, allRingBlocks)
+		unsignedRawTx, err := core.GenerateUnsignedRawTx(txDesc)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(outputPath, unsignedRawTx.Bytes, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("UnsignedRawTx written to file: %v\n", outputPath)
+		ds.demoResultUnsignedRawTx(outputPath, txInDescsToSpend, txOutDescs, estimatedTxFee)
+		return nil
+	}
 }
 
-func (ds *DemoSet) DemoSDKMakeUnsignedRawTxWithMemo(args []string) {
+func (ds *DemoSet) DemoSDKMakeUnsignedRawTxWithMemo(flag *flag.FlagSet) func() error {
 	// Load default args from demo config.
 	defaultScanHeightRange := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.scanHeightRange")
 	defaultSenders := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.senders")
@@ -527,281 +731,1602 @@ func (ds *DemoSet) DemoSDKMakeUnsignedRawTxWithMemo(args []string) {
 	defaultOutputFile := ds.getDemoConfigStringValue("SDKMakeUnsignedRawTx.outputFile")
 	defaultMemo := ""
 
-	// Parse demo args.
-	flag := flag.NewFlagSet("SDKMakeUnsignedRawTxWithMemo", flag.ContinueOnError)
+	// Declare demo args.
 	scanHeightRangeArg := flag.String("txosHeightRange", defaultScanHeightRange, "Range of block heights to scan coins.")
 	sendersArg := flag.String("senders", defaultSenders, "Seqnos of the sender accounts.")
 	receiversArg := flag.String("receivers", defaultReceivers, "Seqnos of the receiver accounts.")
 	outputFileArg := flag.String("outputFile", defaultOutputFile, "Output file name.")
 	memoArg := flag.String("memo", defaultMemo, "write memo into transaction")
-	ds.demoExitOnError(flag.Parse(args))
-
-	// Create RPC client and get demo accounts.
-	client := ds.getDemoAbecRPCClient()
-	demoAccounts := ds.getDemoAccounts()
-
-	ds.demoCase("Process demo args.")
-	// Check block range.
-	scanHeightRange := strings.Split(*scanHeightRangeArg, ",")
-	scanHeightBegin := int64(atoi(scanHeightRange[0]))
-	scanHeightEnd := int64(atoi(scanHeightRange[1]))
-	fmt.Printf("block range to scan coins: [%v, %v]\n", scanHeightBegin, scanHeightEnd)
-	if scanHeightBegin > scanHeightEnd || scanHeightBegin < 0 {
-		ds.demoExitOnError(fmt.Errorf("invalid block range: [%v, %v]", scanHeightBegin, scanHeightEnd))
-	}
-	// Load sender accounts.
-	fmt.Printf("sender seqnos: %v\n", *sendersArg)
-	senderSeqnos := strings.Split(*sendersArg, ",")
-	senderAccounts := make([]*DemoAccount, len(senderSeqnos))
-	for i, seqno := range senderSeqnos {
-		senderAccounts[i] = demoAccounts[atoi(seqno)]
-		fmt.Printf("  sender account %v (seqno=%v): %v\n", i, seqno, senderAccounts[i].ShortAbelAddress)
-	}
-	// Load receiver accounts.
-	fmt.Printf("receiver seqnos: %v\n", *receiversArg)
-	receiverSeqnos := strings.Split(*receiversArg, ",")
-	receiverAccounts := make([]*DemoAccount, len(receiverSeqnos))
-	for i, seqno := range receiverSeqnos {
-		receiverAccounts[i] = demoAccounts[atoi(seqno)]
-		fmt.Printf("  receiver account %v (seqno=%v): %v\n", i, seqno, receiverAccounts[i].ShortAbelAddress)
-	}
-	// Check output file.
-	outputPath := ds.getDemoFilePath(*outputFileArg)
-	fmt.Printf("output file: %v\n", outputPath)
-	if _, err := os.Stat(outputPath); err == nil {
-		ds.demoExitOnError(fmt.Errorf("output file %v already exists", outputPath))
-	}
-
-	ds.demoCase("Find all coins in the block range owned by sender accounts.")
-	senderTxInDescs := make([][]*core.TxInDesc, len(senderAccounts))
-	for height := scanHeightBegin; height <= scanHeightEnd; height++ {
-		_, blockHash, err := client.GetBlockHash(height)
-		ds.demoCheck(err)
-		_, block, err := client.GetBlock(*blockHash)
-		ds.demoCheck(err)
-		fmt.Printf("Searching block %v (hash=%v)...\n", height, *blockHash)
-		for _, txHash := range block.TxHashes {
-			_, tx, err := client.GetRawTx(txHash)
-			ds.demoCheck(err)
-
-			for txIndex, vout := range tx.Vout {
-				voutData := core.MakeBytesFromHexString(vout.Script)
-				coinAddress, err := core.DecodeCoinAddressFromTxOutData(voutData)
-				ds.demoCheck(err)
-
-				for i, senderAccount := range senderAccounts {
-					if bytes.Equal(coinAddress.Fingerprint(), senderAccount.Fingerprint) {
-						txoValue, err := core.DecodeValueFromTxOutData(voutData, senderAccount.ViewSecretKey)
-						ds.demoCheck(err)
-						fmt.Printf("  🔥 Found coin owned by sender %v: %v ABEL\n", i, core.NeutrinoToAbel(txoValue))
-						txoDesc := &core.TxInDesc{
-							TxOutData:  voutData,
-							CoinValue:  txoValue,
-							Owner:      senderAccount.ShortAbelAddress,
-							Height:     height,
-							TxHash:     core.MakeBytesFromHexString(txHash),
-							TxOutIndex: uint8(txIndex),
+	return func() error {
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Process demo args.")
+		// Check block range.
+		scanHeightBegin, scanHeightEnd, err := parseDemoHeightRange("-txosHeightRange", *scanHeightRangeArg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("block range to scan coins: [%v, %v]\n", scanHeightBegin, scanHeightEnd)
+		// Load sender accounts.
+		fmt.Printf("sender seqnos: %v\n", *sendersArg)
+		senderAccounts, err := ds.getDemoAccountsBySeqnos("-senders", *sendersArg)
+		if err != nil {
+			return err
+		}
+		for i, senderAccount := range senderAccounts {
+			fmt.Printf("  sender account %v (seqno=%v): %v\n", i, senderAccount.SerialNo, senderAccount.ShortAbelAddress)
+		}
+		// Load receiver accounts.
+		fmt.Printf("receiver seqnos: %v\n", *receiversArg)
+		receiverAccounts, err := ds.getDemoAccountsBySeqnos("-receivers", *receiversArg)
+		if err != nil {
+			return err
+		}
+		for i, receiverAccount := range receiverAccounts {
+			fmt.Printf("  receiver account %v (seqno=%v): %v\n", i, receiverAccount.SerialNo, receiverAccount.ShortAbelAddress)
+		}
+		// Check output file.
+		outputPath := ds.getDemoFilePath(*outputFileArg)
+		fmt.Printf("output file: %v\n", outputPath)
+		if _, err := os.Stat(outputPath); err == nil {
+			return newDemoError(DemoErrorValidation, "output file %v already exists", outputPath)
+		}
+
+		// Check memo.
+		memo, err := DecodeDemoMemo(*memoArg)
+		if err != nil {
+			return newDemoError(DemoErrorValidation, "invalid -memo: %w", err)
+		}
+
+		ds.demoCase("Find all coins in the block range owned by sender accounts.")
+		senderTxInDescs := make([][]*core.TxInDesc, len(senderAccounts))
+		for height := scanHeightBegin; height <= scanHeightEnd; height++ {
+			_, blockHash, err := client.GetBlockHash(height)
+			if err != nil {
+				return err
+			}
+			_, block, err := client.GetBlock(*blockHash)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Searching block %v (hash=%v)...\n", height, *blockHash)
+			for _, txHash := range block.TxHashes {
+				_, tx, err := client.GetRawTx(txHash)
+				if err != nil {
+					return err
+				}
+
+				for txIndex, vout := range tx.Vout {
+					voutData, coinAddress, err := DecodeDemoTxOut(vout.Script)
+					if err != nil {
+						return err
+					}
+
+					for i, senderAccount := range senderAccounts {
+						if bytes.Equal(coinAddress.Fingerprint(), senderAccount.Fingerprint) {
+							txoValue, err := core.DecodeValueFromTxOutData(voutData, senderAccount.ViewSecretKey.CryptoKey())
+							if err != nil {
+								return err
+							}
+							fmt.Printf("  🔥 Found coin owned by sender %v: %v ABEL\n", i, core.NeutrinoToAbel(txoValue))
+							txoDesc := &core.TxInDesc{
+								TxOutData:  voutData,
+								CoinValue:  txoValue,
+								Owner:      senderAccount.ShortAbelAddress,
+								Height:     height,
+								TxHash:     core.MakeBytesFromHexString(txHash),
+								TxOutIndex: uint8(txIndex),
+							}
+							senderTxInDescs[i] = append(senderTxInDescs[i], txoDesc)
 						}
-						senderTxInDescs[i] = append(senderTxInDescs[i], txoDesc)
 					}
 				}
 			}
 		}
-	}
-	fmt.Printf("Coins found:\n")
-	for i, txInDescs := range senderTxInDescs {
-		fmt.Printf("  sender %v: %d coins\n", i, len(txInDescs))
-	}
+		fmt.Printf("Coins found:\n")
+		for i, txInDescs := range senderTxInDescs {
+			fmt.Printf("  sender %v: %d coins\n", i, len(txInDescs))
+		}
 
-	ds.demoCase("Pick a random coin to spend for each sender account.")
-	txInDescsToSpend := make([]*core.TxInDesc, 0, len(senderAccounts))
-	rand.Seed(time.Now().UnixNano())
-	for _, txInDescs := range senderTxInDescs {
-		if len(txInDescs) == 0 {
-			continue
+		ds.demoCase("Pick a random coin to spend for each sender account.")
+		txInDescsToSpend := make([]*core.TxInDesc, 0, len(senderAccounts))
+		rand.Seed(time.Now().UnixNano())
+		for _, txInDescs := range senderTxInDescs {
+			if len(txInDescs) == 0 {
+				continue
+			}
+			randIndex := rand.Intn(len(txInDescs))
+			txInDescsToSpend = append(txInDescsToSpend, txInDescs[randIndex])
+		}
+		if len(txInDescsToSpend) == 0 {
+			return newDemoError(DemoErrorInsufficientFunds, "failed to find any coin to spend")
+		}
+		fmt.Printf("TxInDescs:\n")
+		for i, txInDescs := range txInDescsToSpend {
+			fmt.Printf("  txInDesc[%v]: height: %d, value: %v ABEL, sender: %v, outpoint: (%s,%d)\n",
+				i, txInDescs.Height, core.NeutrinoToAbel(txInDescs.CoinValue), txInDescs.Owner, txInDescs.TxHash, txInDescs.TxOutIndex)
 		}
-		randIndex := rand.Intn(len(txInDescs))
-		txInDescsToSpend = append(txInDescsToSpend, txInDescs[randIndex])
-	}
-	if len(txInDescsToSpend) == 0 {
-		ds.demoExitOnError(fmt.Errorf("failed to find any coin to spend"))
-	}
-	fmt.Printf("TxInDescs:\n")
-	for i, txInDescs := range txInDescsToSpend {
-		fmt.Printf("  txInDesc[%v]: height: %d, value: %v ABEL, sender: %v, outpoint: (%s,%d)\n",
-			i, txInDescs.Height, core.NeutrinoToAbel(txInDescs.CoinValue), txInDescs.Owner, txInDescs.TxHash, txInDescs.TxOutIndex)
-	}
 
-	ds.demoCase("Get estimated TxFee.")
-	estimatedTxFee := client.GetEstimatedTxFee()
-	fmt.Printf("TxFee: %v ABEL\n", core.NeutrinoToAbel(estimatedTxFee))
+		ds.demoCase("Get estimated TxFee.")
+		estimatedTxFee := client.GetEstimatedTxFee()
+		fmt.Printf("TxFee: %v ABEL\n", core.NeutrinoToAbel(estimatedTxFee))
 
-	ds.demoCase("Calculate TxOutDescs for all receivers.")
-	// Calculate the value to spend by deducting TxFee from the total value in Txos.
-	totalCoinValue := int64(0)
-	for _, txInDesc := range txInDescsToSpend {
-		totalCoinValue += txInDesc.CoinValue
-	}
-	spendableCoinValue := totalCoinValue - estimatedTxFee
-	// Transfer all the value in sender Txos to the receivers, distribute the total value evenly.
-	coinValuePerReceiver := spendableCoinValue / int64(len(receiverAccounts))
-	// Create TxOutDescs for all receivers.
-	txOutDescs := make([]*core.TxOutDesc, len(receiverAccounts))
-	for i, receiverAccount := range receiverAccounts {
-		txOutDescs[i] = &core.TxOutDesc{
-			AbelAddress: receiverAccount.AbelAddress,
-			CoinValue:   coinValuePerReceiver,
+		ds.demoCase("Calculate TxOutDescs for all receivers.")
+		// Calculate the value to spend by deducting TxFee from the total value in Txos.
+		totalCoinValue := int64(0)
+		for _, txInDesc := range txInDescsToSpend {
+			totalCoinValue += txInDesc.CoinValue
 		}
-		if i == len(receiverAccounts)-1 {
-			// The last receiver gets the remaining value.
-			txOutDescs[i].CoinValue += spendableCoinValue % int64(len(receiverAccounts))
+		spendableCoinValue := totalCoinValue - estimatedTxFee
+		if spendableCoinValue < int64(len(receiverAccounts)) {
+			return newDemoError(DemoErrorInsufficientFunds, "coins of %v ABEL do not cover the tx fee of %v ABEL",
+				core.NeutrinoToAbel(totalCoinValue), core.NeutrinoToAbel(estimatedTxFee))
+		}
+		// Transfer all the value in sender Txos to the receivers, distribute the total value evenly.
+		coinValuePerReceiver := spendableCoinValue / int64(len(receiverAccounts))
+		// Create TxOutDescs for all receivers.
+		txOutDescs := make([]*core.TxOutDesc, len(receiverAccounts))
+		for i, receiverAccount := range receiverAccounts {
+			txOutDescs[i] = &core.TxOutDesc{
+				AbelAddress: receiverAccount.AbelAddress,
+				CoinValue:   coinValuePerReceiver,
+			}
+			if i == len(receiverAccounts)-1 {
+				// The last receiver gets the remaining value.
+				txOutDescs[i].CoinValue += spendableCoinValue % int64(len(receiverAccounts))
+			}
+		}
+		fmt.Printf("TxOutDescs:\n")
+		for i, txOutDesc := range txOutDescs {
+			fmt.Printf("  txOutDesc[%v]: value: %v ABEL, receiver: %v\n",
+				i, core.NeutrinoToAbel(txOutDesc.CoinValue), txOutDesc.AbelAddress.GetShortAbelAddress())
 		}
-	}
-	fmt.Printf("TxOutDescs:\n")
-	for i, txOutDesc := range txOutDescs {
-		fmt.Printf("  txOutDesc[%v]: value: %v ABEL, receiver: %v\n",
-			i, core.NeutrinoToAbel(txOutDesc.CoinValue), txOutDesc.AbelAddress.GetShortAbelAddress())
-	}
 
-	ds.demoCase("Get ring blocks for all TxInDescs.")
-	allRingBlockHeights := make([]int64, 0, len(txInDescsToSpend)*3)
-	for _, txInDesc := range txInDescsToSpend {
-		ringBlockHeights := core.GetRingBlockHeights(txInDesc.Height)
-		for _, ringBlockHeight := range ringBlockHeights {
-			if !contains(allRingBlockHeights, ringBlockHeight) {
-				allRingBlockHeights = append(allRingBlockHeights, ringBlockHeight)
+		ds.demoCase("Get ring blocks for all TxInDescs.")
+		ringChain := getDemoRingChain(ds.getDemoChainID())
+		allRingBlockHeights := make([]int64, 0, len(txInDescsToSpend)*ringChain.RingBlockNum)
+		for _, txInDesc := range txInDescsToSpend {
+			ringBlockHeights := ringChain.RingBlockHeights(txInDesc.Height)
+			for _, ringBlockHeight := range ringBlockHeights {
+				if !contains(allRingBlockHeights, ringBlockHeight) {
+					allRingBlockHeights = append(allRingBlockHeights, ringBlockHeight)
+				}
 			}
 		}
+		fmt.Printf("Ring block heights: %v\n", allRingBlockHeights)
+		allRingBlocks := make(map[int64]*core.TxBlockDesc)
+		for _, ringBlockHeight := range allRingBlockHeights {
+			ringBlockBytes, err := client.GetBlockBytesByHeight(ringBlockHeight)
+			if err != nil {
+				return err
+			}
+			ringBlock := core.NewTxBlockDesc(ringBlockBytes, ringBlockHeight)
+			allRingBlocks[ringBlockHeight] = ringBlock
+			fmt.Printf("  ring block %d: %v\n", ringBlock.Height, ringBlock.BinData)
+		}
+
+		ds.demoCase("Generate an UnsignedRawTx and write it to output file.")
+		txDesc := core.NewTxDescWithMemo(txInDescsToSpend, txOutDescs, estimatedTxFee, allRingBlocks, memo)
+		unsignedRawTx, err := core.GenerateUnsignedRawTx(txDesc)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(outputPath, unsignedRawTx.Bytes, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("UnsignedRawTx written to file: %v\n", outputPath)
+		ds.demoResultUnsignedRawTx(outputPath, txInDescsToSpend, txOutDescs, estimatedTxFee)
+		return nil
 	}
-	fmt.Printf("Ring block heights: %v\n", allRingBlockHeights)
-	allRingBlocks := make(map[int64]*core.TxBlockDesc)
-	for _, ringBlockHeight := range allRingBlockHeights {
-		ringBlockBytes, err := client.GetBlockBytesByHeight(ringBlockHeight)
-		ds.demoCheck(err)
-		ringBlock := core.NewTxBlockDesc(ringBlockBytes, ringBlockHeight)
-		allRingBlocks[ringBlockHeight] = ringBlock
-		fmt.Printf("  ring block %d: %v\n", ringBlock.Height, ringBlock.BinData)
-	}
+}
 
-	ds.demoCase("Generate an UnsignedRawTx and write it to output file.")
-	txDesc := core.NewTxDescWithMemo(txInDescsToSpend, txOutDescs, estimatedTxFee, allRingBlocks, core.MakeBytesFromHexString(*memoArg))
-	unsignedRawTx, err := core.GenerateUnsignedRawTx(txDesc)
-	ds.demoCheck(err)
-	err = os.WriteFile(outputPath, unsignedRawTx.Bytes, 0644)
-	ds.demoCheck(err)
-	fmt.Printf("UnsignedRawTx written to file: %v\n", outputPath)
+// demoResultUnsignedRawTx sets the result of the demos making an UnsignedRawTx.
+func (ds *DemoSet) demoResultUnsignedRawTx(outputPath string, txInDescs []*core.TxInDesc, txOutDescs []*core.TxOutDesc, txFee int64) {
+	inputs := make([]map[string]interface{}, len(txInDescs))
+	for i, txInDesc := range txInDescs {
+		inputs[i] = map[string]interface{}{
+			"txHash":     txInDesc.TxHash.HexString(),
+			"txOutIndex": txInDesc.TxOutIndex,
+			"height":     txInDesc.Height,
+			"value":      txInDesc.CoinValue,
+			"sender":     txInDesc.Owner.HexString(),
+		}
+	}
+	outputs := make([]map[string]interface{}, len(txOutDescs))
+	for i, txOutDesc := range txOutDescs {
+		outputs[i] = map[string]interface{}{
+			"value":    txOutDesc.CoinValue,
+			"receiver": txOutDesc.AbelAddress.GetShortAbelAddress().HexString(),
+		}
+	}
+	ds.demoResult("outputFile", outputPath)
+	ds.demoResult("inputs", inputs)
+	ds.demoResult("outputs", outputs)
+	ds.demoResult("txFee", txFee)
 }
 
-func (ds *DemoSet) DemoSDKMakeSignedRawTx(args []string) {
+func (ds *DemoSet) DemoSDKMakeSignedRawTx(flag *flag.FlagSet) func() error {
 	// Load default args from demo config.
 	defaultSenders := ds.getDemoConfigStringValue("SDKMakeSignedRawTx.senders")
 	defaultInputFile := ds.getDemoConfigStringValue("SDKMakeSignedRawTx.inputFile")
 	defaultOutputFile := ds.getDemoConfigStringValue("SDKMakeSignedRawTx.outputFile")
 
-	// Parse demo args.
-	flag := flag.NewFlagSet("SDKMakeSignedRawTx", flag.ContinueOnError)
+	// Declare demo args.
 	sendersArg := flag.String("senders", defaultSenders, "Seqnos of the sender accounts.")
 	inputFileArg := flag.String("inputFile", defaultInputFile, "Input file name.")
 	outputFileArg := flag.String("outputFile", defaultOutputFile, "Output file name.")
-	ds.demoExitOnError(flag.Parse(args))
-
-	ds.demoCase("Process demo args.")
-	// Load sender accounts.
-	fmt.Printf("sender seqnos: %v\n", *sendersArg)
-	demoAccounts := ds.getDemoAccounts()
-	senderSeqnos := strings.Split(*sendersArg, ",")
-	senderAccounts := make([]*DemoAccount, len(senderSeqnos))
-	for i, seqno := range senderSeqnos {
-		senderAccounts[i] = demoAccounts[atoi(seqno)]
-		fmt.Printf("  sender account %v (seqno=%v): %v\n", i, seqno, senderAccounts[i].ShortAbelAddress)
-	}
-	// Check input file.
-	inputPath := ds.getDemoFilePath(*inputFileArg)
-	fmt.Printf("input file: %v\n", inputPath)
-	if _, err := os.Stat(inputPath); err != nil {
-		ds.demoExitOnError(fmt.Errorf("input file %v does not exist", inputPath))
-	}
-	// Check output file.
-	outputPath := ds.getDemoFilePath(*outputFileArg)
-	fmt.Printf("output file: %v\n", outputPath)
-	if _, err := os.Stat(outputPath); err == nil {
-		ds.demoExitOnError(fmt.Errorf("output file %v already exists", outputPath))
-	}
-
-	ds.demoCase("Read UnsignedRawTx from input file.")
-	unsignedRawTxData, err := os.ReadFile(inputPath)
-	ds.demoCheck(err)
-	unsignedRawTx := core.NewUnsignedRawTx(unsignedRawTxData)
-	fmt.Printf("UnsignedRawTx: %v\n", unsignedRawTx)
-
-	ds.demoCase("Generate a SignedRawTx and write it to output file.")
-	senderKeys := make([]*core.CryptoKeysAndAddress, len(senderAccounts))
-	for i, senderAccount := range senderAccounts {
-		senderKeys[i] = &core.CryptoKeysAndAddress{
-			SpendSecretKey:    *senderAccount.SpendSecretKey,
-			SerialNoSecretKey: *senderAccount.SerialNoSecretKey,
-			ViewSecretKey:     *senderAccount.ViewSecretKey,
-			CryptoAddress:     *senderAccount.CryptoAddress,
-		}
-	}
-	signedRawTx, err := core.GenerateSignedRawTx(unsignedRawTx, senderKeys)
-	ds.demoCheck(err)
-	fmt.Printf("SignedRawTx: %v\n", signedRawTx.Bytes)
-	fmt.Printf("Txid: %v\n", signedRawTx.Txid.HexString())
-	err = os.WriteFile(outputPath, signedRawTx.Bytes, 0644)
-	ds.demoCheck(err)
-	fmt.Printf("SignedRawTx written to file: %v\n", outputPath)
-}
-
-func (ds *DemoSet) DemoSDKSubmitSignedRawTx(args []string) {
+	return func() error {
+		ds.demoCase("Process demo args.")
+		// Load sender accounts.
+		fmt.Printf("sender seqnos: %v\n", *sendersArg)
+		senderAccounts, err := ds.getDemoAccountsBySeqnos("-senders", *sendersArg)
+		if err != nil {
+			return err
+		}
+		for i, senderAccount := range senderAccounts {
+			fmt.Printf("  sender account %v (seqno=%v): %v\n", i, senderAccount.SerialNo, senderAccount.ShortAbelAddress)
+		}
+		// Check input file.
+		inputPath := ds.getDemoFilePath(*inputFileArg)
+		fmt.Printf("input file: %v\n", inputPath)
+		if _, err := os.Stat(inputPath); err != nil {
+			return newDemoError(DemoErrorValidation, "input file %v does not exist", inputPath)
+		}
+		// Check output file.
+		outputPath := ds.getDemoFilePath(*outputFileArg)
+		fmt.Printf("output file: %v\n", outputPath)
+		if _, err := os.Stat(outputPath); err == nil {
+			return newDemoError(DemoErrorValidation, "output file %v already exists", outputPath)
+		}
+
+		ds.demoCase("Read UnsignedRawTx from input file.")
+		unsignedRawTxData, err := os.ReadFile(inputPath)
+		if err != nil {
+			return err
+		}
+		unsignedRawTx := core.NewUnsignedRawTx(unsignedRawTxData)
+		fmt.Printf("UnsignedRawTx: %v\n", unsignedRawTx)
+
+		ds.demoCase("Generate a SignedRawTx and write it to output file.")
+		senderKeys := make([]*core.CryptoKeysAndAddress, len(senderAccounts))
+		for i, senderAccount := range senderAccounts {
+			senderKeys[i] = &core.CryptoKeysAndAddress{
+				SpendSecretKey:    *senderAccount.SpendSecretKey.CryptoKey(),
+				SerialNoSecretKey: *senderAccount.SerialNoSecretKey.CryptoKey(),
+				ViewSecretKey:     *senderAccount.ViewSecretKey.CryptoKey(),
+				CryptoAddress:     *senderAccount.CryptoAddress,
+			}
+		}
+		signedRawTx, err := core.GenerateSignedRawTx(unsignedRawTx, senderKeys)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("SignedRawTx: %v\n", signedRawTx.Bytes)
+		fmt.Printf("Txid: %v\n", signedRawTx.Txid.HexString())
+		err = os.WriteFile(outputPath, signedRawTx.Bytes, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("SignedRawTx written to file: %v\n", outputPath)
+		ds.demoResult("outputFile", outputPath)
+		ds.demoResult("txid", signedRawTx.Txid.HexString())
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKSubmitSignedRawTx(flag *flag.FlagSet) func() error {
 	// Load default args from demo config.
 	defaultInputFile := ds.getDemoConfigStringValue("SDKSubmitSignedRawTx.inputFile")
 
-	// Parse demo args.
-	flag := flag.NewFlagSet("SDKSubmitSignedRawTx", flag.ContinueOnError)
+	// Declare demo args.
 	inputFileArg := flag.String("inputFile", defaultInputFile, "Input file name.")
+	waitArg := flag.Bool("wait", false, "Wait until the tx is confirmed, evicted from the mempool or timed out.")
+	confirmationsArg := flag.Int64("confirmations", 6, "Number of confirmations to wait for.")
+	timeoutArg := flag.Duration("timeout", 30*time.Minute, "Maximum time to wait for confirmations.")
+	pollIntervalArg := flag.Duration("pollInterval", 10*time.Second, "Interval between polls of the mempool and new blocks.")
+	return func() error {
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
 
-	// Create RPC client.
-	client := ds.getDemoAbecRPCClient()
+		ds.demoCase("Process demo args.")
+		// Check input file.
+		inputPath := ds.getDemoFilePath(*inputFileArg)
+		fmt.Printf("input file: %v\n", inputPath)
+		if _, err := os.Stat(inputPath); err != nil {
+			return newDemoError(DemoErrorValidation, "input file %v does not exist", inputPath)
+		}
 
-	ds.demoCase("Process demo args.")
-	// Check input file.
-	inputPath := ds.getDemoFilePath(*inputFileArg)
-	fmt.Printf("input file: %v\n", inputPath)
-	if _, err := os.Stat(inputPath); err != nil {
-		ds.demoExitOnError(fmt.Errorf("input file %v does not exist", inputPath))
-	}
+		ds.demoCase("Read UnsignedRawTx from input file.")
+		signedRawTx, err := os.ReadFile(inputPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Length of SignedRawTx: %d\n", len(signedRawTx))
+
+		ds.demoCase("Get the latest status of the chain.")
+		_, chainInfo, err := client.GetChainInfo()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Result: %+v\n", *chainInfo)
+
+		ds.demoCase("Submit SignedRawTx.")
+		hexString := hex.EncodeToString(signedRawTx)
+		_, txHash, err := client.SendRawTx(hexString)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Returned tx hash: %v\n", *txHash)
 
-	ds.demoCase("Read UnsignedRawTx from input file.")
-	signedRawTx, err := os.ReadFile(inputPath)
-	ds.demoCheck(err)
-	fmt.Printf("Length of SignedRawTx: %d\n", len(signedRawTx))
+		ds.demoCase("Record the submitted tx in the outbox.")
+		outbox, err := ds.getDemoOutbox()
+		if err != nil {
+			return err
+		}
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+		outboxEntry := outbox.Add(*txHash, hexString, chainInfo.NumBlocks+1)
+		if err := outboxEntry.LearnSerialNumbers(); err != nil {
+			fmt.Printf("Failed to decode the serial numbers of the coins spent by the tx: %v\n", err)
+		}
+		for _, serialNumber := range outboxEntry.SerialNumbers {
+			if record := coinStore.MarkPending(serialNumber, *txHash); record != nil {
+				fmt.Printf("  coin %v of tracked account %v is now %v\n", record.ID, record.AccountSeqNo, record.Status)
+			}
+		}
+		if err := outbox.Save(); err != nil {
+			return err
+		}
+		if err := coinStore.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Outbox updated: %v\n", ds.getDemoOutboxPath())
+		ds.demoResult("txid", *txHash)
+		ds.demoResult("outbox", ds.getDemoOutboxPath())
 
-	ds.demoCase("Get the latest status of the chain.")
-	_, chainInfo, err := client.GetChainInfo()
-	ds.demoCheck(err)
-	fmt.Printf("Result: %+v\n", *chainInfo)
+		if !*waitArg {
+			return nil
+		}
 
-	ds.demoCase("Submit SignedRawTx.")
-	hexString := hex.EncodeToString(signedRawTx)
-	_, txHash, err := client.SendRawTx(hexString)
-	ds.demoCheck(err)
-	fmt.Printf("Returned tx hash: %v\n", *txHash)
+		ds.demoCase("Wait for %d confirmations of tx %v.", *confirmationsArg, *txHash)
+		tracker := NewDemoTxTracker(client, *txHash, chainInfo.NumBlocks+1)
+		lastConfirmations := int64(-1)
+		status, err := tracker.WaitForConfirmations(*confirmationsArg, *timeoutArg, *pollIntervalArg, func(status *DemoTxStatus) {
+			if status.BlockHeight >= 0 && status.Confirmations != lastConfirmations {
+				fmt.Printf("  tx included in block %v (hash=%v), confirmations: %d/%d\n",
+					status.BlockHeight, status.BlockHash, status.Confirmations, *confirmationsArg)
+				lastConfirmations = status.Confirmations
+			} else if status.BlockHeight < 0 {
+				fmt.Printf("  tx in mempool: %v\n", status.InMempool)
+			}
+			for _, record := range coinStore.ApplyTxStatus(status, tracker.SerialNumbers()) {
+				fmt.Printf("  coin %v of tracked account %v is now %v\n", record.ID, record.AccountSeqNo, record.Status)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if status.State == DemoTxConfirmed {
+			outboxEntry.State = DemoOutboxConfirmed
+			outboxEntry.BlockHeight = status.BlockHeight
+		}
+		if err := outbox.Save(); err != nil {
+			return err
+		}
+		if err := coinStore.Save(); err != nil {
+			return err
+		}
+		ds.demoResult("status", status)
+
+		switch status.State {
+		case DemoTxConfirmed:
+			fmt.Printf("🔥 Tx %v confirmed in block %v with %d confirmations.\n", status.Txid, status.BlockHeight, status.Confirmations)
+		case DemoTxEvicted:
+			return newDemoError(DemoErrorRPC, "tx %v was evicted from the mempool before being included in a block, run SDKRebroadcastTxs to resubmit it", status.Txid)
+		case DemoTxTimeout:
+			return newDemoError(DemoErrorRPC, "timed out after %v waiting for tx %v (confirmations: %d/%d)",
+				*timeoutArg, status.Txid, status.Confirmations, *confirmationsArg)
+		}
+		return nil
+	}
 }
 
-func (ds *DemoSet) DemoSDKGenerateRandomMnemonic(args []string) {
-	mnemonic, err := core.GenerateRandomMnemonic()
-	ds.demoCheck(err)
-	fmt.Printf("Mnemonic: %v\n", strings.Join(mnemonic, ","))
+func (ds *DemoSet) DemoSDKListOutbox(flag *flag.FlagSet) func() error {
+	return func() error {
+		outbox, err := ds.getDemoOutbox()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("List the txs in the outbox.")
+		fmt.Printf("Outbox: %v\n", ds.getDemoOutboxPath())
+		fmt.Printf("Scanned up to block: %v\n", outbox.ScannedHeight)
+		ds.demoResult("outbox", ds.getDemoOutboxPath())
+		ds.demoResult("scannedHeight", outbox.ScannedHeight)
+		ds.demoResult("txs", outbox.SortedEntries())
+		for _, entry := range outbox.SortedEntries() {
+			fmt.Printf("  tx %v: state: %v, submitted: %v, broadcasts: %d, inputs: %d",
+				entry.Txid, entry.State, time.Unix(entry.SubmittedAt, 0).Format(time.RFC3339), entry.BroadcastCount, len(entry.SerialNumbers))
+			if entry.BlockHeight > 0 {
+				fmt.Printf(", block: %v", entry.BlockHeight)
+			}
+			if entry.ConflictTxid != "" {
+				fmt.Printf(", conflicting tx: %v", entry.ConflictTxid)
+			}
+			if entry.LastError != "" {
+				fmt.Printf(", last error: %v", entry.LastError)
+			}
+			fmt.Printf("\n")
+		}
+		return nil
+	}
 }
 
-func (ds *DemoSet) DemoSDKGenerateCryptoSeedFromMnemonic(args []string) {
-	mnemonic := strings.Split("boost,forum,win,black,access,come,thunder,apple,lake,trip,school,romance,face,appear,rifle,dilemma,unknown,shield,juice,aspect,genuine,bottom,push,exclude", ",")
-	sequenceNumber := uint64(0)
+func (ds *DemoSet) DemoSDKRebroadcastTxs(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	intervalArg := flag.Duration("interval", 5*time.Minute, "Interval between rebroadcast rounds.")
+	roundsArg := flag.Int("rounds", 1, "Number of rebroadcast rounds, 0 to run forever.")
+	return func() error {
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+
+		included := make([]*DemoOutboxEntry, 0)
+		reverted := make([]string, 0)
+		rebroadcastTxids := make([]string, 0)
+		failures := make(map[string]string)
+		for round := 1; *roundsArg == 0 || round <= *roundsArg; round++ {
+			if round > 1 {
+				time.Sleep(*intervalArg)
+			}
+			outbox, err := ds.getDemoOutbox()
+			if err != nil {
+				return err
+			}
+			coinStore, err := ds.getDemoCoinStore()
+			if err != nil {
+				return err
+			}
 
-	cryptoSeed, err := core.GenerateCryptoSeedFromMnemonic(mnemonic, sequenceNumber)
-	ds.demoCheck(err)
-	fmt.Printf("Mnemonic: %v\n", strings.Join(mnemonic, ","))
-	fmt.Printf("sequenceNumber: %v\n", sequenceNumber)
-	fmt.Printf("CryptoSeed: %x\n", cryptoSeed)
+			ds.demoCase("Round %d: check pending txs in new blocks.", round)
+			err = outbox.ScanBlocks(client, coinStore, func(entry *DemoOutboxEntry) {
+				switch entry.State {
+				case DemoOutboxPending:
+					reverted = append(reverted, entry.Txid)
+					fmt.Printf("  ❌ tx %v is pending again, its block was replaced\n", entry.Txid)
+				case DemoOutboxConflicted:
+					included = append(included, entry)
+					fmt.Printf("  ❌ tx %v conflicts with tx %v in block %v\n", entry.Txid, entry.ConflictTxid, entry.BlockHeight)
+				default:
+					included = append(included, entry)
+					fmt.Printf("  🔥 tx %v was included in block %v\n", entry.Txid, entry.BlockHeight)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Scanned up to block: %v\n", outbox.ScannedHeight)
+
+			ds.demoCase("Round %d: rebroadcast pending txs missing from the mempool.", round)
+			_, mempool, err := client.GetMempool()
+			if err != nil {
+				return err
+			}
+			for _, entry := range outbox.PendingEntries() {
+				rebroadcast, err := entry.Rebroadcast(client, mempool)
+				if err != nil {
+					fmt.Printf("  failed to rebroadcast tx %v: %v\n", entry.Txid, err)
+					failures[entry.Txid] = err.Error()
+				} else if rebroadcast {
+					fmt.Printf("  rebroadcast tx %v (broadcasts: %d)\n", entry.Txid, entry.BroadcastCount)
+					rebroadcastTxids = append(rebroadcastTxids, entry.Txid)
+					if err := entry.LearnSerialNumbers(); err == nil {
+						for _, serialNumber := range entry.SerialNumbers {
+							coinStore.MarkPending(serialNumber, entry.Txid)
+						}
+					}
+				} else {
+					fmt.Printf("  tx %v is in the mempool\n", entry.Txid)
+				}
+			}
+			if err := outbox.Save(); err != nil {
+				return err
+			}
+			if err := coinStore.Save(); err != nil {
+				return err
+			}
+			ds.demoResult("rounds", round)
+			ds.demoResult("scannedHeight", outbox.ScannedHeight)
+			ds.demoResult("included", included)
+			ds.demoResult("reverted", reverted)
+			ds.demoResult("rebroadcast", rebroadcastTxids)
+			ds.demoResult("failures", failures)
+		}
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKAbandonTx(flag *flag.FlagSet) func() error {
+	return func() error {
+		txid := flag.Arg(0)
+		if strings.HasPrefix(txid, "0x") {
+			txid = txid[2:]
+		}
+
+		ds.demoCase("Abandon tx %v and release its coins.", txid)
+		outbox, err := ds.getDemoOutbox()
+		if err != nil {
+			return err
+		}
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+		released, err := outbox.Abandon(txid, coinStore)
+		if err != nil {
+			return err
+		}
+		for _, record := range released {
+			fmt.Printf("  coin %v of tracked account %v is now %v\n", record.ID, record.AccountSeqNo, record.Status)
+		}
+		if err := outbox.Save(); err != nil {
+			return err
+		}
+		if err := coinStore.Save(); err != nil {
+			return err
+		}
+		ds.demoResult("txid", txid)
+		ds.demoResult("released", released)
+		fmt.Printf("Released %d coins. Note the tx may still be confirmed if it remains in the mempool of any node, SDKRebroadcastTxs keeps looking for it in new blocks.\n", len(released))
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKAccountReport(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	accountsArg := flag.String("accounts", "", "Seqnos of the accounts to report, all accounts of the coin store if empty.")
+	ledgerArg := flag.Bool("ledger", true, "Print the ledger of every account.")
+	return func() error {
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+		seqnos := coinStore.AccountSeqNos()
+		if *accountsArg != "" {
+			var err error
+			seqnos, err = parseDemoSeqnos("-accounts", *accountsArg)
+			if err != nil {
+				return err
+			}
+		}
+		formatValue := func(value int64) string {
+			return fmt.Sprintf("%v ABEL (%v Neutrino)", core.NeutrinoToAbel(value), value)
+		}
+
+		ds.demoCase("Report the balances and the ledgers of the accounts.")
+		fmt.Printf("Coin store: %v\n", ds.getDemoCoinStorePath())
+		fmt.Printf("Scanned up to block: %v\n", coinStore.ScannedHeight)
+		reports := coinStore.Reports(seqnos)
+		for _, report := range reports {
+			fmt.Printf("Account %d: %d coins\n", report.AccountSeqNo, report.Coins)
+			fmt.Printf("  confirmed: %v\n", formatValue(report.Unspent))
+			fmt.Printf("  pending:   %v\n", formatValue(report.Pending))
+			fmt.Printf("  spent:     %v\n", formatValue(report.Spent))
+			if !*ledgerArg {
+				continue
+			}
+			for _, entry := range report.Ledger {
+				sign := "+"
+				if entry.Direction == DemoLedgerSpend {
+					sign = "-"
+				}
+				fmt.Printf("  block %v, %-7v %v%v ABEL, balance %v ABEL, tx %v\n",
+					entry.Height, entry.Direction, sign, core.NeutrinoToAbel(entry.Value), core.NeutrinoToAbel(entry.Balance), entry.Txid)
+			}
+		}
+		ds.demoResult("scannedHeight", coinStore.ScannedHeight)
+		ds.demoResult("accounts", reports)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKExportHistory(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	formatArg := flag.String("format", "csv", "Export format, csv or ofx.")
+	outputFileArg := flag.String("outputFile", "", "Output file name, history.<format> if empty.")
+	accountsArg := flag.String("accounts", "", "Seqnos of the accounts to export, all accounts of the coin store if empty.")
+	fromHeightArg := flag.Int64("fromHeight", 0, "First block height to export.")
+	toHeightArg := flag.Int64("toHeight", 0, "Last block height to export, the last scanned if 0.")
+	fromArg := flag.String("from", "", "First block date or RFC 3339 time to export, e.g. 2024-01-01.")
+	toArg := flag.String("to", "", "Last block date or RFC 3339 time to export, the date included, e.g. 2024-01-31.")
+	return func() error {
+		format := DemoExportFormat(*formatArg)
+		if format != DemoExportCSV && format != DemoExportOFX {
+			return newDemoError(DemoErrorUsage, "-format must be csv or ofx, not %q", *formatArg)
+		}
+		filter := &DemoExportFilter{FromHeight: *fromHeightArg, ToHeight: *toHeightArg}
+		var err error
+		if *accountsArg != "" {
+			filter.Accounts, err = parseDemoSeqnos("-accounts", *accountsArg)
+			if err != nil {
+				return err
+			}
+		}
+		if *fromArg != "" {
+			filter.From, err = ParseDemoExportTime(*fromArg, false)
+			if err != nil {
+				return newDemoError(DemoErrorUsage, "-from is neither a date nor an RFC 3339 time: %v", err)
+			}
+		}
+		if *toArg != "" {
+			filter.To, err = ParseDemoExportTime(*toArg, true)
+			if err != nil {
+				return newDemoError(DemoErrorUsage, "-to is neither a date nor an RFC 3339 time: %v", err)
+			}
+		}
+		outputFile := *outputFileArg
+		if outputFile == "" {
+			outputFile = fmt.Sprintf("history.%s", format)
+		}
+
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Export the history of the accounts as %v.", strings.ToUpper(string(format)))
+		rows, err := NewDemoExportRows(client, coinStore, filter)
+		if err != nil {
+			return err
+		}
+		outputPath := ds.getDemoFilePath(outputFile)
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if format == DemoExportCSV {
+			err = WriteDemoExportCSV(file, rows)
+		} else {
+			err = WriteDemoExportOFX(file, rows, ds.getDemoChainID(), time.Now())
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d entries to %v.\n", len(rows), outputPath)
+		ds.demoResult("outputFile", outputPath)
+		ds.demoResult("rows", rows)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKBalanceSnapshot(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	heightArg := flag.Int64("height", -1, "Height to take the snapshot at.")
+	timeArg := flag.String("time", "", "Date or RFC 3339 time to take the snapshot at, instead of -height, e.g. 2024-01-01T00:00:00Z.")
+	accountsArg := flag.String("accounts", "", "Seqnos of the demo accounts, all accounts of the coin store if empty.")
+	outputFileArg := flag.String("outputFile", "", "Output file name, snapshot-<height>.json if empty.")
+	return func() error {
+		if (*heightArg < 0) == (*timeArg == "") {
+			return newDemoError(DemoErrorUsage, "either -height or -time is required")
+		}
+		var accounts []*DemoAccount
+		var err error
+		if *accountsArg != "" {
+			accounts, err = ds.getDemoAccountsBySeqnos("-accounts", *accountsArg)
+			if err != nil {
+				return err
+			}
+		}
+		seed, err := ds.getDemoSnapshotSigningKey()
+		if err != nil {
+			return err
+		}
+
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+
+		height := *heightArg
+		if *timeArg != "" {
+			at, err := ParseDemoExportTime(*timeArg, false)
+			if err != nil {
+				return newDemoError(DemoErrorUsage, "-time is neither a date nor an RFC 3339 time: %v", err)
+			}
+			ds.demoCase("Find the last block at or before %v.", at.UTC().Format(time.RFC3339))
+			height, err = FindDemoHeightAtTime(client, at, coinStore.ScannedHeight)
+			if err != nil {
+				return err
+			}
+			if height < 0 {
+				return newDemoError(DemoErrorValidation, "no block is at or before %v", at.UTC().Format(time.RFC3339))
+			}
+			fmt.Printf("Block height: %v\n", height)
+		}
+
+		ds.demoCase("Compute the balances of the accounts at block %v.", height)
+		report, err := NewDemoSnapshotReport(client, coinStore, ds.getDemoChainID(), height, accounts)
+		if err != nil {
+			return err
+		}
+		printDemoSnapshotReport(report)
+
+		ds.demoCase("Sign the snapshot report.")
+		snapshot, err := SignDemoSnapshotReport(report, seed)
+		if err != nil {
+			return err
+		}
+		outputFile := *outputFileArg
+		if outputFile == "" {
+			outputFile = fmt.Sprintf("snapshot-%d.json", height)
+		}
+		outputPath := ds.getDemoFilePath(outputFile)
+		if err := snapshot.Save(outputPath); err != nil {
+			return err
+		}
+		fmt.Printf("Public key: %v\n", snapshot.PublicKey)
+		fmt.Printf("Saved the signed snapshot to %v.\n", outputPath)
+		ds.demoResult("outputFile", outputPath)
+		ds.demoResult("report", report)
+		ds.demoResult("publicKey", snapshot.PublicKey)
+		ds.demoResult("signature", snapshot.Signature)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKVerifySnapshot(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	publicKeyArg := flag.String("publicKey", "", "Hex encoded public key the snapshot has to be signed with, config snapshot.trustedKey if empty.")
+	return func() error {
+		// The public key carried by the snapshot proves nothing by itself, it has to be one the signer published.
+		trustedKey := *publicKeyArg
+		if trustedKey == "" {
+			trustedKey = ds.getDemoConfigStringValue("snapshot.trustedKey")
+		}
+		if trustedKey == "" {
+			return newDemoError(DemoErrorUsage, "-publicKey is required unless config snapshot.trustedKey is set")
+		}
+		path := ds.getDemoFilePath(flag.Arg(0))
+		ds.demoCase("Verify the signed snapshot %v.", path)
+		snapshot, err := LoadDemoSignedSnapshot(path)
+		if err != nil {
+			return err
+		}
+		report, err := VerifyDemoSnapshot(snapshot, trustedKey)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("The signature of public key %v is valid.\n", snapshot.PublicKey)
+		printDemoSnapshotReport(report)
+		ds.demoResult("report", report)
+		ds.demoResult("publicKey", snapshot.PublicKey)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKDepositAddress(flag *flag.FlagSet) func() error {
+	return func() error {
+		customerID := flag.Arg(0)
+		book, err := ds.getDemoDepositBook()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Allocate the deposit address of customer %v.", customerID)
+		// Allocate under the lock, so that no other process allocates the same seqno.
+		unlock, err := book.Lock()
+		if err != nil {
+			return err
+		}
+		customer, isNew, err := book.Allocate(customerID)
+		if err == nil && isNew {
+			err = book.Save()
+		}
+		unlock()
+		if err != nil {
+			return err
+		}
+		if isNew {
+			fmt.Printf("Derived account %d of the master mnemonic for the new customer.\n", customer.SeqNo)
+		} else {
+			fmt.Printf("Customer already has account %d of the master mnemonic.\n", customer.SeqNo)
+		}
+		fmt.Printf("abel address: %v\n", core.MakeBytesFromHexString(customer.AbelAddress))
+		fmt.Printf("fingerprint: %v\n", customer.Fingerprint)
+		ds.demoResult("customer", customer)
+		ds.demoResult("new", isNew)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKScanDeposits(flag *flag.FlagSet) func() error {
+	// Load default args from demo config.
+	defaultFromHeight, fromHeightErr := ds.getDemoConfigIntValue("deposit.scanFromHeight")
+	defaultConfirmations, confirmationsErr := ds.getDemoConfigIntValue("deposit.confirmations")
+
+	// Declare demo args.
+	fromHeightArg := flag.Int64("fromHeight", defaultFromHeight, "Height to start scanning blocks from, if none were scanned yet.")
+	confirmationsArg := flag.Int64("confirmations", defaultConfirmations, "Number of confirmations to credit a deposit.")
+	intervalArg := flag.Duration("interval", time.Minute, "Interval between scan rounds.")
+	roundsArg := flag.Int("rounds", 1, "Number of scan rounds, 0 to run forever.")
+	return func() error {
+		// The config is only checked before running the demo.
+		for _, err := range []error{fromHeightErr, confirmationsErr} {
+			if err != nil {
+				return err
+			}
+		}
+
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+		book, err := ds.getDemoDepositBook()
+		if err != nil {
+			return err
+		}
+		webhooks, err := ds.getDemoWebhookQueue()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("customers: %d, deposit book: %v\n", len(book.Customers), ds.getDemoDepositBookPath())
+
+		found := make([]*DemoDeposit, 0)
+		credited := make([]*DemoDeposit, 0)
+		// rewind picks up the customers allocated since the last round, and drops the deposits of the blocks
+		// replaced since then.
+		rewind := func() ([]*DemoDeposit, error) {
+			unlock, err := book.Lock()
+			if err != nil {
+				return nil, err
+			}
+			defer unlock()
+			dropped, err := book.Rewind(client)
+			if err != nil || len(dropped) == 0 {
+				return dropped, err
+			}
+			return dropped, book.Save()
+		}
+		// credit credits and saves under the lock, merging the changes saved by the other processes during the scan.
+		credit := func(tip int64) error {
+			unlock, err := book.Lock()
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			for _, deposit := range book.Credit(tip, *confirmationsArg) {
+				fmt.Printf("  💰 Credited %v ABEL to customer %v for deposit %v.\n", core.NeutrinoToAbel(deposit.Coin.Value), deposit.CustomerID, deposit.Coin.ID)
+				credited = append(credited, deposit)
+				_, err := webhooks.AddEvent(&DemoWebhookEvent{
+					ID:            fmt.Sprintf("%s:%s", DemoWebhookDepositCredited, deposit.Coin.ID),
+					Type:          DemoWebhookDepositCredited,
+					ChainID:       ds.getDemoChainID(),
+					Coin:          deposit.Coin,
+					Confirmations: tip - deposit.Coin.BlockHeight + 1,
+					CustomerID:    deposit.CustomerID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			// Save the events before the book, so that no credit goes unnotified.
+			if err := webhooks.Save(); err != nil {
+				return err
+			}
+			return book.Save()
+		}
+
+		for round := 1; *roundsArg == 0 || round <= *roundsArg; round++ {
+			if round > 1 {
+				time.Sleep(*intervalArg)
+			}
+			dropped, err := rewind()
+			if err != nil {
+				return err
+			}
+			for _, deposit := range dropped {
+				fmt.Printf("  ❌ Dropped deposit %v of customer %v, block %v was replaced.\n",
+					deposit.Coin.ID, deposit.CustomerID, deposit.Coin.BlockHeight)
+			}
+			scanner := NewDemoCoinScanner(client, book.Accounts())
+			_, chainInfo, err := client.GetChainInfo()
+			if err != nil {
+				return err
+			}
+
+			beginHeight := book.ScannedHeight + 1
+			if beginHeight < *fromHeightArg {
+				beginHeight = *fromHeightArg
+			}
+			ds.demoCase("Round %d: scan blocks %d to %d for deposits.", round, beginHeight, chainInfo.NumBlocks)
+			for height := beginHeight; height <= chainInfo.NumBlocks; height++ {
+				scannedBlock, err := scanner.ScanBlock(height)
+				if err != nil {
+					return err
+				}
+				for _, scannedCoin := range scannedBlock.Coins {
+					// Attribute the coin by the fingerprint of its address.
+					if deposit := book.Attribute(scannedCoin.Coin); deposit != nil {
+						fmt.Printf("  🔥 Found deposit %v of customer %v in block %v: %v ABEL\n",
+							deposit.Coin.ID, deposit.CustomerID, height, core.NeutrinoToAbel(deposit.Coin.Value))
+						found = append(found, deposit)
+					}
+				}
+				book.Scanned(height, scannedBlock.Hash)
+			}
+
+			ds.demoCase("Round %d: credit the deposits with %d confirmations.", round, *confirmationsArg)
+			if err := credit(chainInfo.NumBlocks); err != nil {
+				return err
+			}
+			if webhooks.Enabled() {
+				ds.demoResultWebhookAttempts(webhooks.Deliver())
+				if err := webhooks.Save(); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("Scanned up to block: %v\n", book.ScannedHeight)
+			ds.demoResult("rounds", round)
+			ds.demoResult("scannedHeight", book.ScannedHeight)
+			ds.demoResult("found", found)
+			ds.demoResult("credited", credited)
+			ds.demoResult("dropped", dropped)
+		}
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKListDeposits(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	customerArg := flag.String("customer", "", "Customer ID to list the deposits of, all customers if empty.")
+	return func() error {
+		book, err := ds.getDemoDepositBook()
+		if err != nil {
+			return err
+		}
+		if *customerArg != "" && book.Customers[*customerArg] == nil {
+			return newDemoError(DemoErrorValidation, "customer %v has no deposit address", *customerArg)
+		}
+
+		ds.demoCase("List the customers and their deposits.")
+		fmt.Printf("Deposit book: %v\n", ds.getDemoDepositBookPath())
+		fmt.Printf("Scanned up to block: %v\n", book.ScannedHeight)
+		customers := make([]*DemoDepositCustomer, 0)
+		for _, customer := range book.SortedCustomers() {
+			if *customerArg != "" && customer.CustomerID != *customerArg {
+				continue
+			}
+			customers = append(customers, customer)
+			seen, creditedValue := int64(0), int64(0)
+			for _, deposit := range book.SortedDeposits(customer.CustomerID) {
+				if deposit.State == DemoDepositCredited {
+					creditedValue += deposit.Coin.Value
+				} else {
+					seen += deposit.Coin.Value
+				}
+			}
+			fmt.Printf("  customer %v (account %d, fingerprint %v): credited: %v ABEL, awaiting confirmations: %v ABEL\n",
+				customer.CustomerID, customer.SeqNo, customer.Fingerprint, core.NeutrinoToAbel(creditedValue), core.NeutrinoToAbel(seen))
+			for _, deposit := range book.SortedDeposits(customer.CustomerID) {
+				fmt.Printf("    deposit %v in block %v: %v ABEL, %v\n", deposit.Coin.ID, deposit.Coin.BlockHeight, core.NeutrinoToAbel(deposit.Coin.Value), deposit.State)
+			}
+		}
+		ds.demoResult("scannedHeight", book.ScannedHeight)
+		ds.demoResult("customers", customers)
+		ds.demoResult("deposits", book.SortedDeposits(*customerArg))
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKRequestWithdrawal(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	addressArg := flag.String("address", "", "Hex encoded abel address to withdraw to.")
+	accountArg := flag.Int("account", -1, "Seqno of a demo account to withdraw to, instead of -address.")
+	valueArg := flag.Int64("value", 0, "Value to withdraw in Neutrino.")
+	return func() error {
+		address := *addressArg
+		if *accountArg >= 0 {
+			demoAccounts, err := ds.getDemoAccounts()
+			if err != nil {
+				return err
+			}
+			account, ok := demoAccounts[*accountArg]
+			if !ok {
+				return newDemoError(DemoErrorValidation, "demo account %d does not exist for chain %d", *accountArg, ds.getDemoChainID())
+			}
+			address = account.AbelAddress.HexString()
+		}
+		if address == "" {
+			return newDemoError(DemoErrorUsage, "either -address or -account is required")
+		}
+
+		queue, err := ds.getDemoWithdrawalQueue()
+		if err != nil {
+			return err
+		}
+		policy, err := ds.getDemoWithdrawalPolicy()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Request a withdrawal of %v ABEL.", core.NeutrinoToAbel(*valueArg))
+		// Request under the lock, so that no other process allocates the same ID.
+		unlock, err := queue.Lock()
+		if err != nil {
+			return err
+		}
+		withdrawal, err := queue.Request(address, *valueArg, ds.getDemoChainID(), policy)
+		if err == nil {
+			err = queue.Save()
+		}
+		unlock()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Withdrawal %v to fingerprint %v is %v.\n", withdrawal.ID, withdrawal.Fingerprint, withdrawal.State)
+		if withdrawal.Reason != "" {
+			fmt.Printf("reason: %v\n", withdrawal.Reason)
+		}
+		ds.demoResult("withdrawal", withdrawal)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKApproveWithdrawal(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	rejectArg := flag.Bool("reject", false, "Reject the withdrawal instead of approving it.")
+	reasonArg := flag.String("reason", "rejected by an operator", "Reason of the rejection.")
+	return func() error {
+		id := flag.Arg(0)
+		queue, err := ds.getDemoWithdrawalQueue()
+		if err != nil {
+			return err
+		}
+
+		unlock, err := queue.Lock()
+		if err != nil {
+			return err
+		}
+		var withdrawal *DemoWithdrawal
+		if *rejectArg {
+			ds.demoCase("Reject withdrawal %v.", id)
+			withdrawal, err = queue.Reject(id, *reasonArg)
+		} else {
+			ds.demoCase("Approve withdrawal %v.", id)
+			var policy *DemoWithdrawalPolicy
+			if policy, err = ds.getDemoWithdrawalPolicy(); err == nil {
+				withdrawal, err = queue.Approve(id, policy)
+			}
+		}
+		if err == nil {
+			err = queue.Save()
+		}
+		unlock()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Withdrawal %v of %v ABEL is %v.\n", withdrawal.ID, core.NeutrinoToAbel(withdrawal.Value), withdrawal.State)
+		if withdrawal.Reason != "" {
+			fmt.Printf("reason: %v\n", withdrawal.Reason)
+		}
+		ds.demoResult("withdrawal", withdrawal)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKProcessWithdrawals(flag *flag.FlagSet) func() error {
+	// Load default args from demo config.
+	defaultHotAccounts := ds.getDemoConfigStringValue("withdrawal.hotAccounts")
+	defaultBatchSize, batchSizeErr := ds.getDemoConfigIntValue("withdrawal.batchSize")
+	defaultFromHeight, fromHeightErr := ds.getDemoConfigIntValue("wallet.scanFromHeight")
+
+	// Declare demo args.
+	hotAccountsArg := flag.String("hotAccounts", defaultHotAccounts, "Seqnos of the demo accounts paying the withdrawals.")
+	batchSizeArg := flag.Int("batchSize", int(defaultBatchSize), "Maximum number of withdrawals paid by a tx.")
+	fromHeightArg := flag.Int64("fromHeight", defaultFromHeight, "Height to start scanning blocks from, if none were scanned yet.")
+	intervalArg := flag.Duration("interval", time.Minute, "Interval between rounds.")
+	roundsArg := flag.Int("rounds", 1, "Number of rounds, 0 to run forever.")
+	return func() error {
+		// The config is only checked before running the demo.
+		for _, err := range []error{batchSizeErr, fromHeightErr} {
+			if err != nil {
+				return err
+			}
+		}
+
+		if *hotAccountsArg == "" {
+			return newDemoError(DemoErrorValidation,
+				"no hot accounts to pay the withdrawals, set them with -hotAccounts or `config set withdrawal.hotAccounts <seqnos>`")
+		}
+		hotAccounts, err := ds.getDemoAccountsBySeqnos("-hotAccounts", *hotAccountsArg)
+		if err != nil {
+			return err
+		}
+
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+		queue, err := ds.getDemoWithdrawalQueue()
+		if err != nil {
+			return err
+		}
+		policy, err := ds.getDemoWithdrawalPolicy()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Load the hot wallet.")
+		accountsPath := ds.getDemoFilePath(fmt.Sprintf("wallet-accounts-chain-%d.json", ds.getDemoChainID()))
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+		outbox, err := ds.getDemoOutbox()
+		if err != nil {
+			return err
+		}
+		wallet, err := NewDemoWallet(client, ds.getDemoChainID(), hotAccounts, coinStore, outbox, accountsPath, *fromHeightArg)
+		if err != nil {
+			return err
+		}
+		hotSeqNos := make([]int, len(hotAccounts))
+		hotAddresses := make([]string, len(hotAccounts))
+		for i, account := range hotAccounts {
+			hotSeqNos[i] = account.SerialNo
+			hotAddresses[i] = account.AbelAddress.HexString()
+		}
+		fmt.Printf("hot account seqnos: %v\n", hotSeqNos)
+		fmt.Printf("withdrawal queue: %v\n", ds.getDemoWithdrawalQueuePath())
+
+		submitted := make([]*DemoWithdrawal, 0)
+		// settle settles the txs whose submission failed in a previous round or run, under the lock of the queue.
+		settle := func(round int) error {
+			unlock, err := queue.Lock()
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			txids := make([]string, 0)
+			for _, withdrawal := range queue.SortedWithdrawals(DemoWithdrawalSubmitting) {
+				if !contains(txids, withdrawal.Txid) {
+					txids = append(txids, withdrawal.Txid)
+				}
+			}
+			if len(txids) > 0 {
+				ds.demoCase("Round %d: settle the txs whose submission failed.", round)
+			}
+			for _, txid := range txids {
+				state, found, err := wallet.SettleTx(txid)
+				if err != nil {
+					fmt.Printf("  failed to submit tx %v again: %v\n", txid, err)
+				}
+				for _, withdrawal := range queue.Settle(txid, state, found, err == nil) {
+					fmt.Printf("  withdrawal %v of %v ABEL is %v\n", withdrawal.ID, core.NeutrinoToAbel(withdrawal.Value), withdrawal.State)
+				}
+			}
+			// Reconcile the submitted and paid withdrawals with the final state of their txs in the outbox.
+			for _, txid := range queue.SubmittedTxids() {
+				state, found := wallet.OutboxState(txid)
+				for _, withdrawal := range queue.Reconcile(txid, state, found) {
+					fmt.Printf("  withdrawal %v of %v ABEL is %v\n", withdrawal.ID, core.NeutrinoToAbel(withdrawal.Value), withdrawal.State)
+				}
+			}
+			return queue.Save()
+		}
+		// payBatch pays a batch of the queued withdrawals under the lock of the queue, so that no other process
+		// changes its withdrawals meanwhile. It returns false once there is no batch left to pay.
+		payBatch := func() (bool, error) {
+			unlock, err := queue.Lock()
+			if err != nil {
+				return false, err
+			}
+			defer unlock()
+			now := time.Now()
+			batch := queue.Batch(policy, now, *batchSizeArg)
+			if len(batch) == 0 {
+				return false, queue.Save()
+			}
+			request := &DemoWalletTxRequest{Senders: hotSeqNos}
+			for _, withdrawal := range batch {
+				request.Outputs = append(request.Outputs, &DemoWalletTxOutput{Address: withdrawal.Address, Value: withdrawal.Value})
+			}
+			unsignedTx, err := wallet.BuildUnsignedTx(request)
+			var kinder demoErrorKinder
+			if errors.As(err, &kinder) && kinder.DemoErrorKind() == DemoErrorInsufficientFunds {
+				// Leave the batch queued until the hot accounts are refilled.
+				fmt.Printf("  %v\n", err)
+				for _, withdrawal := range batch {
+					withdrawal.Reason = "waits for the hot accounts to be refilled"
+				}
+				return false, queue.Save()
+			}
+			if err != nil {
+				return false, err
+			}
+
+			// The policy checks the tx once more before it is signed.
+			if err := policy.CheckTx(unsignedTx, batch, hotAddresses, queue.SubmittedValue(now.Add(-24*time.Hour).Unix())); err != nil {
+				return false, err
+			}
+			signedTx, err := wallet.SignTx(unsignedTx.UnsignedRawTx, unsignedTx.Signers)
+			if err != nil {
+				return false, err
+			}
+
+			// Save the withdrawals as submitting before submitting the tx, so that none is ever paid twice.
+			for _, withdrawal := range batch {
+				withdrawal.State = DemoWithdrawalSubmitting
+				withdrawal.Reason = ""
+				withdrawal.Txid = signedTx.Txid
+				withdrawal.SubmittedAt = now.Unix()
+			}
+			if err := queue.Save(); err != nil {
+				return false, err
+			}
+			_, err = wallet.SubmitTx(signedTx.SignedRawTx)
+			// Only a tx rejected by the node, or never recorded, puts the batch back in the queue.
+			state, found := wallet.OutboxState(signedTx.Txid)
+			queue.Settle(signedTx.Txid, state, found, err == nil)
+			if err := queue.Save(); err != nil {
+				return false, err
+			}
+			if err != nil {
+				return false, err
+			}
+			fmt.Printf("  🚀 Submitted tx %v paying %d withdrawals with a fee of %v ABEL.\n", signedTx.Txid, len(batch), core.NeutrinoToAbel(unsignedTx.Fee))
+			submitted = append(submitted, batch...)
+			return true, nil
+		}
+
+		for round := 1; *roundsArg == 0 || round <= *roundsArg; round++ {
+			if round > 1 {
+				time.Sleep(*intervalArg)
+			}
+
+			ds.demoCase("Round %d: sync the coins of the hot accounts.", round)
+			for {
+				scanned, err := wallet.Sync(100)
+				if err != nil {
+					return err
+				}
+				if scanned == 0 {
+					break
+				}
+			}
+			for _, balance := range wallet.Balances() {
+				if contains(hotSeqNos, balance.AccountSeqNo) {
+					fmt.Printf("  account %d: unspent %v ABEL, pending %v ABEL\n", balance.AccountSeqNo, core.NeutrinoToAbel(balance.Unspent), core.NeutrinoToAbel(balance.Pending))
+				}
+			}
+			if err := settle(round); err != nil {
+				return err
+			}
+
+			ds.demoCase("Round %d: pay the queued withdrawals.", round)
+			for {
+				paid, err := payBatch()
+				if err != nil {
+					return err
+				}
+				if !paid {
+					break
+				}
+			}
+
+			for _, withdrawal := range queue.SortedWithdrawals("") {
+				if withdrawal.State == DemoWithdrawalQueued || withdrawal.State == DemoWithdrawalHeld {
+					fmt.Printf("  withdrawal %v of %v ABEL is %v: %v\n", withdrawal.ID, core.NeutrinoToAbel(withdrawal.Value), withdrawal.State, withdrawal.Reason)
+				}
+			}
+			ds.demoResult("rounds", round)
+			ds.demoResult("submitted", submitted)
+		}
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKListWithdrawals(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	stateArg := flag.String("state", "", "State of the withdrawals to list, one of queued, held, submitting, submitted, paid or rejected, all if empty.")
+	return func() error {
+		state := DemoWithdrawalState(*stateArg)
+		if state != "" && !contains([]string{"queued", "held", "submitting", "submitted", "paid", "rejected"}, *stateArg) {
+			return newDemoError(DemoErrorUsage, "-state must be one of queued, held, submitting, submitted, paid or rejected, not %q", *stateArg)
+		}
+		queue, err := ds.getDemoWithdrawalQueue()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("List the withdrawals.")
+		fmt.Printf("Withdrawal queue: %v\n", ds.getDemoWithdrawalQueuePath())
+		fmt.Printf("Submitted in the last 24 hours: %v ABEL\n", core.NeutrinoToAbel(queue.SubmittedValue(time.Now().Add(-24*time.Hour).Unix())))
+		withdrawals := queue.SortedWithdrawals(state)
+		for _, withdrawal := range withdrawals {
+			fmt.Printf("  withdrawal %v of %v ABEL to fingerprint %v: %v", withdrawal.ID, core.NeutrinoToAbel(withdrawal.Value), withdrawal.Fingerprint, withdrawal.State)
+			if withdrawal.Txid != "" {
+				fmt.Printf(" in tx %v", withdrawal.Txid)
+			}
+			if withdrawal.Reason != "" {
+				fmt.Printf(", %v", withdrawal.Reason)
+			}
+			fmt.Println()
+		}
+		ds.demoResult("withdrawals", withdrawals)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKDeliverWebhooks(flag *flag.FlagSet) func() error {
+	// Declare demo args.
+	intervalArg := flag.Duration("interval", time.Minute, "Interval between delivery rounds.")
+	roundsArg := flag.Int("rounds", 1, "Number of delivery rounds, 0 to run until the queue is empty.")
+	logArg := flag.Int("log", 10, "Number of the latest delivery attempts to list.")
+	return func() error {
+		webhooks, err := ds.getDemoWebhookQueue()
+		if err != nil {
+			return err
+		}
+		if !webhooks.Enabled() {
+			return newDemoError(DemoErrorValidation, "no webhook configured, set webhook.url and webhook.secret")
+		}
+		fmt.Printf("Webhook queue: %v\n", ds.getDemoWebhookQueuePath())
+
+		for round := 1; *roundsArg == 0 || round <= *roundsArg; round++ {
+			if round > 1 {
+				if len(webhooks.PendingDeliveries()) == 0 {
+					break
+				}
+				time.Sleep(*intervalArg)
+			}
+			ds.demoCase("Round %d: deliver the due webhook events.", round)
+			ds.demoResultWebhookAttempts(webhooks.Deliver())
+			if err := webhooks.Save(); err != nil {
+				return err
+			}
+			ds.demoResult("rounds", round)
+		}
+
+		ds.demoCase("List the pending deliveries and the latest attempts.")
+		pending := webhooks.PendingDeliveries()
+		for _, delivery := range pending {
+			fmt.Printf("  delivery %v: attempts: %d, next attempt: %v\n",
+				delivery.ID, delivery.Attempts, time.Unix(delivery.NextAttemptAt, 0).Format(time.RFC3339))
+		}
+		log := webhooks.SortedLog()
+		if len(log) > *logArg {
+			log = log[:*logArg]
+		}
+		for _, attempt := range log {
+			fmt.Printf("  %v delivery %v attempt %d: %v", time.Unix(attempt.At, 0).Format(time.RFC3339), attempt.DeliveryID, attempt.Attempt, attempt.State)
+			if attempt.StatusCode != 0 {
+				fmt.Printf(", status: %d", attempt.StatusCode)
+			}
+			if attempt.Error != "" {
+				fmt.Printf(", error: %v", attempt.Error)
+			}
+			fmt.Printf("\n")
+		}
+		ds.demoResult("pending", pending)
+		ds.demoResult("log", log)
+		return nil
+	}
+}
+
+func (ds *DemoSet) demoResultWebhookAttempts(attempts []*DemoWebhookAttempt) {
+	delivered := 0
+	for _, attempt := range attempts {
+		if attempt.State == DemoWebhookDelivered {
+			delivered += 1
+			fmt.Printf("  delivered %v to %v\n", attempt.DeliveryID, attempt.URL)
+		} else {
+			fmt.Printf("  failed to deliver %v to %v (attempt %d, %v): %v\n", attempt.DeliveryID, attempt.URL, attempt.Attempt, attempt.State, attempt.Error)
+		}
+	}
+	fmt.Printf("Delivered %d of %d webhook events.\n", delivered, len(attempts))
+	ds.demoResult("webhookAttempts", attempts)
+}
+
+func (ds *DemoSet) DemoSDKWalletServer(flag *flag.FlagSet) func() error {
+	// Load default args from demo config.
+	defaultListen := ds.getDemoConfigStringValue("wallet.listen")
+	defaultGRPCListen := ds.getDemoConfigStringValue("wallet.grpcListen")
+	defaultAccounts := ds.getDemoConfigStringValue("wallet.accounts")
+	defaultFromHeight, fromHeightErr := ds.getDemoConfigIntValue("wallet.scanFromHeight")
+	defaultSyncInterval, syncIntervalErr := ds.getDemoConfigDurationValue("wallet.syncInterval")
+
+	// Declare demo args.
+	listenArg := flag.String("listen", defaultListen, "Address to serve the wallet API on.")
+	grpcListenArg := flag.String("grpcListen", defaultGRPCListen, "Address to serve the wallet gRPC service on, disabled if empty.")
+	accountsArg := flag.String("accounts", defaultAccounts, "Seqnos of the demo accounts to track, all of them if empty.")
+	fromHeightArg := flag.Int64("fromHeight", defaultFromHeight, "Height to start scanning blocks from, if none were scanned yet.")
+	syncIntervalArg := flag.Duration("syncInterval", defaultSyncInterval, "Interval between syncs once caught up with the chain.")
+	return func() error {
+		// The config is only checked before running the demo.
+		for _, err := range []error{fromHeightErr, syncIntervalErr} {
+			if err != nil {
+				return err
+			}
+		}
+
+		apiKey := ds.getDemoConfigStringValue("wallet.apiKey")
+		if apiKey == "" {
+			return newDemoError(DemoErrorValidation,
+				"no API key for the wallet API, set it with `config set wallet.apiKey <key>` or ABELSDK_WALLET_APIKEY")
+		}
+
+		// Create RPC client.
+		client, err := ds.getDemoAbecRPCClient()
+		if err != nil {
+			return err
+		}
+
+		ds.demoCase("Load the tracked accounts, the coin store and the outbox.")
+		var accounts []*DemoAccount
+		if *accountsArg == "" {
+			demoAccounts, err := ds.getDemoAccounts()
+			if err != nil {
+				return err
+			}
+			for _, account := range demoAccounts {
+				accounts = append(accounts, account)
+			}
+		} else {
+			accounts, err = ds.getDemoAccountsBySeqnos("-accounts", *accountsArg)
+			if err != nil {
+				return err
+			}
+		}
+		accountsPath := ds.getDemoFilePath(fmt.Sprintf("wallet-accounts-chain-%d.json", ds.getDemoChainID()))
+		coinStore, err := ds.getDemoCoinStore()
+		if err != nil {
+			return err
+		}
+		outbox, err := ds.getDemoOutbox()
+		if err != nil {
+			return err
+		}
+		wallet, err := NewDemoWallet(client, ds.getDemoChainID(), accounts, coinStore, outbox, accountsPath, *fromHeightArg)
+		if err != nil {
+			return err
+		}
+		webhooks, err := ds.getDemoWebhookQueue()
+		if err != nil {
+			return err
+		}
+		wallet.SetWebhooks(webhooks)
+		fmt.Printf("tracked account seqnos: %v\n", wallet.AccountSeqNos())
+		fmt.Printf("wallet accounts: %v\n", accountsPath)
+		fmt.Printf("coin store: %v\n", ds.getDemoCoinStorePath())
+
+		ds.demoCase("Serve the wallet API and sync the coin store in the background.")
+		server, err := NewDemoWalletServer(wallet, apiKey)
+		if err != nil {
+			return err
+		}
+		if err := server.Start(*listenArg); err != nil {
+			return err
+		}
+		defer server.Close()
+		fmt.Printf("Listening on %s, send the API key in an X-API-Key header. Press Ctrl-C to stop.\n", server.URL)
+		if *grpcListenArg != "" {
+			grpcServer, err := NewDemoWalletGRPCServer(wallet, apiKey)
+			if err != nil {
+				return err
+			}
+			if err := grpcServer.Start(*grpcListenArg); err != nil {
+				return err
+			}
+			defer grpcServer.Close()
+			fmt.Printf("Serving the gRPC service on %s, send the API key in the x-api-key metadata.\n", grpcServer.Addr)
+			ds.demoResult("grpcAddr", grpcServer.Addr)
+		}
+
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			for {
+				// Sync in batches until caught up, so the API serves fresh balances along the way.
+				scanned, err := wallet.Sync(100)
+				status := wallet.SyncStatus()
+				if err != nil {
+					fmt.Printf("Sync failed at block %v: %v\n", status.ScannedHeight+1, err)
+				} else if scanned > 0 {
+					fmt.Printf("Synced up to block %v of %v.\n", status.ScannedHeight, status.Tip)
+				}
+				if webhooks.Enabled() {
+					for _, attempt := range webhooks.Deliver() {
+						if attempt.Error != "" {
+							fmt.Printf("Webhook delivery %v failed (attempt %d, %v): %v\n", attempt.DeliveryID, attempt.Attempt, attempt.State, attempt.Error)
+						}
+					}
+					if err := webhooks.Save(); err != nil {
+						fmt.Printf("Failed to save the webhook queue: %v\n", err)
+					}
+				}
+				if err == nil && status.ScannedHeight < status.Tip {
+					select {
+					case <-stop:
+						return
+					default:
+						continue
+					}
+				}
+				select {
+				case <-stop:
+					return
+				case <-time.After(*syncIntervalArg):
+				}
+			}
+		}()
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		<-interrupt
+		close(stop)
+		<-stopped
+		status := wallet.SyncStatus()
+		fmt.Printf("Stopped at block %v.\n", status.ScannedHeight)
+		ds.demoResult("url", server.URL)
+		ds.demoResult("scannedHeight", status.ScannedHeight)
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKGenerateRandomMnemonic(flag *flag.FlagSet) func() error {
+	return func() error {
+		mnemonic, err := core.GenerateRandomMnemonic()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Mnemonic: %v\n", DemoSecret(strings.Join(mnemonic, ",")))
+		ds.demoResult("mnemonic", DemoSecret(strings.Join(mnemonic, ",")))
+		return nil
+	}
+}
+
+func (ds *DemoSet) DemoSDKGenerateCryptoSeedFromMnemonic(flag *flag.FlagSet) func() error {
+	return func() error {
+		mnemonic := strings.Split("boost,forum,win,black,access,come,thunder,apple,lake,trip,school,romance,face,appear,rifle,dilemma,unknown,shield,juice,aspect,genuine,bottom,push,exclude", ",")
+		sequenceNumber := uint64(0)
+
+		cryptoSeed, err := core.GenerateCryptoSeedFromMnemonic(mnemonic, sequenceNumber)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Mnemonic: %v\n", DemoSecret(strings.Join(mnemonic, ",")))
+		fmt.Printf("sequenceNumber: %v\n", sequenceNumber)
+		fmt.Printf("CryptoSeed: %x\n", DemoSecretBytes(cryptoSeed))
+		ds.demoResult("mnemonic", DemoSecret(strings.Join(mnemonic, ",")))
+		ds.demoResult("sequenceNumber", sequenceNumber)
+		ds.demoResult("cryptoSeed", DemoSecretBytes(cryptoSeed))
+		return nil
+	}
 }
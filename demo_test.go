@@ -0,0 +1,751 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Golden-output tests run every demo against a mock abec node serving the chain fixture in
+// testdata/fixture, and compare its normalized stdout with testdata/golden/<DemoName>.golden.
+//
+// Run `go test -tags test -run TestDemoGolden -update` to (re)generate the fixture, if missing,
+// and the golden files. Review the diff of the golden files before committing them.
+var updateGolden = flag.Bool("update", false, "Update the golden files, and generate the chain fixture if missing.")
+
+const (
+	demoGoldenFixtureDir = "testdata/fixture"
+	demoGoldenDir        = "testdata/golden"
+	demoGoldenRunEnv     = "ABELSDK_DEMO_GOLDEN_RUN"
+	demoGoldenArgsEnv    = "ABELSDK_DEMO_GOLDEN_ARGS"
+	demoGoldenUsername   = "golden"
+	demoGoldenPassword   = "golden"
+)
+
+// demoGoldenCase describes how a demo is run in the golden test.
+type demoGoldenCase struct {
+	// args returns the demo args, it may look at the fixture and at the files left by the demos run before.
+	args func(env *demoGoldenEnv) []string
+	// masks are patterns of random values to replace in the output, on top of demoGoldenMasks.
+	masks []*regexp.Regexp
+	// unordered matches lines printed in the order of random values, such as tx hashes. The runs of
+	// consecutive matching lines are sorted once masked.
+	unordered *regexp.Regexp
+	// config holds config values of this demo only, on top of demoGoldenConfig. They are passed to the demo
+	// as ABELSDK_* env vars, so they have to be keys of the config schema.
+	config map[string]string
+	// files are files the demo writes to the demo env, whose contents are appended to its output.
+	files []string
+	// serve is called once a demo serving until interrupted prints the URL it listens on. Its output is
+	// appended to the output of the demo, which is then interrupted.
+	serve func(t *testing.T, env *demoGoldenEnv, url string) []byte
+	// webhooks points the webhook config of the demo at the receiver of the golden test, whose log of the
+	// requests it got is appended to the output of the demo.
+	webhooks bool
+	// skip tells why the demo is not run.
+	skip string
+}
+
+type demoGoldenEnv struct {
+	dir      string
+	endpoint string
+	fixture  *DemoChainFixture
+	server   *DemoMockAbecServer
+	webhooks *demoGoldenWebhookReceiver
+}
+
+var (
+	demoHashMask        = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+	demoBytesMask       = regexp.MustCompile(`data:[0-9a-f.∅]+\|md5:[0-9a-f.∅]+`)
+	demoFingerprintMask = regexp.MustCompile(`fp:[0-9a-f.∅]+`)
+	demoURLMask         = regexp.MustCompile(`http://127\.0\.0\.1:\d+`)
+	demoTimeMask        = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?( [A-Z]{3,4})?( m=[+-][\d.]+)?`)
+)
+
+// demoGoldenMasks apply to the output of all demos.
+var demoGoldenMasks = []*regexp.Regexp{demoTimeMask}
+
+// demoGoldenWorkflow lists demos that depend on the files left by each other, in the order they are run.
+// All other demos are run before them in alphabetical order.
+var demoGoldenWorkflow = []string{
+	"SDKTrackCoins",
+	"SDKDeliverWebhooks",
+	// The withdrawals are paid before any block is mined by the mock node, since the mined blocks have no
+	// coinbase to build the rings of their coins.
+	"SDKRequestWithdrawal",
+	"SDKApproveWithdrawal",
+	"SDKProcessWithdrawals",
+	"SDKListWithdrawals",
+	"SDKMakeUnsignedRawTx",
+	"SDKMakeUnsignedRawTxWithMemo",
+	"SDKMakeSignedRawTx",
+	"SDKSubmitSignedRawTx",
+	"SDKGetMempool",
+	"SDKWatchMempool",
+	"SDKListOutbox",
+	"SDKRebroadcastTxs",
+	"SDKAbandonTx",
+	"SDKDepositAddress",
+	"SDKWalletServer",
+	"SDKAccountReport",
+	"SDKExportHistory",
+	"SDKBalanceSnapshot",
+	"SDKVerifySnapshot",
+	"SDKScanDeposits",
+	"SDKListDeposits",
+}
+
+var demoGoldenCases = map[string]*demoGoldenCase{
+	"BasicAddress": {
+		masks: []*regexp.Regexp{demoBytesMask, demoFingerprintMask},
+	},
+	"BasicAbecRPCClient": {
+		// The responses are printed with %+v, which shows the pointers they hold.
+		masks: []*regexp.Regexp{regexp.MustCompile(`0x[0-9a-f]{9,}`)},
+		// The calls are recorded for BasicRPCCassette.
+		config: map[string]string{"abec.rpc.record": demoGoldenCassette},
+	},
+	"BasicBytes": {
+		masks: []*regexp.Regexp{
+			demoBytesMask,
+			regexp.MustCompile(`bytes\.Slice\(\): \[(\d+ ){15}\d+\]`),
+			regexp.MustCompile(`bytes\.(HexString|Base64String|Md5|Sha256)\(\): [0-9a-zA-Z+/=]{16,}`),
+		},
+	},
+	"BasicGenerateAccounts": {
+		args: func(env *demoGoldenEnv) []string { return []string{"-count", "2"} },
+	},
+	"BasicGenerateChainFixture": {
+		args: func(env *demoGoldenEnv) []string {
+			return []string{"-blocks", "6", "-outputDir", filepath.Join(env.dir, "generated-fixture")}
+		},
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"BasicRPCCassette": {
+		args: func(env *demoGoldenEnv) []string { return []string{"-file", demoGoldenCassette} },
+	},
+	"BasicMockAbecServer": {
+		skip: "serves until interrupted, the golden test itself runs against the mock node",
+	},
+	"SDKAbandonTx": {
+		args: func(env *demoGoldenEnv) []string {
+			outbox, err := LoadDemoOutbox(filepath.Join(env.dir, fmt.Sprintf("outbox-chain-%d.json", env.fixture.ChainInfo.NetID)), 10)
+			if err != nil || len(outbox.PendingEntries()) == 0 {
+				return nil
+			}
+			return []string{outbox.PendingEntries()[0].Txid}
+		},
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKAccountReport": {
+		args:  func(env *demoGoldenEnv) []string { return []string{"-accounts", "0,1,2,3"} },
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKBalanceSnapshot": {
+		args:   func(env *demoGoldenEnv) []string { return []string{"-height", "20"} },
+		config: map[string]string{"snapshot.signingKey": demoGoldenSnapshotSeed},
+	},
+	"SDKDeliverWebhooks": {
+		masks:    []*regexp.Regexp{demoURLMask},
+		webhooks: true,
+	},
+	"SDKDepositAddress": {
+		args:   func(env *demoGoldenEnv) []string { return []string{"customer-1"} },
+		config: map[string]string{"deposit.mnemonic": demoGoldenDepositMnemonic},
+	},
+	"SDKExportHistory": {
+		args:  func(env *demoGoldenEnv) []string { return []string{"-format", "csv", "-outputFile", "history.csv"} },
+		files: []string{"history.csv"},
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKGenerateAddresses": {
+		masks: []*regexp.Regexp{demoBytesMask, demoFingerprintMask},
+	},
+	"SDKGenerateCryptoKeysAndAddress": {
+		masks: []*regexp.Regexp{demoBytesMask, demoFingerprintMask},
+	},
+	"SDKGenerateRandomMnemonic": {
+		masks: []*regexp.Regexp{regexp.MustCompile(`Mnemonic: .*`)},
+	},
+	"SDKGetBlockOrTx": {
+		args: func(env *demoGoldenEnv) []string { return []string{"1"} },
+	},
+	"SDKGetMemoInTx": {
+		args: func(env *demoGoldenEnv) []string { return []string{env.fixture.Blocks[4].Block.TxHashes[1]} },
+	},
+	"SDKGetMempool": {
+		masks: []*regexp.Regexp{demoHashMask, regexp.MustCompile(`Time:\d+`)},
+	},
+	"SDKListDeposits": {
+		masks:  []*regexp.Regexp{demoHashMask},
+		config: map[string]string{"deposit.mnemonic": demoGoldenDepositMnemonic},
+	},
+	"SDKListOutbox": {
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKMakeSignedRawTx": {
+		masks: []*regexp.Regexp{demoHashMask, demoBytesMask},
+	},
+	"SDKMakeUnsignedRawTxWithMemo": {
+		args: func(env *demoGoldenEnv) []string {
+			return []string{"-outputFile", "unsigned-raw-tx-with-memo.json", "-memo", "676f6c64656e"}
+		},
+	},
+	"SDKRebroadcastTxs": {
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKScanDeposits": {
+		masks:  []*regexp.Regexp{demoHashMask},
+		config: map[string]string{"deposit.mnemonic": demoGoldenDepositMnemonic, "deposit.confirmations": "2"},
+	},
+	"SDKSubmitSignedRawTx": {
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKTrackCoins": {
+		masks:    []*regexp.Regexp{demoURLMask},
+		webhooks: true,
+	},
+	"SDKRequestWithdrawal": {
+		args: func(env *demoGoldenEnv) []string { return []string{"-account", "2", "-value", "1000000000"} },
+		// The withdrawal is held for SDKApproveWithdrawal.
+		config: map[string]string{"withdrawal.approvalThreshold": "100000000"},
+	},
+	"SDKApproveWithdrawal": {
+		args: func(env *demoGoldenEnv) []string { return []string{"w000001"} },
+	},
+	"SDKProcessWithdrawals": {
+		masks: []*regexp.Regexp{demoHashMask},
+		// The hot wallet shares the coin store of the later demos, so it has to scan the coins of all their accounts.
+		config: map[string]string{"withdrawal.hotAccounts": "0,1,2,3"},
+	},
+	"SDKListWithdrawals": {
+		masks: []*regexp.Regexp{demoHashMask},
+	},
+	"SDKVerifySnapshot": {
+		args:   func(env *demoGoldenEnv) []string { return []string{"snapshot-20.json"} },
+		config: map[string]string{"snapshot.trustedKey": demoGoldenSnapshotPublicKey()},
+	},
+	"SDKWatchMempool": {
+		args:   func(env *demoGoldenEnv) []string { return []string{"-accounts", "0,1,2,3"} },
+		masks:  []*regexp.Regexp{demoHashMask},
+		config: map[string]string{"mempool.pollInterval": "10ms"},
+		// The txs of SDKProcessWithdrawals and SDKSubmitSignedRawTx are polled in the order of their hashes.
+		unordered: regexp.MustCompile(`^  ⏳ `),
+	},
+	"SDKWalletServer": {
+		args: func(env *demoGoldenEnv) []string { return []string{"-listen", "127.0.0.1:0"} },
+		config: map[string]string{
+			"wallet.apiKey":       demoGoldenPassword,
+			"wallet.accounts":     "0,1,2,3",
+			"wallet.syncInterval": "1h",
+		},
+		serve: serveDemoGoldenWallet,
+		masks: []*regexp.Regexp{demoHashMask, demoURLMask},
+	},
+}
+
+const (
+	// demoGoldenDepositMnemonic is the master mnemonic of the deposit addresses, fixed so that they are too.
+	demoGoldenDepositMnemonic = "nation,such,wrong,choice,crisp,town,nerve,water,make,scorpion,cannon,cargo," +
+		"section,pumpkin,tiny,bring,brother,sister,weapon,infant,armed,term,coral,idle"
+	demoGoldenSnapshotSeed  = "0101010101010101010101010101010101010101010101010101010101010101"
+	demoGoldenWebhookSecret = "golden"
+	demoGoldenCassette      = "golden-cassette.json"
+)
+
+// demoGoldenWebhookReceiver receives the webhook events of the demos, failing the first attempt of every
+// delivery so that the retries are exercised, and logs the requests it gets.
+type demoGoldenWebhookReceiver struct {
+	*httptest.Server
+	attempts map[string]int
+	log      bytes.Buffer
+	mutex    sync.Mutex
+}
+
+func newDemoGoldenWebhookReceiver() *demoGoldenWebhookReceiver {
+	receiver := &demoGoldenWebhookReceiver{attempts: make(map[string]int)}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(receiver.serveHTTP))
+	return receiver
+}
+
+func (receiver *demoGoldenWebhookReceiver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	signature := "valid"
+	if err := VerifyDemoWebhookSignature(demoGoldenWebhookSecret, r.Header.Get(DemoWebhookSignatureHeader), body, time.Minute); err != nil {
+		signature = err.Error()
+	}
+	var event DemoWebhookEvent
+	json.Unmarshal(body, &event)
+
+	deliveryID := r.Header.Get(DemoWebhookDeliveryHeader)
+	receiver.attempts[deliveryID] += 1
+	status := http.StatusOK
+	if receiver.attempts[deliveryID] == 1 {
+		status = http.StatusServiceUnavailable
+	}
+	fmt.Fprintf(&receiver.log, "%s %s: confirmations: %d, signature: %s, status: %d\n",
+		r.Header.Get(DemoWebhookEventHeader), deliveryID, event.Confirmations, signature, status)
+	w.WriteHeader(status)
+}
+
+// takeLog returns the requests logged since the last call.
+func (receiver *demoGoldenWebhookReceiver) takeLog() []byte {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	log := append([]byte(nil), receiver.log.Bytes()...)
+	receiver.log.Reset()
+	return log
+}
+
+func demoGoldenSnapshotPublicKey() string {
+	seed, _ := hex.DecodeString(demoGoldenSnapshotSeed)
+	return hex.EncodeToString(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+}
+
+// serveDemoGoldenWallet waits for the wallet server to sync up to the tip of the fixture, and pays the deposit
+// address of customer-1 from account 0 through the API. The tx is then mined, with enough blocks on top of it
+// for SDKScanDeposits to credit the deposit.
+func serveDemoGoldenWallet(t *testing.T, env *demoGoldenEnv, url string) []byte {
+	var served bytes.Buffer
+	call := func(method string, path string, request interface{}, result interface{}) {
+		var body io.Reader
+		if request != nil {
+			data, err := json.Marshal(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequest(method, url+path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-API-Key", demoGoldenPassword)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := &struct {
+			OK     bool            `json:"ok"`
+			Result json.RawMessage `json:"result"`
+		}{}
+		if err := json.Unmarshal(data, output); err != nil || !output.OK {
+			t.Fatalf("%s %s failed with status %d: %s", method, path, resp.StatusCode, data)
+		}
+		if err := json.Unmarshal(output.Result, result); err != nil {
+			t.Fatal(err)
+		}
+		// The raw txs are too large to be part of the output, and random anyway.
+		if path != "/v1/sync" && path != "/v1/txs/unsigned" && path != "/v1/txs/sign" {
+			fmt.Fprintf(&served, "%s %s\n%s\n", method, path, data)
+		}
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	for {
+		sync := &struct {
+			Sync *DemoWalletSyncStatus `json:"sync"`
+		}{}
+		call(http.MethodGet, "/v1/sync", nil, sync)
+		if sync.Sync.ScannedHeight == env.fixture.Tip() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("wallet is not synced up to block %d after a minute", env.fixture.Tip())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	call(http.MethodGet, "/v1/balances", nil, &struct{}{})
+
+	book, err := LoadDemoDepositBook(filepath.Join(env.dir, fmt.Sprintf("deposits-chain-%d.json", env.fixture.ChainInfo.NetID)),
+		strings.Split(demoGoldenDepositMnemonic, ","), int8(env.fixture.ChainInfo.NetID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := book.Customers["customer-1"]
+	if customer == nil {
+		t.Fatal("customer-1 has no deposit address, SDKDepositAddress has to run first")
+	}
+	unsigned := &struct {
+		Tx *DemoWalletUnsignedTx `json:"tx"`
+	}{}
+	call(http.MethodPost, "/v1/txs/unsigned", &DemoWalletTxRequest{
+		Senders: []int{0},
+		Outputs: []*DemoWalletTxOutput{{Address: customer.AbelAddress, Value: core.AbelToNeutrino(10)}},
+	}, unsigned)
+	signed := &struct {
+		Tx *DemoWalletSignedTx `json:"tx"`
+	}{}
+	call(http.MethodPost, "/v1/txs/sign", map[string]interface{}{"unsignedRawTx": unsigned.Tx.UnsignedRawTx, "signers": unsigned.Tx.Signers}, signed)
+	call(http.MethodPost, "/v1/txs/submit", map[string]interface{}{"signedRawTx": signed.Tx.SignedRawTx}, &struct{}{})
+	if _, err := env.server.Generate(3); err != nil {
+		t.Fatal(err)
+	}
+	return served.Bytes()
+}
+
+// demoGoldenConfig points the demos to the mock node and to the coins of the fixture.
+//
+// The coinbase of block 5 pays account 1 and is never spent in the fixture, so it is the only coin
+// found by SDKMakeUnsignedRawTx and the tx built from it is accepted by the mock node.
+func demoGoldenConfig(env *demoGoldenEnv) map[string]string {
+	return map[string]string{
+		"chainID":           fmt.Sprintf("%d", env.fixture.ChainInfo.NetID),
+		"abec.rpc.endpoint": env.endpoint,
+		"abec.rpc.username": demoGoldenUsername,
+		"abec.rpc.password": demoGoldenPassword,
+
+		"SDKTrackCoins.accounts":         "0,1,2,3",
+		"SDKTrackCoins.scanHeightRange":  "0,8",
+		"SDKTrackCoins.trackHeightRange": fmt.Sprintf("0,%d", env.fixture.Tip()),
+
+		"SDKMakeUnsignedRawTx.scanHeightRange": "5,5",
+		"SDKMakeUnsignedRawTx.senders":         "1",
+		"SDKMakeUnsignedRawTx.receivers":       "2,3",
+		"SDKMakeUnsignedRawTx.outputFile":      "unsigned-raw-tx.json",
+
+		"SDKMakeSignedRawTx.senders":    "1",
+		"SDKMakeSignedRawTx.inputFile":  "unsigned-raw-tx.json",
+		"SDKMakeSignedRawTx.outputFile": "signed-raw-tx.json",
+
+		"SDKSubmitSignedRawTx.inputFile": "signed-raw-tx.json",
+	}
+}
+
+// demoGoldenFixtureSpec is used to generate the fixture once, the fixture is then checked in.
+// The accounts are those bundled for chain 2, read without going through the demo config.
+func demoGoldenFixtureSpec(t testing.TB) *DemoChainFixtureSpec {
+	data, err := demoResources.ReadFile("resources/accounts-chain-2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var demoAccountResource DemoAccountResource
+	if err := json.Unmarshal(data, &demoAccountResource); err != nil {
+		t.Fatal(err)
+	}
+	demoAccounts, err := NewDemoAccounts(&demoAccountResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DemoChainFixtureSpec{
+		ChainID:       2,
+		NumBlocks:     30,
+		Accounts:      []*DemoAccount{demoAccounts[0], demoAccounts[1], demoAccounts[2], demoAccounts[3]},
+		CoinbaseValue: core.AbelToNeutrino(256),
+		TxFee:         core.AbelToNeutrino(0.1),
+		GenesisTime:   1700000000,
+		BlockInterval: 256,
+	}
+}
+
+func TestMain(m *testing.M) {
+	// Run a single demo when the test binary is re-executed by runDemoGolden.
+	if name := os.Getenv(demoGoldenRunEnv); name != "" {
+		var args []string
+		json.Unmarshal([]byte(os.Getenv(demoGoldenArgsEnv)), &args)
+		os.Exit(ds.demoExit(RunDemo(name, args)))
+	}
+	os.Exit(m.Run())
+}
+
+func TestDemoGolden(t *testing.T) {
+	fixture, err := LoadDemoChainFixture(demoGoldenFixtureDir)
+	if os.IsNotExist(err) && *updateGolden {
+		t.Logf("generating chain fixture in %s", demoGoldenFixtureDir)
+		fixture, err = GenerateDemoChainFixture(demoGoldenFixtureSpec(t))
+		if err == nil {
+			err = fixture.Save(demoGoldenFixtureDir)
+		}
+	}
+	if os.IsNotExist(err) {
+		t.Skipf("no chain fixture in %s, run `go test -tags test -run TestDemoGolden -update` to generate it", demoGoldenFixtureDir)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewDemoMockAbecServer(fixture, demoGoldenUsername, demoGoldenPassword)
+	if err := server.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	webhooks := newDemoGoldenWebhookReceiver()
+	defer webhooks.Close()
+
+	env := &demoGoldenEnv{dir: t.TempDir(), endpoint: server.URL, fixture: fixture, server: server, webhooks: webhooks}
+	configBytes, err := json.MarshalIndent(demoGoldenConfig(env), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.dir, ".config.json"), configBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range demoGoldenOrder(GetAllDemoNames()) {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			goldenCase := demoGoldenCases[name]
+			if goldenCase == nil {
+				goldenCase = &demoGoldenCase{}
+			}
+			if goldenCase.skip != "" {
+				t.Skip(goldenCase.skip)
+			}
+			var args []string
+			if goldenCase.args != nil {
+				args = goldenCase.args(env)
+			}
+
+			output := runDemoGolden(t, env, name, goldenCase, args)
+			for _, file := range goldenCase.files {
+				data, err := os.ReadFile(filepath.Join(env.dir, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				output = append(append(output, fmt.Sprintf("\n[file: %s]\n", file)...), data...)
+			}
+			output = normalizeDemoGoldenOutput(output, env, goldenCase.masks)
+			if goldenCase.unordered != nil {
+				output = sortDemoGoldenLines(output, goldenCase.unordered)
+			}
+			goldenPath := filepath.Join(demoGoldenDir, name+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(demoGoldenDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, output, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+			}
+			if !bytes.Equal(output, golden) {
+				t.Errorf("output differs from %s, run with -update and review the diff if the change is intended:\n%s",
+					goldenPath, diffDemoGoldenOutput(golden, output))
+			}
+		})
+	}
+}
+
+// demoGoldenOrder sorts demo names alphabetically, with the workflow demos last in their own order.
+func demoGoldenOrder(names []string) []string {
+	rank := func(name string) int {
+		for i, workflowName := range demoGoldenWorkflow {
+			if name == workflowName {
+				return i + 1
+			}
+		}
+		return 0
+	}
+
+	sorted := make([]string, len(names))
+	copy(sorted, names)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if rank(sorted[i]) != rank(sorted[j]) {
+			return rank(sorted[i]) < rank(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// runDemoGolden runs the demo in a child process since demos exit the process on error.
+// It returns the stdout of the demo followed by its exit code.
+//
+// The child only sees the config of the demo env and of the case: the ABELSDK_* env vars of the test
+// process are left out, so that neither the shell of the developer nor another case leaks into it.
+func runDemoGolden(t *testing.T, env *demoGoldenEnv, name string, goldenCase *demoGoldenCase, args []string) []byte {
+	argsBytes, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, "ABELSDK_") {
+			cmd.Env = append(cmd.Env, variable)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		demoGoldenRunEnv+"="+name,
+		demoGoldenArgsEnv+"="+string(argsBytes),
+		"ABELSDK_DEMO_ENV="+env.dir,
+	)
+	for key, value := range goldenCase.config {
+		cmd.Env = append(cmd.Env, getDemoConfigEnvName(key)+"="+value)
+	}
+	if goldenCase.webhooks {
+		// The retries are due as soon as the next delivery round.
+		for key, value := range map[string]string{
+			"webhook.url":            env.webhooks.URL,
+			"webhook.secret":         demoGoldenWebhookSecret,
+			"webhook.retryBaseDelay": "1ms",
+			"webhook.retryMaxDelay":  "1ms",
+		} {
+			cmd.Env = append(cmd.Env, getDemoConfigEnvName(key)+"="+value)
+		}
+	}
+	stdout := &demoGoldenBuffer{}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	var served []byte
+	if goldenCase.serve == nil {
+		err = cmd.Run()
+	} else {
+		served, err = serveDemoGolden(t, env, cmd, stdout, goldenCase.serve)
+	}
+
+	exitCode := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+		t.Logf("demo exited with code %d, stderr:\n%s", exitCode, stderr.String())
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	output := stdout.Bytes()
+	if served != nil {
+		output = append(append(output, "\n[served]\n"...), served...)
+	}
+	if goldenCase.webhooks {
+		output = append(append(output, "\n[webhooks]\n"...), env.webhooks.takeLog()...)
+	}
+	return append(output, fmt.Sprintf("\n[exit code: %d]\n", exitCode)...)
+}
+
+var demoGoldenListeningPattern = regexp.MustCompile(`Listening on (http://\S+?),`)
+
+// serveDemoGolden starts a demo serving until interrupted, calls serve with the URL it listens on, then
+// interrupts it and waits for it to exit.
+func serveDemoGolden(t *testing.T, env *demoGoldenEnv, cmd *exec.Cmd, stdout *demoGoldenBuffer,
+	serve func(t *testing.T, env *demoGoldenEnv, url string) []byte) ([]byte, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	// Kill the demo if serve fails the test, it has exited otherwise.
+	defer cmd.Process.Kill()
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(time.Minute)
+	for {
+		if match := demoGoldenListeningPattern.FindSubmatch(stdout.Bytes()); match != nil {
+			served := serve(t, env, string(match[1]))
+			cmd.Process.Signal(os.Interrupt)
+			return served, <-exited
+		}
+		select {
+		case err := <-exited:
+			// The demo failed before it served.
+			return nil, err
+		case <-deadline:
+			cmd.Process.Kill()
+			<-exited
+			t.Fatalf("demo does not listen after a minute, output:\n%s", stdout.Bytes())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// demoGoldenBuffer collects the output of a demo while it is read by serveDemoGolden.
+type demoGoldenBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *demoGoldenBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *demoGoldenBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]byte(nil), b.buffer.Bytes()...)
+}
+
+func normalizeDemoGoldenOutput(output []byte, env *demoGoldenEnv, masks []*regexp.Regexp) []byte {
+	normalized := string(output)
+	normalized = strings.ReplaceAll(normalized, env.dir, "$DEMO_ENV")
+	normalized = strings.ReplaceAll(normalized, env.endpoint, "$ABEC_RPC_ENDPOINT")
+	for _, mask := range append(demoGoldenMasks, masks...) {
+		normalized = mask.ReplaceAllString(normalized, "<random>")
+	}
+	return []byte(normalized)
+}
+
+// sortDemoGoldenLines sorts the runs of consecutive lines matching pattern.
+func sortDemoGoldenLines(output []byte, pattern *regexp.Regexp) []byte {
+	lines := strings.Split(string(output), "\n")
+	for start := 0; start < len(lines); start++ {
+		end := start
+		for end < len(lines) && pattern.MatchString(lines[end]) {
+			end++
+		}
+		sort.Strings(lines[start:end])
+		start = end
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// diffDemoGoldenOutput shows the first differing lines, which is enough to spot most regressions.
+func diffDemoGoldenOutput(golden []byte, output []byte) string {
+	goldenLines := strings.Split(string(golden), "\n")
+	outputLines := strings.Split(string(output), "\n")
+	var diff strings.Builder
+	shown := 0
+	for i := 0; i < len(goldenLines) || i < len(outputLines); i++ {
+		var goldenLine, outputLine string
+		if i < len(goldenLines) {
+			goldenLine = goldenLines[i]
+		}
+		if i < len(outputLines) {
+			outputLine = outputLines[i]
+		}
+		if goldenLine == outputLine {
+			continue
+		}
+		fmt.Fprintf(&diff, "line %d:\n- %s\n+ %s\n", i+1, goldenLine, outputLine)
+		shown += 1
+		if shown >= 10 {
+			diff.WriteString("...\n")
+			break
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,362 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for the deposit addresses of customers.
+//
+// Every customer gets its own account, derived from the master mnemonic at the next sequence number.
+// Only the customer mapping is saved, the keys are derived again from the mnemonic when loaded. The
+// coins found for these accounts are attributed to the customers by the fingerprints of their
+// addresses, and credited once their blocks have enough confirmations. Until then, the hashes of the
+// scanned blocks are kept, so that the deposits of the blocks replaced by a reorg are dropped and the
+// new blocks scanned again, see Rewind.
+type DemoDepositState string
+
+const (
+	DemoDepositSeen     DemoDepositState = "seen"
+	DemoDepositCredited DemoDepositState = "credited"
+)
+
+var demoCustomerIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:@-]{1,64}$`)
+
+type DemoDepositCustomer struct {
+	CustomerID  string `json:"customerID"`
+	SeqNo       uint64 `json:"seqno"`
+	AbelAddress string `json:"abelAddress"`
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   int64  `json:"createdAt"`
+}
+
+type DemoDeposit struct {
+	CustomerID     string           `json:"customerID"`
+	Coin           *DemoCoinRecord  `json:"coin"`
+	State          DemoDepositState `json:"state"`
+	SeenAt         int64            `json:"seenAt"`
+	CreditedAt     int64            `json:"creditedAt,omitempty"`
+	CreditedHeight int64            `json:"creditedHeight,omitempty"`
+}
+
+type DemoDepositBook struct {
+	path     string
+	chainID  int8
+	mnemonic []string
+	accounts map[string]*DemoAccount
+	// MnemonicID identifies the master mnemonic, so that a book is never used with another mnemonic.
+	MnemonicID    string `json:"mnemonicID"`
+	NextSeqNo     uint64 `json:"nextSeqno"`
+	ScannedHeight int64  `json:"scannedHeight"`
+	// ScannedHashes holds the hashes of the scanned blocks without enough confirmations to credit their deposits.
+	ScannedHashes map[int64]string                `json:"scannedHashes"`
+	Customers     map[string]*DemoDepositCustomer `json:"customers"`
+	Deposits      map[string]*DemoDeposit         `json:"deposits"`
+}
+
+// getDemoMnemonicID returns the fingerprint of the first account of a mnemonic, which is public unlike any
+// digest of the mnemonic itself.
+func getDemoMnemonicID(mnemonic []string, chainID int8) (string, error) {
+	account, err := NewDemoAccountFromMnemonic(mnemonic, 0, chainID)
+	if err != nil {
+		return "", err
+	}
+	return account.Fingerprint.HexString(), nil
+}
+
+// LoadDemoDepositBook loads a deposit book and derives the accounts of its customers from the mnemonic.
+func LoadDemoDepositBook(path string, mnemonic []string, chainID int8) (*DemoDepositBook, error) {
+	mnemonicID, err := getDemoMnemonicID(mnemonic, chainID)
+	if err != nil {
+		return nil, err
+	}
+	book := &DemoDepositBook{
+		path:          path,
+		chainID:       chainID,
+		mnemonic:      mnemonic,
+		accounts:      make(map[string]*DemoAccount),
+		MnemonicID:    mnemonicID,
+		ScannedHeight: -1,
+		ScannedHashes: make(map[int64]string),
+		Customers:     make(map[string]*DemoDepositCustomer),
+		Deposits:      make(map[string]*DemoDeposit),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, book)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deposit book %s: %v", path, err)
+	}
+	if book.MnemonicID != mnemonicID {
+		return nil, newDemoError(DemoErrorValidation, "deposit book %s was made with another master mnemonic", path)
+	}
+	if book.ScannedHashes == nil {
+		book.ScannedHashes = make(map[int64]string)
+	}
+	if book.Customers == nil {
+		book.Customers = make(map[string]*DemoDepositCustomer)
+	}
+	if book.Deposits == nil {
+		book.Deposits = make(map[string]*DemoDeposit)
+	}
+	for _, customer := range book.Customers {
+		account, err := NewDemoAccountFromMnemonic(mnemonic, customer.SeqNo, chainID)
+		if err != nil {
+			return nil, err
+		}
+		if account.Fingerprint.HexString() != customer.Fingerprint {
+			return nil, fmt.Errorf("account %d of customer %s does not match the master mnemonic", customer.SeqNo, customer.CustomerID)
+		}
+		book.accounts[customer.Fingerprint] = account
+	}
+
+	return book, nil
+}
+
+func (book *DemoDepositBook) Save() error {
+	data, err := json.MarshalIndent(book, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeDemoSecretFile(book.path, data)
+}
+
+// Lock takes the lock of the book file, and merges the book with the one saved by the other processes since
+// it was loaded, e.g. the customers allocated by SDKDepositAddress while SDKScanDeposits runs. The book should
+// be saved before calling the returned function, which releases the lock.
+func (book *DemoDepositBook) Lock() (func(), error) {
+	unlock, err := lockDemoFile(book.path)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := LoadDemoDepositBook(book.path, book.mnemonic, book.chainID)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	book.merge(saved)
+	return unlock, nil
+}
+
+// merge adds the customers and deposits of another book of the same mnemonic, keeping the credited state of the
+// deposits and the highest seqno and scanned height of both books, along with the hashes of that scan.
+func (book *DemoDepositBook) merge(other *DemoDepositBook) {
+	for customerID, customer := range other.Customers {
+		if _, ok := book.Customers[customerID]; !ok {
+			book.Customers[customerID] = customer
+			book.accounts[customer.Fingerprint] = other.accounts[customer.Fingerprint]
+		}
+	}
+	for id, deposit := range other.Deposits {
+		if existing, ok := book.Deposits[id]; !ok || (existing.State == DemoDepositSeen && deposit.State == DemoDepositCredited) {
+			book.Deposits[id] = deposit
+		}
+	}
+	if other.NextSeqNo > book.NextSeqNo {
+		book.NextSeqNo = other.NextSeqNo
+	}
+	if other.ScannedHeight > book.ScannedHeight {
+		book.ScannedHeight = other.ScannedHeight
+		book.ScannedHashes = other.ScannedHashes
+	}
+}
+
+// Allocate returns the deposit address of a customer, deriving a new account for a new customer.
+// It returns true if the customer is new.
+func (book *DemoDepositBook) Allocate(customerID string) (*DemoDepositCustomer, bool, error) {
+	if !demoCustomerIDPattern.MatchString(customerID) {
+		return nil, false, newDemoError(DemoErrorValidation, "customer ID %q is not made of 1 to 64 letters, digits or _.:@-", customerID)
+	}
+	if customer, ok := book.Customers[customerID]; ok {
+		return customer, false, nil
+	}
+
+	account, err := NewDemoAccountFromMnemonic(book.mnemonic, book.NextSeqNo, book.chainID)
+	if err != nil {
+		return nil, false, err
+	}
+	customer := &DemoDepositCustomer{
+		CustomerID:  customerID,
+		SeqNo:       book.NextSeqNo,
+		AbelAddress: account.AbelAddress.HexString(),
+		Fingerprint: account.Fingerprint.HexString(),
+		CreatedAt:   time.Now().Unix(),
+	}
+	book.Customers[customerID] = customer
+	book.accounts[customer.Fingerprint] = account
+	book.NextSeqNo += 1
+	return customer, true, nil
+}
+
+// Accounts returns the accounts of the customers, ordered by seqno.
+func (book *DemoDepositBook) Accounts() []*DemoAccount {
+	accounts := make([]*DemoAccount, 0, len(book.accounts))
+	for _, account := range book.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].SerialNo < accounts[j].SerialNo })
+	return accounts
+}
+
+// FindCustomer returns the customer owning the address with the given fingerprint, or nil.
+func (book *DemoDepositBook) FindCustomer(fingerprint core.Bytes) *DemoDepositCustomer {
+	for _, customer := range book.Customers {
+		if customer.Fingerprint == fingerprint.HexString() {
+			return customer
+		}
+	}
+	return nil
+}
+
+// Attribute records a coin found for a customer account, returning nil if it is not new or not of a customer.
+func (book *DemoDepositBook) Attribute(coin *core.Coin) *DemoDeposit {
+	customer := book.FindCustomer(coin.OwnerShortAddress.Fingerprint())
+	if customer == nil {
+		return nil
+	}
+	record := NewDemoCoinRecord(coin, int(customer.SeqNo))
+	if _, ok := book.Deposits[record.ID]; ok {
+		return nil
+	}
+	deposit := &DemoDeposit{
+		CustomerID: customer.CustomerID,
+		Coin:       record,
+		State:      DemoDepositSeen,
+		SeenAt:     time.Now().Unix(),
+	}
+	book.Deposits[record.ID] = deposit
+	return deposit
+}
+
+// Scanned records a scanned block.
+func (book *DemoDepositBook) Scanned(height int64, hash string) {
+	book.ScannedHeight = height
+	book.ScannedHashes[height] = hash
+}
+
+// Rewind checks the hashes of the scanned blocks against the node, from the last one down to the first one still
+// on the chain. The deposits of the replaced blocks are dropped and returned, and the scanned height is set back
+// to the last block still on the chain, so that the blocks replacing them are scanned again.
+//
+// Only the blocks without enough confirmations to credit their deposits are checked: the credited deposits are
+// final, as long as no reorg goes deeper than the confirmations.
+func (book *DemoDepositBook) Rewind(client DemoAbecRPCClient) ([]*DemoDeposit, error) {
+	forkHeight := book.ScannedHeight + 1
+	for height := book.ScannedHeight; height >= 0; height-- {
+		hash, ok := book.ScannedHashes[height]
+		if !ok {
+			break
+		}
+		_, nodeHash, err := client.GetBlockHash(height)
+		if err != nil {
+			return nil, err
+		}
+		if *nodeHash == hash {
+			break
+		}
+		forkHeight = height
+	}
+	if forkHeight > book.ScannedHeight {
+		return nil, nil
+	}
+
+	dropped := make([]*DemoDeposit, 0)
+	for _, deposit := range book.SortedDeposits("") {
+		if deposit.State == DemoDepositSeen && deposit.Coin.BlockHeight >= forkHeight {
+			delete(book.Deposits, deposit.Coin.ID)
+			dropped = append(dropped, deposit)
+		}
+	}
+	for height := range book.ScannedHashes {
+		if height >= forkHeight {
+			delete(book.ScannedHashes, height)
+		}
+	}
+	book.ScannedHeight = forkHeight - 1
+	return dropped, nil
+}
+
+// Credit credits the deposits with the given confirmations at the given tip, and returns them. The hashes of the
+// blocks deep enough to be credited are no longer kept.
+func (book *DemoDepositBook) Credit(tip int64, confirmations int64) []*DemoDeposit {
+	credited := make([]*DemoDeposit, 0)
+	for _, deposit := range book.SortedDeposits("") {
+		if deposit.State != DemoDepositSeen || tip-deposit.Coin.BlockHeight+1 < confirmations {
+			continue
+		}
+		deposit.State = DemoDepositCredited
+		deposit.CreditedAt = time.Now().Unix()
+		deposit.CreditedHeight = tip
+		credited = append(credited, deposit)
+	}
+	for height := range book.ScannedHashes {
+		if tip-height+1 >= confirmations {
+			delete(book.ScannedHashes, height)
+		}
+	}
+	return credited
+}
+
+// SortedDeposits returns the deposits of a customer, or of all customers if empty, ordered by block height.
+func (book *DemoDepositBook) SortedDeposits(customerID string) []*DemoDeposit {
+	deposits := make([]*DemoDeposit, 0)
+	for _, deposit := range book.Deposits {
+		if customerID == "" || deposit.CustomerID == customerID {
+			deposits = append(deposits, deposit)
+		}
+	}
+	sort.Slice(deposits, func(i, j int) bool {
+		if deposits[i].Coin.BlockHeight != deposits[j].Coin.BlockHeight {
+			return deposits[i].Coin.BlockHeight < deposits[j].Coin.BlockHeight
+		}
+		return deposits[i].Coin.ID < deposits[j].Coin.ID
+	})
+	return deposits
+}
+
+// SortedCustomers returns the customers ordered by seqno.
+func (book *DemoDepositBook) SortedCustomers() []*DemoDepositCustomer {
+	customers := make([]*DemoDepositCustomer, 0, len(book.Customers))
+	for _, customer := range book.Customers {
+		customers = append(customers, customer)
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].SeqNo < customers[j].SeqNo })
+	return customers
+}
+
+func (ds *DemoSet) getDemoDepositBookPath() string {
+	return ds.getDemoFilePath(fmt.Sprintf("deposits-chain-%d.json", ds.getDemoChainID()))
+}
+
+func (ds *DemoSet) getDemoDepositBook() (*DemoDepositBook, error) {
+	mnemonic := ds.getDemoConfigStringValue("deposit.mnemonic")
+	if mnemonic == "" {
+		return nil, newDemoError(DemoErrorValidation,
+			"no master mnemonic for the deposit addresses, generate one with SDKGenerateRandomMnemonic and set it with `config set deposit.mnemonic`")
+	}
+	path := ds.getDemoDepositBookPath()
+	unlock, err := lockDemoFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return LoadDemoDepositBook(path, strings.Split(mnemonic, ","), ds.getDemoChainID())
+}
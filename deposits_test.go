@@ -0,0 +1,149 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	core "abelian.info/sdk/core"
+)
+
+// demoReorgClient serves the block hashes of a chain whose blocks from a height on were replaced.
+type demoReorgClient struct {
+	DemoAbecRPCClient
+	forkHeight int64
+}
+
+func (client *demoReorgClient) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	hash := fmt.Sprintf("b%d", height)
+	if height >= client.forkHeight {
+		hash += "'"
+	}
+	return nil, &hash, nil
+}
+
+func newDemoDepositTestBook(t *testing.T) *DemoDepositBook {
+	book, err := LoadDemoDepositBook(filepath.Join(t.TempDir(), "deposits.json"), strings.Split(demoGoldenDepositMnemonic, ","), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+// addDemoDepositTestDeposits scans the blocks 0 to 9, with a deposit of customer-1 in every odd block.
+func addDemoDepositTestDeposits(t *testing.T, book *DemoDepositBook) {
+	if _, _, err := book.Allocate("customer-1"); err != nil {
+		t.Fatal(err)
+	}
+	for height := int64(0); height < 10; height++ {
+		if height%2 == 1 {
+			id := fmt.Sprintf("t%d:0", height)
+			book.Deposits[id] = &DemoDeposit{
+				CustomerID: "customer-1",
+				Coin:       &DemoCoinRecord{ID: id, BlockHash: fmt.Sprintf("b%d", height), BlockHeight: height},
+				State:      DemoDepositSeen,
+			}
+		}
+		book.Scanned(height, fmt.Sprintf("b%d", height))
+	}
+}
+
+func getDemoDepositTestIDs(deposits []*DemoDeposit) []string {
+	ids := make([]string, 0, len(deposits))
+	for _, deposit := range deposits {
+		ids = append(ids, deposit.Coin.ID)
+	}
+	return ids
+}
+
+func TestDemoDepositBookMnemonic(t *testing.T) {
+	book := newDemoDepositTestBook(t)
+	customer, _, err := book.Allocate("customer-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The mnemonic is identified by the fingerprint of its first account, that of the first customer.
+	if book.MnemonicID != customer.Fingerprint || strings.Contains(book.MnemonicID, demoGoldenDepositMnemonic) {
+		t.Errorf("got mnemonic ID %s, expecting the fingerprint %s", book.MnemonicID, customer.Fingerprint)
+	}
+	if err := book.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadDemoDepositBook(book.path, book.mnemonic, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Customers) != 1 || loaded.Accounts()[0].Fingerprint.HexString() != customer.Fingerprint {
+		t.Errorf("got customers %v from the saved book, expecting %s", loaded.Customers, customer.CustomerID)
+	}
+
+	otherMnemonic, err := core.GenerateRandomMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDemoDepositBook(book.path, otherMnemonic, 0); demoExitCode(err) != DemoErrorValidation.ExitCode() {
+		t.Errorf("got %v loading the book with another mnemonic, expecting a validation error", err)
+	}
+}
+
+func TestDemoDepositBookCredit(t *testing.T) {
+	book := newDemoDepositTestBook(t)
+	addDemoDepositTestDeposits(t, book)
+
+	// At tip 9, the blocks up to 6 have at least 4 confirmations.
+	credited := book.Credit(9, 4)
+	if got := strings.Join(getDemoDepositTestIDs(credited), ","); got != "t1:0,t3:0,t5:0" {
+		t.Errorf("got credited deposits %s, expecting t1:0,t3:0,t5:0", got)
+	}
+	if credited := book.Credit(9, 4); len(credited) != 0 {
+		t.Errorf("got credited deposits %v again, expecting none", getDemoDepositTestIDs(credited))
+	}
+	for _, deposit := range credited {
+		if deposit.State != DemoDepositCredited || deposit.CreditedHeight != 9 {
+			t.Errorf("got deposit %s %v at height %d, expecting it credited at height 9", deposit.Coin.ID, deposit.State, deposit.CreditedHeight)
+		}
+	}
+	if len(book.ScannedHashes) != 3 {
+		t.Errorf("got the hashes of %d blocks, expecting those of the blocks 7 to 9", len(book.ScannedHashes))
+	}
+}
+
+func TestDemoDepositBookRewind(t *testing.T) {
+	book := newDemoDepositTestBook(t)
+	addDemoDepositTestDeposits(t, book)
+	book.Credit(9, 4)
+
+	dropped, err := book.Rewind(&demoReorgClient{forkHeight: 10})
+	if err != nil || len(dropped) != 0 || book.ScannedHeight != 9 {
+		t.Fatalf("got %v, %v, scanned height %d without a reorg, expecting nothing dropped", getDemoDepositTestIDs(dropped), err, book.ScannedHeight)
+	}
+
+	dropped, err = book.Rewind(&demoReorgClient{forkHeight: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(getDemoDepositTestIDs(dropped), ","); got != "t9:0" {
+		t.Errorf("got dropped deposits %s, expecting t9:0", got)
+	}
+	if book.ScannedHeight != 7 || len(book.ScannedHashes) != 1 {
+		t.Errorf("got scanned height %d and %d hashes, expecting 7 and the hash of block 7", book.ScannedHeight, len(book.ScannedHashes))
+	}
+	if _, ok := book.Deposits["t7:0"]; !ok {
+		t.Errorf("deposit of block 7 was dropped, expecting it kept")
+	}
+
+	// The credited deposits are final, whatever the node says.
+	dropped, err = book.Rewind(&demoReorgClient{forkHeight: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(getDemoDepositTestIDs(dropped), ","); got != "t7:0" || len(book.Deposits) != 3 {
+		t.Errorf("got dropped deposits %s and %d deposits left, expecting t7:0 and the 3 credited ones", got, len(book.Deposits))
+	}
+}
@@ -0,0 +1,320 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for exporting the ledgers of the accounts to accounting tools.
+//
+// A row is exported per ledger entry, dated by the time of its block. The fee of a tx is given once,
+// on the first spend of the tracked account with the lowest seqno spending coins in the tx, the
+// counterparty is known when it is another tracked account, and the memo is the text of the tx memo,
+// or its hex encoding if it is not printable.
+type DemoExportFormat string
+
+const (
+	DemoExportCSV DemoExportFormat = "csv"
+	DemoExportOFX DemoExportFormat = "ofx"
+)
+
+type DemoExportRow struct {
+	AccountSeqNo int                 `json:"account"`
+	BlockTime    int64               `json:"blockTime"`
+	Height       int64               `json:"height"`
+	Direction    DemoLedgerDirection `json:"direction"`
+	Value        int64               `json:"value"`
+	Fee          int64               `json:"fee"`
+	Counterparty string              `json:"counterparty,omitempty"`
+	Memo         string              `json:"memo,omitempty"`
+	Txid         string              `json:"txid"`
+	CoinID       string              `json:"coinID"`
+	Balance      int64               `json:"balance"`
+}
+
+// DemoExportFilter selects the ledger entries to export. Zero values do not filter.
+type DemoExportFilter struct {
+	Accounts   []int
+	FromHeight int64
+	ToHeight   int64
+	// From and To are the first block time exported and the block time from which none is.
+	From time.Time
+	To   time.Time
+}
+
+// NewDemoExportRows returns the rows of the ledger entries selected by the filter, in chain order.
+func NewDemoExportRows(client DemoAbecRPCClient, store *DemoCoinStore, filter *DemoExportFilter) ([]*DemoExportRow, error) {
+	accounts := filter.Accounts
+	if len(accounts) == 0 {
+		accounts = store.AccountSeqNos()
+	}
+
+	txAccounts := newDemoExportTxAccounts(store)
+	txs := make(map[string]*demoExportTx)
+	feePaid := make(map[string]bool)
+	rows := make([]*DemoExportRow, 0)
+	for _, seqno := range accounts {
+		for _, entry := range store.Ledger(seqno) {
+			if (filter.FromHeight > 0 && entry.Height < filter.FromHeight) || (filter.ToHeight > 0 && entry.Height > filter.ToHeight) {
+				continue
+			}
+			tx, ok := txs[entry.Txid]
+			if !ok {
+				_, abecTx, err := client.GetRawTx(entry.Txid)
+				if err != nil {
+					return nil, err
+				}
+				// The fee in ABEL is not exact in float64, add half a Neutrino since AbelToNeutrino truncates.
+				tx = &demoExportTx{
+					blockTime: abecTx.BlockTime,
+					fee:       core.AbelToNeutrino(abecTx.Fee + core.NeutrinoToAbel(1)/2),
+					memo:      getDemoExportMemo(abecTx.Memo),
+				}
+				txs[entry.Txid] = tx
+			}
+			blockTime := time.Unix(tx.blockTime, 0)
+			if (!filter.From.IsZero() && blockTime.Before(filter.From)) || (!filter.To.IsZero() && !blockTime.Before(filter.To)) {
+				continue
+			}
+
+			row := &DemoExportRow{
+				AccountSeqNo: seqno,
+				BlockTime:    tx.blockTime,
+				Height:       entry.Height,
+				Direction:    entry.Direction,
+				Value:        entry.Value,
+				Counterparty: txAccounts.counterparty(entry),
+				Memo:         tx.memo,
+				Txid:         entry.Txid,
+				CoinID:       entry.CoinID,
+				Balance:      entry.Balance,
+			}
+			if entry.Direction == DemoLedgerSpend && !feePaid[entry.Txid] && txAccounts.feePayer(entry.Txid) == seqno {
+				row.Fee = tx.fee
+				feePaid[entry.Txid] = true
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Height < rows[j].Height })
+	return rows, nil
+}
+
+type demoExportTx struct {
+	blockTime int64
+	fee       int64
+	memo      string
+}
+
+func getDemoExportMemo(memoHex string) string {
+	memo, err := DecodeDemoMemo(memoHex)
+	if err != nil || memo.Len() == 0 {
+		return ""
+	}
+	text := string(memo.Slice())
+	if utf8.ValidString(text) && strings.IndexFunc(text, func(r rune) bool { return !unicode.IsPrint(r) }) < 0 {
+		return text
+	}
+	return "0x" + memo.HexString()
+}
+
+// demoExportTxAccounts indexes the tracked accounts by the txs spending and receiving their coins.
+type demoExportTxAccounts struct {
+	spenders  map[string][]int
+	receivers map[string][]int
+}
+
+func newDemoExportTxAccounts(store *DemoCoinStore) *demoExportTxAccounts {
+	index := &demoExportTxAccounts{spenders: make(map[string][]int), receivers: make(map[string][]int)}
+	add := func(accounts map[string][]int, txid string, seqno int) {
+		if !contains(accounts[txid], seqno) {
+			accounts[txid] = append(accounts[txid], seqno)
+			sort.Ints(accounts[txid])
+		}
+	}
+	for _, record := range store.SortedCoins() {
+		add(index.receivers, record.TxHash, record.AccountSeqNo)
+		if record.Status == DemoCoinSpent {
+			add(index.spenders, record.SpentTxid, record.AccountSeqNo)
+		}
+	}
+	return index
+}
+
+// feePayer returns the tracked account the fee of a tx is given on, the one with the lowest seqno spending
+// coins in the tx, or -1 if none does.
+func (index *demoExportTxAccounts) feePayer(txid string) int {
+	if spenders := index.spenders[txid]; len(spenders) > 0 {
+		return spenders[0]
+	}
+	return -1
+}
+
+// counterparty returns the other tracked accounts spending coins in the tx of a receive, or receiving coins from
+// the tx of a spend.
+func (index *demoExportTxAccounts) counterparty(entry *DemoLedgerEntry) string {
+	seqnos := index.receivers[entry.Txid]
+	if entry.Direction == DemoLedgerReceive {
+		seqnos = index.spenders[entry.Txid]
+	}
+	names := make([]string, 0, len(seqnos))
+	for _, seqno := range seqnos {
+		if seqno != entry.AccountSeqNo {
+			names = append(names, fmt.Sprintf("account %d", seqno))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatDemoAbel formats a value in Neutrino as ABEL, without the rounding of floats.
+func formatDemoAbel(value int64) string {
+	sign := ""
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	return fmt.Sprintf("%s%d.%07d", sign, value/1e7, value%1e7)
+}
+
+// ParseDemoExportTime parses a date or an RFC 3339 time. A date given as the end of a range
+// is taken as the end of the day, in UTC.
+func ParseDemoExportTime(value string, isEnd bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if isEnd {
+			t = t.Add(24 * time.Hour)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// WriteDemoExportCSV writes the rows as CSV with a header line, amounts in ABEL and dates in RFC 3339.
+func WriteDemoExportCSV(w io.Writer, rows []*DemoExportRow) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"date", "account", "direction", "amount", "fee", "counterparty", "memo", "txid", "height", "coin_id", "balance"})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		amount := row.Value
+		if row.Direction == DemoLedgerSpend {
+			amount = -amount
+		}
+		err := writer.Write([]string{
+			time.Unix(row.BlockTime, 0).UTC().Format(time.RFC3339),
+			strconv.Itoa(row.AccountSeqNo),
+			string(row.Direction),
+			formatDemoAbel(amount),
+			formatDemoAbel(row.Fee),
+			escapeDemoCSVCell(row.Counterparty),
+			escapeDemoCSVCell(row.Memo),
+			row.Txid,
+			strconv.FormatInt(row.Height, 10),
+			row.CoinID,
+			formatDemoAbel(row.Balance),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// escapeDemoCSVCell prefixes a cell which a spreadsheet would take as a formula with a quote, since memos are
+// on-chain text anyone can set.
+func escapeDemoCSVCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// WriteDemoExportOFX writes the rows as an OFX 2.2 bank statement per account.
+//
+// ABEL has no ISO 4217 code, so the statements are in the XXX currency and the amounts in ABEL. The
+// FITID of a transaction is its coin ID and direction, so that re-importing an overlapping export
+// does not duplicate transactions.
+func WriteDemoExportOFX(w io.Writer, rows []*DemoExportRow, chainID int8, now time.Time) error {
+	var b strings.Builder
+	ofxTime := func(unix int64) string { return time.Unix(unix, 0).UTC().Format("20060102150405") }
+	escape := func(text string) string {
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(text))
+		return escaped.String()
+	}
+
+	accounts := make([]int, 0)
+	for _, row := range rows {
+		if !contains(accounts, row.AccountSeqNo) {
+			accounts = append(accounts, row.AccountSeqNo)
+		}
+	}
+	sort.Ints(accounts)
+
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	b.WriteString("<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+	b.WriteString("<OFX>\n")
+	b.WriteString("<SIGNONMSGSRSV1><SONRS>\n")
+	b.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	fmt.Fprintf(&b, "<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE>\n", ofxTime(now.Unix()))
+	b.WriteString("</SONRS></SIGNONMSGSRSV1>\n")
+	b.WriteString("<BANKMSGSRSV1>\n")
+	for i, seqno := range accounts {
+		accountRows := make([]*DemoExportRow, 0)
+		for _, row := range rows {
+			if row.AccountSeqNo == seqno {
+				accountRows = append(accountRows, row)
+			}
+		}
+		first, last := accountRows[0], accountRows[len(accountRows)-1]
+
+		b.WriteString("<STMTTRNRS>\n")
+		fmt.Fprintf(&b, "<TRNUID>%d</TRNUID>\n", i+1)
+		b.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		b.WriteString("<STMTRS>\n<CURDEF>XXX</CURDEF>\n")
+		fmt.Fprintf(&b, "<BANKACCTFROM><BANKID>ABEL-%d</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", chainID, seqno)
+		fmt.Fprintf(&b, "<BANKTRANLIST>\n<DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxTime(first.BlockTime), ofxTime(last.BlockTime))
+		for _, row := range accountRows {
+			trnType, amount := "CREDIT", row.Value
+			if row.Direction == DemoLedgerSpend {
+				trnType, amount = "DEBIT", -row.Value
+			}
+			memo := fmt.Sprintf("tx %s", row.Txid)
+			if row.Fee > 0 {
+				memo += fmt.Sprintf(", fee %s", formatDemoAbel(row.Fee))
+			}
+			if row.Memo != "" {
+				memo += ", memo " + row.Memo
+			}
+			b.WriteString("<STMTTRN>\n")
+			fmt.Fprintf(&b, "<TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT>\n", trnType, ofxTime(row.BlockTime), formatDemoAbel(amount))
+			fmt.Fprintf(&b, "<FITID>%s</FITID>\n", escape(fmt.Sprintf("%s:%s", row.CoinID, row.Direction)))
+			if row.Counterparty != "" {
+				fmt.Fprintf(&b, "<NAME>%s</NAME>\n", escape(row.Counterparty))
+			}
+			fmt.Fprintf(&b, "<MEMO>%s</MEMO>\n", escape(memo))
+			b.WriteString("</STMTTRN>\n")
+		}
+		b.WriteString("</BANKTRANLIST>\n")
+		fmt.Fprintf(&b, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", formatDemoAbel(last.Balance), ofxTime(last.BlockTime))
+		b.WriteString("</STMTRS>\n</STMTTRNRS>\n")
+	}
+	b.WriteString("</BANKMSGSRSV1>\n</OFX>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,190 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+func TestFormatDemoAbel(t *testing.T) {
+	tests := []struct {
+		value int64
+		want  string
+	}{
+		{0, "0.0000000"},
+		{1, "0.0000001"},
+		{1e7, "1.0000000"},
+		{123456789, "12.3456789"},
+		{-1, "-0.0000001"},
+		{-25e6, "-2.5000000"},
+		// Floats lose the last digits of values this large.
+		{9007199254740993, "900719925.4740993"},
+	}
+	for _, test := range tests {
+		if got := formatDemoAbel(test.value); got != test.want {
+			t.Errorf("formatDemoAbel(%d) = %s, expecting %s", test.value, got, test.want)
+		}
+	}
+}
+
+// demoExportTestClient answers GetRawTx from a map of txs, it is the only call made by NewDemoExportRows.
+type demoExportTestClient struct {
+	DemoAbecRPCClient
+	txs map[string]*core.AbecTx
+}
+
+func (client *demoExportTestClient) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	tx, ok := client.txs[hash]
+	if !ok {
+		return nil, nil, errors.New("unknown tx " + hash)
+	}
+	return nil, tx, nil
+}
+
+// newDemoExportTestRows exports a chain where t1 pays accounts 0 and 2, and t2 spends both coins to pay account 1.
+func newDemoExportTestRows(t *testing.T, filter *DemoExportFilter) []*DemoExportRow {
+	store := &DemoCoinStore{ScannedHeight: 2, Coins: map[string]*DemoCoinRecord{
+		"t1:0": {ID: "t1:0", AccountSeqNo: 2, TxHash: "t1", Value: 5e7, BlockHeight: 1, Status: DemoCoinSpent, SpentTxid: "t2", SpentHeight: 2},
+		"t1:1": {ID: "t1:1", AccountSeqNo: 0, TxHash: "t1", TxOutIndex: 1, Value: 10e7, BlockHeight: 1, Status: DemoCoinSpent, SpentTxid: "t2", SpentHeight: 2},
+		"t2:0": {ID: "t2:0", AccountSeqNo: 1, TxHash: "t2", Value: 149e6, BlockHeight: 2, Status: DemoCoinUnspent},
+	}}
+	client := &demoExportTestClient{txs: map[string]*core.AbecTx{
+		"t1": {TxID: "t1", BlockTime: 1700000000, Fee: 0.1},
+		"t2": {TxID: "t2", BlockTime: 1700000256, Fee: 0.1, Memo: core.Bytes("rent <march> & fees").HexString()},
+	}}
+	rows, err := NewDemoExportRows(client, store, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestNewDemoExportRows(t *testing.T) {
+	rows := newDemoExportTestRows(t, &DemoExportFilter{})
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, expecting 5", len(rows))
+	}
+
+	fees := make(map[int]int64)
+	for _, row := range rows {
+		fees[row.AccountSeqNo] += row.Fee
+		if row.Direction == DemoLedgerReceive && row.Fee != 0 {
+			t.Errorf("receive of coin %s has a fee of %d", row.CoinID, row.Fee)
+		}
+	}
+	// Accounts 0 and 2 both spend in t2, its fee is given once, on the lowest seqno.
+	if fees[0] != 1e6 || fees[1] != 0 || fees[2] != 0 {
+		t.Errorf("got fees %v by account, expecting the fee of t2 on account 0 only", fees)
+	}
+
+	for _, row := range rows {
+		want := ""
+		switch {
+		case row.Txid == "t2" && row.Direction == DemoLedgerReceive:
+			want = "account 0, account 2"
+		case row.Txid == "t2" && row.Direction == DemoLedgerSpend:
+			want = "account 1"
+		}
+		if row.Counterparty != want {
+			t.Errorf("%s of coin %s by account %d has counterparty %q, expecting %q", row.Direction, row.CoinID, row.AccountSeqNo, row.Counterparty, want)
+		}
+		if row.Txid == "t2" && row.Memo != "rent <march> & fees" {
+			t.Errorf("got memo %q", row.Memo)
+		}
+	}
+
+	// The fee stays on account 0 when only account 2 is exported.
+	for _, row := range newDemoExportTestRows(t, &DemoExportFilter{Accounts: []int{2}}) {
+		if row.Fee != 0 {
+			t.Errorf("%s of coin %s by account 2 has a fee of %d", row.Direction, row.CoinID, row.Fee)
+		}
+	}
+}
+
+func TestWriteDemoExportCSV(t *testing.T) {
+	rows := newDemoExportTestRows(t, &DemoExportFilter{Accounts: []int{0, 1}})
+	var b strings.Builder
+	if err := WriteDemoExportCSV(&b, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := `date,account,direction,amount,fee,counterparty,memo,txid,height,coin_id,balance
+2023-11-14T22:13:20Z,0,receive,10.0000000,0.0000000,,,t1,1,t1:1,10.0000000
+2023-11-14T22:17:36Z,0,spend,-10.0000000,0.1000000,account 1,rent <march> & fees,t2,2,t1:1,0.0000000
+2023-11-14T22:17:36Z,1,receive,14.9000000,0.0000000,"account 0, account 2",rent <march> & fees,t2,2,t2:0,14.9000000
+`
+	if b.String() != want {
+		t.Errorf("got csv:\n%s\nexpecting:\n%s", b.String(), want)
+	}
+}
+
+func TestEscapeDemoCSVCell(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{"", ""},
+		{"rent", "rent"},
+		{"a=1", "a=1"},
+		{`=HYPERLINK("http://x")`, `'=HYPERLINK("http://x")`},
+		{"+1+1", "'+1+1"},
+		{"-1+1", "'-1+1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+	}
+	for _, test := range tests {
+		if got := escapeDemoCSVCell(test.cell); got != test.want {
+			t.Errorf("escapeDemoCSVCell(%q) = %q, expecting %q", test.cell, got, test.want)
+		}
+	}
+}
+
+func TestWriteDemoExportOFX(t *testing.T) {
+	rows := newDemoExportTestRows(t, &DemoExportFilter{})
+	var b strings.Builder
+	if err := WriteDemoExportOFX(&b, rows, 2, time.Unix(1700001000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	ofx := b.String()
+
+	// The file has to be well-formed XML, with the memo escaped.
+	decoder := xml.NewDecoder(strings.NewReader(ofx))
+	statements := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ofx is not well-formed: %v\n%s", err, ofx)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "STMTRS" {
+			statements += 1
+		}
+	}
+	if statements != 3 {
+		t.Errorf("got %d statements, expecting one per account", statements)
+	}
+
+	for _, want := range []string{
+		"<DTSERVER>20231114223000</DTSERVER>",
+		"<BANKACCTFROM><BANKID>ABEL-2</BANKID><ACCTID>0</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>",
+		"<DTSTART>20231114221320</DTSTART><DTEND>20231114221736</DTEND>",
+		"<TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20231114221736</DTPOSTED><TRNAMT>-10.0000000</TRNAMT>\n<FITID>t1:1:spend</FITID>\n<NAME>account 1</NAME>\n<MEMO>tx t2, fee 0.1000000, memo rent &lt;march&gt; &amp; fees</MEMO>",
+		"<TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20231114221736</DTPOSTED><TRNAMT>-5.0000000</TRNAMT>\n<FITID>t1:0:spend</FITID>\n<NAME>account 1</NAME>\n<MEMO>tx t2, memo rent &lt;march&gt; &amp; fees</MEMO>",
+		"<TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20231114221736</DTPOSTED><TRNAMT>14.9000000</TRNAMT>\n<FITID>t2:0:receive</FITID>\n<NAME>account 0, account 2</NAME>",
+		"<LEDGERBAL><BALAMT>14.9000000</BALAMT><DTASOF>20231114221736</DTASOF></LEDGERBAL>",
+	} {
+		if !strings.Contains(ofx, want) {
+			t.Errorf("ofx is missing %s:\n%s", want, ofx)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Define a lock on the files shared by the demo processes, e.g. the deposit book, which is written both by
+// SDKDepositAddress and by a running SDKScanDeposits.
+//
+// The lock is an exclusive flock on <path>.lock rather than on the file itself, so that it does not depend on
+// how the file is written. It is released when the process exits, so a crashed process never leaves it held.
+func lockDemoFile(path string) (func(), error) {
+	lockFile, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+	return func() {
+		syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		lockFile.Close()
+	}, nil
+}
+
+// writeDemoFileAtomic writes a file through a temporary file renamed over it, so that a process reading it, or
+// a crash while writing it, never sees it half written.
+func writeDemoFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(perm); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
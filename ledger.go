@@ -0,0 +1,106 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"sort"
+)
+
+// Define data types and methods for the ledgers of the accounts in the coin store.
+//
+// The ledger of an account lists the confirmed receives and spends of its coins in chain order,
+// receives first within a block, along with the running balance after each of them. Coins pending
+// on a tx not yet confirmed are still part of the balance.
+type DemoLedgerDirection string
+
+const (
+	DemoLedgerReceive DemoLedgerDirection = "receive"
+	DemoLedgerSpend   DemoLedgerDirection = "spend"
+)
+
+type DemoLedgerEntry struct {
+	AccountSeqNo int                 `json:"account"`
+	Height       int64               `json:"height"`
+	Txid         string              `json:"txid"`
+	CoinID       string              `json:"coinID"`
+	Direction    DemoLedgerDirection `json:"direction"`
+	Value        int64               `json:"value"`
+	// Balance is the balance of the account after the entry, in Neutrino.
+	Balance int64 `json:"balance"`
+}
+
+type DemoAccountReport struct {
+	*DemoCoinBalance
+	Ledger []*DemoLedgerEntry `json:"ledger"`
+}
+
+// AccountSeqNos returns the seqnos of the accounts with coins in the store, in ascending order.
+func (store *DemoCoinStore) AccountSeqNos() []int {
+	seqnos := make([]int, 0)
+	for _, record := range store.Coins {
+		if !contains(seqnos, record.AccountSeqNo) {
+			seqnos = append(seqnos, record.AccountSeqNo)
+		}
+	}
+	sort.Ints(seqnos)
+	return seqnos
+}
+
+// Ledger returns the ledger of an account.
+func (store *DemoCoinStore) Ledger(accountSeqNo int) []*DemoLedgerEntry {
+	entries := make([]*DemoLedgerEntry, 0)
+	for _, record := range store.AccountCoins(accountSeqNo, "") {
+		entries = append(entries, &DemoLedgerEntry{
+			AccountSeqNo: accountSeqNo,
+			Height:       record.BlockHeight,
+			Txid:         record.TxHash,
+			CoinID:       record.ID,
+			Direction:    DemoLedgerReceive,
+			Value:        record.Value,
+		})
+		if record.Status == DemoCoinSpent {
+			entries = append(entries, &DemoLedgerEntry{
+				AccountSeqNo: accountSeqNo,
+				Height:       record.SpentHeight,
+				Txid:         record.SpentTxid,
+				CoinID:       record.ID,
+				Direction:    DemoLedgerSpend,
+				Value:        record.Value,
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Height != entries[j].Height {
+			return entries[i].Height < entries[j].Height
+		}
+		if entries[i].Direction != entries[j].Direction {
+			return entries[i].Direction == DemoLedgerReceive
+		}
+		if entries[i].Txid != entries[j].Txid {
+			return entries[i].Txid < entries[j].Txid
+		}
+		return entries[i].CoinID < entries[j].CoinID
+	})
+
+	balance := int64(0)
+	for _, entry := range entries {
+		if entry.Direction == DemoLedgerReceive {
+			balance += entry.Value
+		} else {
+			balance -= entry.Value
+		}
+		entry.Balance = balance
+	}
+	return entries
+}
+
+// Reports returns the balances and the ledgers of the given accounts, in the given order.
+func (store *DemoCoinStore) Reports(accountSeqNos []int) []*DemoAccountReport {
+	reports := make([]*DemoAccountReport, len(accountSeqNos))
+	for i, balance := range store.Balances(accountSeqNos) {
+		reports[i] = &DemoAccountReport{DemoCoinBalance: balance, Ledger: store.Ledger(balance.AccountSeqNo)}
+	}
+	return reports
+}
@@ -9,15 +9,38 @@ import (
 )
 
 func main() {
-	demoNames := GetAllDemoNames()
-	if len(os.Args) < 2 || !contains(demoNames, os.Args[1]) {
-		fmt.Printf("Usage: %s <DEMO_NAME> [args...]\n", os.Args[0])
-		fmt.Printf("\nAvailable demo names: \n")
-		for _, name := range demoNames {
-			fmt.Printf("  %s\n", name)
-		}
-		os.Exit(1)
+	os.Exit(ds.demoExit(runDemoMain(os.Args[1:])))
+}
+
+// runDemoMain runs the command given by the args, returning the error it ends on.
+func runDemoMain(args []string) (err error) {
+	defer demoRecoverPanic(&err)
+
+	args, err = parseDemoGlobalFlags(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n\n", err)
+		printDemoUsage()
+		return &demoPrintedError{err: &DemoError{Kind: DemoErrorUsage, Err: err}}
+	}
+	if len(args) < 1 {
+		printDemoUsage()
+		return &demoPrintedError{err: newDemoError(DemoErrorUsage, "no command")}
 	}
 
-	RunDemo(os.Args[1], os.Args[2:])
+	switch name := args[0]; name {
+	case "help", "-h", "-help", "--help":
+		return DemoHelp(args[1:])
+	case "completion":
+		return DemoCompletion(args[1:])
+	case "config":
+		return DemoConfig(args[1:])
+	default:
+		ds.demoRedirectOutput()
+		if FindDemoCommand(name) == nil {
+			fmt.Printf("Unknown command: %s\n\n", name)
+			printDemoUsage()
+			return &demoPrintedError{err: newDemoError(DemoErrorUsage, "unknown command %s", name)}
+		}
+		return RunDemo(name, args[1:])
+	}
 }
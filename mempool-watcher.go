@@ -0,0 +1,101 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Define data types and methods for watching the mempool for txs of the tracked accounts.
+//
+// Every poll fetches the txs which entered the mempool since the last one, and reports their
+// outputs to the tracked accounts as pending receives, and their inputs whose serial numbers are
+// those of coins in the coin store as pending spends. A tx is inspected once while it stays in the
+// mempool, unless emitting its events fails; the events are then confirmed, or not, by the blocks scanned later.
+type DemoMempoolEvent struct {
+	Type DemoWebhookEventType `json:"type"`
+	// Txid is the tx in the mempool creating or spending the coin.
+	Txid   string          `json:"txid"`
+	Coin   *DemoCoinRecord `json:"coin"`
+	SeenAt int64           `json:"seenAt"`
+}
+
+type DemoMempoolWatcher struct {
+	client  DemoAbecRPCClient
+	scanner *DemoCoinScanner
+	// seen holds the txs already inspected which are still in the mempool.
+	seen map[string]bool
+}
+
+func NewDemoMempoolWatcher(client DemoAbecRPCClient, accounts []*DemoAccount) *DemoMempoolWatcher {
+	return &DemoMempoolWatcher{
+		client:  client,
+		scanner: NewDemoCoinScanner(client, accounts),
+		seen:    make(map[string]bool),
+	}
+}
+
+// Poll inspects the txs new to the mempool, ordered by txid, and passes their events to emit. A tx is marked
+// seen once all its events are emitted, so that a tx whose inspection or emission fails is inspected again by the
+// next poll. Txs which leave the mempool before they are inspected are skipped. Spends are matched against the
+// coins of the given coin store, which may be kept up to date by another process. It returns the emitted events,
+// along with the first error, which stops the poll.
+func (watcher *DemoMempoolWatcher) Poll(coinStore *DemoCoinStore, emit func(*DemoMempoolEvent) error) ([]*DemoMempoolEvent, error) {
+	_, mempool, err := watcher.client.GetMempool()
+	if err != nil {
+		return nil, err
+	}
+
+	txids := make([]string, 0, len(*mempool))
+	for txid := range *mempool {
+		txids = append(txids, txid)
+	}
+	sort.Strings(txids)
+
+	// Forget the txs which left the mempool, mined or dropped.
+	for txid := range watcher.seen {
+		if _, ok := (*mempool)[txid]; !ok {
+			delete(watcher.seen, txid)
+		}
+	}
+
+	events := make([]*DemoMempoolEvent, 0)
+	now := time.Now().Unix()
+	for _, txid := range txids {
+		if watcher.seen[txid] {
+			continue
+		}
+		coins, spends, err := watcher.scanner.ScanTx(txid)
+		if IsDemoRPCNotFound(err) {
+			// The tx left the mempool since it was listed.
+			continue
+		}
+		if err != nil {
+			return events, err
+		}
+		txEvents := make([]*DemoMempoolEvent, 0)
+		for _, scannedCoin := range coins {
+			record := NewDemoCoinRecord(scannedCoin.Coin, scannedCoin.Account.SerialNo)
+			txEvents = append(txEvents, &DemoMempoolEvent{Type: DemoWebhookCoinPendingReceive, Txid: txid, Coin: record, SeenAt: now})
+		}
+		for _, spend := range spends {
+			record := coinStore.FindBySerialNumber(spend.SerialNumber)
+			if record == nil || record.Status == DemoCoinSpent {
+				continue
+			}
+			copied := *record
+			txEvents = append(txEvents, &DemoMempoolEvent{Type: DemoWebhookCoinPendingSpend, Txid: txid, Coin: &copied, SeenAt: now})
+		}
+		for _, event := range txEvents {
+			if err := emit(event); err != nil {
+				return events, err
+			}
+			events = append(events, event)
+		}
+		watcher.seen[txid] = true
+	}
+	return events, nil
+}
@@ -0,0 +1,136 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	core "abelian.info/sdk/core"
+)
+
+// demoChurnMempoolClient lists a ghost tx in the mempool, which fails with the given error when fetched, as a tx
+// which leaves the mempool between getrawmempool and getrawtransaction.
+type demoChurnMempoolClient struct {
+	DemoAbecRPCClient
+	ghostTxid string
+	ghostErr  error
+}
+
+func (client *demoChurnMempoolClient) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	data, mempool, err := client.DemoAbecRPCClient.GetMempool()
+	if err == nil && client.ghostTxid != "" {
+		(*mempool)[client.ghostTxid] = (*mempool)[""]
+	}
+	return data, mempool, err
+}
+
+func (client *demoChurnMempoolClient) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	if hash == client.ghostTxid && client.ghostErr != nil {
+		return nil, nil, client.ghostErr
+	}
+	return client.DemoAbecRPCClient.GetRawTx(hash)
+}
+
+// newDemoMempoolWatcherTest submits a tx from account 0 to account 1, and returns a watcher of the fixture
+// accounts polling the mempool through the churn client.
+func newDemoMempoolWatcherTest(t *testing.T) (*DemoMempoolWatcher, *demoChurnMempoolClient, *DemoWallet, string) {
+	var churnClient *demoChurnMempoolClient
+	_, _, wallet := newDemoWalletTestNode(t, func(client DemoAbecRPCClient) DemoAbecRPCClient {
+		churnClient = &demoChurnMempoolClient{DemoAbecRPCClient: client}
+		return churnClient
+	})
+	syncDemoWalletTest(t, wallet)
+	signedTx := buildDemoWalletTestTx(t, wallet, core.AbelToNeutrino(1))
+	if _, err := wallet.SubmitTx(signedTx.SignedRawTx); err != nil {
+		t.Fatal(err)
+	}
+	return NewDemoMempoolWatcher(churnClient, demoGoldenFixtureSpec(t).Accounts), churnClient, wallet, signedTx.Txid
+}
+
+func emitDemoMempoolTestEvent(*DemoMempoolEvent) error {
+	return nil
+}
+
+// checkDemoMempoolTestEvents checks that the events are those of the submitted tx: a receive by account 1, the
+// change back to account 0 and the spends of the coins of account 0.
+func checkDemoMempoolTestEvents(t *testing.T, events []*DemoMempoolEvent, txid string) {
+	receivers := make([]int, 0)
+	spends := 0
+	for _, event := range events {
+		if event.Txid != txid {
+			t.Errorf("got event %v of tx %v, expecting tx %v", event.Type, event.Txid, txid)
+		}
+		switch event.Type {
+		case DemoWebhookCoinPendingReceive:
+			receivers = append(receivers, event.Coin.AccountSeqNo)
+		case DemoWebhookCoinPendingSpend:
+			spends += 1
+		}
+	}
+	if !contains(receivers, 0) || !contains(receivers, 1) || spends == 0 {
+		t.Errorf("got receives by %v and %d spends, expecting receives by accounts 0 and 1 and a spend", receivers, spends)
+	}
+}
+
+// TestDemoMempoolWatcherChurn polls a mempool listing a tx which is gone once it is fetched.
+func TestDemoMempoolWatcherChurn(t *testing.T) {
+	watcher, client, wallet, txid := newDemoMempoolWatcherTest(t)
+	client.ghostTxid = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	events, err := watcher.Poll(wallet.coinStore, emitDemoMempoolTestEvent)
+	if err != nil {
+		t.Fatalf("got error %v, expecting the ghost tx to be skipped", err)
+	}
+	checkDemoMempoolTestEvents(t, events, txid)
+	if watcher.seen[client.ghostTxid] {
+		t.Errorf("ghost tx is marked seen")
+	}
+
+	// The txs are inspected once while they stay in the mempool.
+	if events, err := watcher.Poll(wallet.coinStore, emitDemoMempoolTestEvent); err != nil || len(events) != 0 {
+		t.Errorf("got %d events and error %v polling again, expecting none", len(events), err)
+	}
+}
+
+// TestDemoMempoolWatcherError polls a mempool whose last tx fails to be fetched, and checks that the events of the
+// txs inspected before are returned, and not emitted again.
+func TestDemoMempoolWatcherError(t *testing.T) {
+	watcher, client, wallet, txid := newDemoMempoolWatcherTest(t)
+	client.ghostTxid = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	client.ghostErr = &DemoRPCError{Kind: DemoRPCErrorTimeout, Method: "getrawtransaction", Err: errors.New("timeout")}
+
+	events, err := watcher.Poll(wallet.coinStore, emitDemoMempoolTestEvent)
+	if !IsDemoRPCTransient(err) {
+		t.Fatalf("got error %v, expecting a timeout", err)
+	}
+	checkDemoMempoolTestEvents(t, events, txid)
+
+	client.ghostErr = nil
+	client.ghostTxid = ""
+	if events, err := watcher.Poll(wallet.coinStore, emitDemoMempoolTestEvent); err != nil || len(events) != 0 {
+		t.Errorf("got %d events and error %v polling again, expecting none", len(events), err)
+	}
+}
+
+// TestDemoMempoolWatcherEmitError checks that a tx whose events fail to be emitted is inspected again.
+func TestDemoMempoolWatcherEmitError(t *testing.T) {
+	watcher, _, wallet, txid := newDemoMempoolWatcherTest(t)
+
+	emitErr := errors.New("queue is full")
+	events, err := watcher.Poll(wallet.coinStore, func(*DemoMempoolEvent) error { return emitErr })
+	if !errors.Is(err, emitErr) || len(events) != 0 {
+		t.Fatalf("got %d events and error %v, expecting the error of emit", len(events), err)
+	}
+	if watcher.seen[txid] {
+		t.Errorf("tx is marked seen although its events were not emitted")
+	}
+
+	events, err = watcher.Poll(wallet.coinStore, emitDemoMempoolTestEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDemoMempoolTestEvents(t, events, txid)
+}
@@ -0,0 +1,391 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	core "abelian.info/sdk/core"
+	"github.com/abesuite/abec/abecrypto/abecryptoparam"
+	"github.com/abesuite/abec/wire"
+)
+
+// DecodeDemoAbecTx decodes a serialized tx into the verbose form returned by getrawtransaction.
+func DecodeDemoAbecTx(txBytes []byte) (*core.AbecTx, error) {
+	msgTx := &wire.MsgTxAbe{}
+	err := msgTx.DeserializeFull(bytes.NewReader(txBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &core.AbecTx{
+		Hex:      hex.EncodeToString(txBytes),
+		TxID:     msgTx.TxHash().String(),
+		TxHash:   msgTx.TxHashFull().String(),
+		Version:  int64(msgTx.Version),
+		Size:     int64(msgTx.SerializeSize()),
+		FullSize: int64(len(txBytes)),
+		Memo:     hex.EncodeToString(msgTx.TxMemo),
+		Fee:      core.NeutrinoToAbel(int64(msgTx.TxFee)),
+		Witness:  hex.EncodeToString(msgTx.TxWitness),
+	}
+	for _, txIn := range msgTx.TxIns {
+		tx.Vin = append(tx.Vin, &core.AbecTxVin{SerialNumber: hex.EncodeToString(txIn.SerialNumber)})
+	}
+	for i, txOut := range msgTx.TxOuts {
+		buffer := bytes.NewBuffer(make([]byte, 0, txOut.SerializeSize()))
+		err = wire.WriteTxOutAbe(buffer, 0, msgTx.Version, txOut)
+		if err != nil {
+			return nil, err
+		}
+		tx.Vout = append(tx.Vout, &core.AbecTxVout{N: int64(i), Script: hex.EncodeToString(buffer.Bytes())})
+	}
+
+	return tx, nil
+}
+
+// Define data types and methods for the mock abec JSON-RPC server.
+type DemoMockAbecServer struct {
+	URL      string
+	requests int
+	fixture  *DemoChainFixture
+	username string
+	password string
+	mempool  map[string]*DemoFixtureTx
+	poolTime map[string]int64
+	mutex    sync.Mutex
+	server   *http.Server
+}
+
+type demoMockRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *demoMockRPCError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func NewDemoMockAbecServer(fixture *DemoChainFixture, username string, password string) *DemoMockAbecServer {
+	return &DemoMockAbecServer{
+		fixture:  fixture,
+		username: username,
+		password: password,
+		mempool:  make(map[string]*DemoFixtureTx),
+		poolTime: make(map[string]int64),
+	}
+}
+
+// Start listens on the given address, e.g. 127.0.0.1:0 for a random port, and serves in the background.
+func (s *DemoMockAbecServer) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.URL = "http://" + listener.Addr().String()
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(listener)
+	return nil
+}
+
+func (s *DemoMockAbecServer) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// Requests returns the number of authenticated requests served so far.
+func (s *DemoMockAbecServer) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+func (s *DemoMockAbecServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(s.username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="abec RPC"`)
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "405 Method Not Allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &core.AbecJSONRPCRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests += 1
+	result, err := s.handle(req.Method, req.Params)
+	s.mutex.Unlock()
+
+	resp := map[string]interface{}{"result": result, "error": nil, "id": req.ID}
+	if err != nil {
+		rpcErr, ok := err.(*demoMockRPCError)
+		if !ok {
+			rpcErr = &demoMockRPCError{Code: -32603, Message: err.Error()}
+		}
+		resp["result"] = nil
+		resp["error"] = rpcErr
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *DemoMockAbecServer) handle(method string, params []interface{}) (interface{}, error) {
+	stringParam := func(i int) (string, error) {
+		if i >= len(params) {
+			return "", &demoMockRPCError{Code: -1, Message: fmt.Sprintf("missing parameter %d", i)}
+		}
+		value, ok := params[i].(string)
+		if !ok {
+			return "", &demoMockRPCError{Code: -1, Message: fmt.Sprintf("parameter %d is not a string", i)}
+		}
+		return value, nil
+	}
+	intParam := func(i int, defaultValue int64) (int64, error) {
+		if i >= len(params) {
+			return defaultValue, nil
+		}
+		switch value := params[i].(type) {
+		case float64:
+			return int64(value), nil
+		case bool:
+			if value {
+				return 1, nil
+			}
+			return 0, nil
+		default:
+			return 0, &demoMockRPCError{Code: -1, Message: fmt.Sprintf("parameter %d is not a number", i)}
+		}
+	}
+
+	tip := s.fixture.Tip()
+	switch method {
+	case "getinfo":
+		chainInfo := s.fixture.ChainInfo
+		chainInfo.NumBlocks = tip
+		return chainInfo, nil
+
+	case "getblockhash":
+		height, err := intParam(0, -1)
+		if err != nil {
+			return nil, err
+		}
+		if height < 0 || height > tip {
+			return nil, &demoMockRPCError{Code: -1, Message: "Block number out of range"}
+		}
+		return s.fixture.Blocks[height].Block.BlockHash, nil
+
+	case "getblockabe", "getblock":
+		hash, err := stringParam(0)
+		if err != nil {
+			return nil, err
+		}
+		verbosity, err := intParam(1, 1)
+		if err != nil {
+			return nil, err
+		}
+		fixtureBlock := s.fixture.FindBlock(hash)
+		if fixtureBlock == nil {
+			return nil, &demoMockRPCError{Code: -5, Message: "Block not found"}
+		}
+		if verbosity == 0 {
+			if fixtureBlock.Hex == "" {
+				return nil, &demoMockRPCError{Code: -32603, Message: "Block data not available in the chain fixture"}
+			}
+			return fixtureBlock.Hex, nil
+		}
+		block := *fixtureBlock.Block
+		block.Confirmations = tip - block.Height + 1
+		if block.Height < tip {
+			block.NextBlockHash = s.fixture.Blocks[block.Height+1].Block.BlockHash
+		}
+		return block, nil
+
+	case "getrawtransaction":
+		hash, err := stringParam(0)
+		if err != nil {
+			return nil, err
+		}
+		verbose, err := intParam(1, 0)
+		if err != nil {
+			return nil, err
+		}
+		fixtureTx, inPool := s.mempool[hash]
+		if !inPool {
+			fixtureTx = s.fixture.Txs[hash]
+		}
+		if fixtureTx == nil {
+			return nil, &demoMockRPCError{Code: -5, Message: "No information available about transaction"}
+		}
+		if verbose == 0 {
+			return fixtureTx.Hex, nil
+		}
+		tx := *fixtureTx.Tx
+		if block := s.fixture.FindBlock(tx.BlockHash); block != nil {
+			tx.Confirmations = tip - block.Block.Height + 1
+		}
+		return tx, nil
+
+	case "getrawmempool":
+		verbose, err := intParam(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		txids := make([]string, 0, len(s.mempool))
+		for txid := range s.mempool {
+			txids = append(txids, txid)
+		}
+		sort.Strings(txids)
+		if verbose == 0 {
+			return txids, nil
+		}
+		mempool := make(map[string]interface{})
+		for _, txid := range txids {
+			tx := s.mempool[txid].Tx
+			mempool[txid] = map[string]interface{}{
+				"size":     tx.Size,
+				"fullsize": tx.FullSize,
+				"fee":      tx.Fee,
+				"time":     s.poolTime[txid],
+				"height":   tip,
+			}
+		}
+		return mempool, nil
+
+	case "sendrawtransactionabe", "sendrawtransaction":
+		hexString, err := stringParam(0)
+		if err != nil {
+			return nil, err
+		}
+		txBytes, err := hex.DecodeString(hexString)
+		if err != nil {
+			return nil, &demoMockRPCError{Code: -22, Message: "TX decode failed"}
+		}
+		tx, err := DecodeDemoAbecTx(txBytes)
+		if err != nil {
+			return nil, &demoMockRPCError{Code: -22, Message: fmt.Sprintf("TX decode failed: %v", err)}
+		}
+		if _, ok := s.fixture.Txs[tx.TxID]; ok {
+			return nil, &demoMockRPCError{Code: -27, Message: "transaction already exists"}
+		}
+		if _, ok := s.mempool[tx.TxID]; !ok {
+			// The input of a coinbase tx carries the null serial number, which spends no coin.
+			nullSerialNumber, _ := abecryptoparam.GetNullSerialNumber(uint32(tx.Version))
+			for _, vin := range tx.Vin {
+				if vin.SerialNumber == hex.EncodeToString(nullSerialNumber) {
+					continue
+				}
+				if spender := s.findSpender(vin.SerialNumber); spender != "" {
+					return nil, &demoMockRPCError{Code: -26, Message: fmt.Sprintf("TX rejected: serial number %s is already spent by tx %s", vin.SerialNumber, spender)}
+				}
+			}
+		}
+		s.mempool[tx.TxID] = &DemoFixtureTx{Tx: tx, Hex: hexString}
+		s.poolTime[tx.TxID] = time.Now().Unix()
+		return tx.TxID, nil
+
+	case "generate":
+		count, err := intParam(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		if count < 1 || count > 1000 {
+			return nil, &demoMockRPCError{Code: -8, Message: "Please request between 1 and 1000 blocks to generate"}
+		}
+		return s.generate(int(count))
+
+	default:
+		return nil, &demoMockRPCError{Code: -32601, Message: "Method not found"}
+	}
+}
+
+// generate mines the given number of blocks, the first of which includes all txs in the mempool.
+func (s *DemoMockAbecServer) generate(count int) ([]string, error) {
+	txids := make([]string, 0, len(s.mempool))
+	for txid := range s.mempool {
+		txids = append(txids, txid)
+	}
+	sort.Strings(txids)
+	txs := make([]*DemoFixtureTx, 0, len(txids))
+	for _, txid := range txids {
+		txs = append(txs, s.mempool[txid])
+	}
+	s.mempool = make(map[string]*DemoFixtureTx)
+	s.poolTime = make(map[string]int64)
+
+	blockTime := time.Now().Unix()
+	if tip := s.fixture.Tip(); tip >= 0 && s.fixture.Blocks[tip].Block.Time >= blockTime {
+		blockTime = s.fixture.Blocks[tip].Block.Time + 1
+	}
+	hashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		fixtureBlock, err := s.fixture.AppendBlock(txs, blockTime+int64(i))
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, fixtureBlock.Block.BlockHash)
+		txs = nil
+	}
+	return hashes, nil
+}
+
+// findSpender returns the txid of the tx in a block or in the mempool spending the given serial number, if any.
+func (s *DemoMockAbecServer) findSpender(serialNumber string) string {
+	for _, fixtureBlock := range s.fixture.Blocks {
+		for _, txid := range fixtureBlock.Block.TxHashes {
+			fixtureTx := s.fixture.Txs[txid]
+			if fixtureTx == nil {
+				continue
+			}
+			for _, vin := range fixtureTx.Tx.Vin {
+				if vin.SerialNumber == serialNumber {
+					return txid
+				}
+			}
+		}
+	}
+	for txid, fixtureTx := range s.mempool {
+		for _, vin := range fixtureTx.Tx.Vin {
+			if vin.SerialNumber == serialNumber {
+				return txid
+			}
+		}
+	}
+	return ""
+}
+
+// Generate mines blocks in-process, see generate.
+func (s *DemoMockAbecServer) Generate(count int) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.generate(count)
+}
+
+// EvictFromMempool drops a tx from the mempool, as a node does when the tx expires.
+func (s *DemoMockAbecServer) EvictFromMempool(txid string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.mempool, txid)
+	delete(s.poolTime, txid)
+}
@@ -0,0 +1,311 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	core "abelian.info/sdk/core"
+	"github.com/abesuite/abec/wire"
+)
+
+// Define data types and methods for a read-through on-disk cache of confirmed blocks and txs.
+//
+// Blocks and txs are stored by hash, and block hashes by height, once they are at least
+// finalityDepth blocks deep. Cached entries are assumed never to be reorganized away, but they are
+// checked against their hash when read, and a corrupted entry is dropped and fetched again.
+type DemoRPCCache struct {
+	client        DemoAbecRPCClient
+	dir           string
+	finalityDepth int64
+	tipTTL        time.Duration
+	tip           int64
+	tipTime       time.Time
+	mutex         sync.Mutex
+	Hits          int
+	Misses        int
+}
+
+func NewDemoRPCCache(client DemoAbecRPCClient, dir string, finalityDepth int64) *DemoRPCCache {
+	return &DemoRPCCache{client: client, dir: dir, finalityDepth: finalityDepth, tipTTL: 10 * time.Second}
+}
+
+func (cache *DemoRPCCache) path(kind string, key string) string {
+	return filepath.Join(cache.dir, kind, key[:2], key)
+}
+
+// read loads an entry and checks it with verify, which returns an error if the entry does not match its key.
+func (cache *DemoRPCCache) read(kind string, key string, verify func(data core.Bytes) error) (core.Bytes, bool) {
+	if len(key) < 2 {
+		return nil, false
+	}
+	path := cache.path(kind, key)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err = verify(core.AsBytes(data)); err != nil {
+			demoLog.Printf("dropping corrupted cache entry %s/%s: %v", kind, key, err)
+			if err := os.Remove(path); err != nil {
+				demoLog.Printf("failed to remove cache entry %s/%s: %v", kind, key, err)
+			}
+		}
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if err != nil {
+		cache.Misses += 1
+		return nil, false
+	}
+	cache.Hits += 1
+	return core.AsBytes(data), true
+}
+
+func verifyDemoCachedBlockHash(hash string) func(data core.Bytes) error {
+	return func(data core.Bytes) error {
+		block := &core.AbecBlock{}
+		if err := data.JSONUnmarshal(block); err != nil {
+			return err
+		}
+		if block.BlockHash != hash {
+			return fmt.Errorf("got block %s", block.BlockHash)
+		}
+		return nil
+	}
+}
+
+func verifyDemoCachedBlockBytes(hash string) func(data core.Bytes) error {
+	return func(data core.Bytes) error {
+		header := &wire.BlockHeader{}
+		if err := header.Deserialize(bytes.NewReader(data)); err != nil {
+			return err
+		}
+		if blockHash := header.BlockHash().String(); blockHash != hash {
+			return fmt.Errorf("got block %s", blockHash)
+		}
+		return nil
+	}
+}
+
+func verifyDemoCachedTx(hash string) func(data core.Bytes) error {
+	return func(data core.Bytes) error {
+		tx := &core.AbecTx{}
+		if err := data.JSONUnmarshal(tx); err != nil {
+			return err
+		}
+		decodedTx, err := DecodeDemoAbecTx(core.MakeBytesFromHexString(tx.Hex))
+		if err != nil {
+			return err
+		}
+		if decodedTx.TxID != hash {
+			return fmt.Errorf("got the content of tx %s", decodedTx.TxID)
+		}
+		if tx.TxID != hash {
+			return fmt.Errorf("got tx %s", tx.TxID)
+		}
+		return nil
+	}
+}
+
+// refreshDemoCachedConfirmations returns a cached block or tx response with its confirmations replaced, keeping
+// the other fields of the node as they are.
+func refreshDemoCachedConfirmations(data core.Bytes, confirmations int64) (core.Bytes, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := data.JSONUnmarshal(&fields); err != nil {
+		return nil, err
+	}
+	fields["confirmations"] = json.RawMessage(fmt.Sprintf("%d", confirmations))
+	respBytes, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return core.AsBytes(respBytes), nil
+}
+
+// write stores an entry atomically. Failures are logged but not returned since the cache is best effort.
+func (cache *DemoRPCCache) write(kind string, key string, data core.Bytes) {
+	if len(key) < 2 {
+		return
+	}
+	path := cache.path(kind, key)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		tmpPath := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+		err = os.WriteFile(tmpPath, data, 0644)
+		if err == nil {
+			err = os.Rename(tmpPath, path)
+		}
+	}
+	if err != nil {
+		demoLog.Printf("failed to write cache entry %s/%s: %v", kind, key, err)
+	}
+}
+
+func (cache *DemoRPCCache) currentTip() (int64, error) {
+	cache.mutex.Lock()
+	if time.Since(cache.tipTime) < cache.tipTTL {
+		defer cache.mutex.Unlock()
+		return cache.tip, nil
+	}
+	cache.mutex.Unlock()
+
+	_, _, err := cache.GetChainInfo()
+	if err != nil {
+		return 0, err
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.tip, nil
+}
+
+func (cache *DemoRPCCache) isFinal(height int64) bool {
+	tip, err := cache.currentTip()
+	return err == nil && tip-height+1 >= cache.finalityDepth
+}
+
+func (cache *DemoRPCCache) GetChainInfo() (core.Bytes, *core.AbecChainInfo, error) {
+	respBytes, chainInfo, err := cache.client.GetChainInfo()
+	if err == nil {
+		cache.mutex.Lock()
+		cache.tip = chainInfo.NumBlocks
+		cache.tipTime = time.Now()
+		cache.mutex.Unlock()
+	}
+	return respBytes, chainInfo, err
+}
+
+func (cache *DemoRPCCache) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	return cache.client.GetMempool()
+}
+
+func (cache *DemoRPCCache) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	key := fmt.Sprintf("%02d", height)
+	blockHash := new(string)
+	// A height has no hash of its own, the block it points to is checked if cached.
+	verify := func(data core.Bytes) error {
+		if err := data.JSONUnmarshal(blockHash); err != nil {
+			return err
+		}
+		if blockBytes, err := os.ReadFile(cache.path("blocks", *blockHash)); err == nil {
+			block := &core.AbecBlock{}
+			if core.AsBytes(blockBytes).JSONUnmarshal(block) == nil && block.Height != height {
+				return fmt.Errorf("got block %s at height %d", *blockHash, block.Height)
+			}
+		}
+		return nil
+	}
+	if respBytes, ok := cache.read("heights", key, verify); ok {
+		return respBytes, blockHash, nil
+	}
+
+	respBytes, blockHash, err := cache.client.GetBlockHash(height)
+	if err == nil && cache.isFinal(height) {
+		cache.write("heights", key, respBytes)
+	}
+	return respBytes, blockHash, err
+}
+
+func (cache *DemoRPCCache) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	if respBytes, ok := cache.read("blocks", hash, verifyDemoCachedBlockHash(hash)); ok {
+		block := &core.AbecBlock{}
+		if respBytes.JSONUnmarshal(block) == nil {
+			// Confirmations in the cached response are stale, refresh them from the current tip.
+			if tip, err := cache.currentTip(); err == nil {
+				block.Confirmations = tip - block.Height + 1
+				respBytes, err = refreshDemoCachedConfirmations(respBytes, block.Confirmations)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			return respBytes, block, nil
+		}
+	}
+
+	respBytes, block, err := cache.client.GetBlock(hash)
+	if err == nil && block.Confirmations >= cache.finalityDepth {
+		cache.write("blocks", hash, respBytes)
+	}
+	return respBytes, block, err
+}
+
+func (cache *DemoRPCCache) GetBlockBytes(hash string) (core.Bytes, error) {
+	if blockBytes, ok := cache.read("blockbytes", hash, verifyDemoCachedBlockBytes(hash)); ok {
+		return blockBytes, nil
+	}
+
+	blockBytes, err := cache.client.GetBlockBytes(hash)
+	if err != nil {
+		return nil, err
+	}
+	if height, err := cache.blockBytesHeight(hash, blockBytes); err == nil && cache.isFinal(height) {
+		cache.write("blockbytes", hash, blockBytes)
+	}
+	return blockBytes, nil
+}
+
+// blockBytesHeight returns the height of a block from its header, which only carries it since the EthashPoW
+// version. The height of an older block is fetched along with the block.
+func (cache *DemoRPCCache) blockBytesHeight(hash string, blockBytes core.Bytes) (int64, error) {
+	header := &wire.BlockHeader{}
+	if err := header.Deserialize(bytes.NewReader(blockBytes)); err != nil {
+		return 0, err
+	}
+	if header.Version == int32(wire.BlockVersionEthashPow) {
+		return int64(header.Height), nil
+	}
+	_, block, err := cache.GetBlock(hash)
+	if err != nil {
+		return 0, err
+	}
+	return block.Height, nil
+}
+
+func (cache *DemoRPCCache) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	if respBytes, ok := cache.read("txs", hash, verifyDemoCachedTx(hash)); ok {
+		tx := &core.AbecTx{}
+		if respBytes.JSONUnmarshal(tx) == nil {
+			// Confirmations in the cached response are stale, refresh them from the height of its block.
+			_, block, err := cache.GetBlock(tx.BlockHash)
+			if err == nil {
+				tx.Confirmations = block.Confirmations
+				respBytes, err = refreshDemoCachedConfirmations(respBytes, tx.Confirmations)
+				if err != nil {
+					return nil, nil, err
+				}
+				return respBytes, tx, nil
+			}
+		}
+	}
+
+	respBytes, tx, err := cache.client.GetRawTx(hash)
+	if err == nil && tx.BlockHash != "" && tx.Confirmations >= cache.finalityDepth {
+		cache.write("txs", hash, respBytes)
+	}
+	return respBytes, tx, err
+}
+
+func (cache *DemoRPCCache) GetBlockBytesByHeight(height int64) (core.Bytes, error) {
+	_, hash, err := cache.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	return cache.GetBlockBytes(*hash)
+}
+
+func (cache *DemoRPCCache) GetEstimatedTxFee() int64 {
+	return cache.client.GetEstimatedTxFee()
+}
+
+func (cache *DemoRPCCache) SendRawTx(txStr string) (core.Bytes, *string, error) {
+	return cache.client.SendRawTx(txStr)
+}
+
+func (ds *DemoSet) getDemoRPCCacheDir() string {
+	return ds.getDemoFilePath(fmt.Sprintf(".cache/chain-%d", ds.getDemoChainID()))
+}
@@ -0,0 +1,140 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// newDemoRPCCacheTestNode starts a mock node serving the golden fixture, and creates a cache in front of it
+// which refreshes the tip on every call.
+func newDemoRPCCacheTestNode(t *testing.T) (*DemoChainFixture, *DemoMockAbecServer, *DemoRPCCache) {
+	fixture, err := LoadDemoChainFixture(demoGoldenFixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewDemoMockAbecServer(fixture, demoGoldenUsername, demoGoldenPassword)
+	if err := server.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	client := NewDemoAbecHTTPClient(server.URL, demoGoldenUsername, demoGoldenPassword, 10*time.Second)
+	cache := NewDemoRPCCache(client, t.TempDir(), 2)
+	cache.tipTTL = 0
+	return fixture, server, cache
+}
+
+func TestDemoRPCCacheTxConfirmations(t *testing.T) {
+	fixture, server, cache := newDemoRPCCacheTestNode(t)
+	txid := fixture.Blocks[1].Block.TxHashes[0]
+	if _, _, err := cache.GetRawTx(txid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Generate(2); err != nil {
+		t.Fatal(err)
+	}
+
+	respBytes, tx, err := cache.GetRawTx(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Hits == 0 {
+		t.Fatalf("tx %s was not read from the cache", txid)
+	}
+	if want := fixture.Tip(); tx.Confirmations != want {
+		t.Errorf("got %d confirmations for the cached tx of block 1, expecting %d", tx.Confirmations, want)
+	}
+	respTx := &core.AbecTx{}
+	if err := respBytes.JSONUnmarshal(respTx); err != nil || respTx.Confirmations != tx.Confirmations || respTx.Hex != tx.Hex {
+		t.Errorf("got the response %+v, %v, expecting it to match the tx with %d confirmations", respTx, err, tx.Confirmations)
+	}
+}
+
+// TestDemoRPCCacheBlockBytesFinality caches the bytes of a final block, telling its height from its header.
+func TestDemoRPCCacheBlockBytesFinality(t *testing.T) {
+	fixture, server, cache := newDemoRPCCacheTestNode(t)
+	for _, height := range []int64{1, fixture.Tip()} {
+		blockHash := fixture.Blocks[height].Block.BlockHash
+		requests := server.Requests()
+		if _, err := cache.GetBlockBytes(blockHash); err != nil {
+			t.Fatal(err)
+		}
+		// The bytes of the block, and the tip to tell whether the block is final.
+		if got := server.Requests() - requests; got != 2 {
+			t.Errorf("got %d requests for the bytes of block %d, expecting 2", got, height)
+		}
+		hits := cache.Hits
+		if _, err := cache.GetBlockBytes(blockHash); err != nil {
+			t.Fatal(err)
+		}
+		if final := cache.Hits > hits; final != (height == 1) {
+			t.Errorf("got the bytes of block %d cached %v, expecting them cached only if final", height, final)
+		}
+	}
+}
+
+func TestDemoRPCCacheCorruptedEntries(t *testing.T) {
+	fixture, _, cache := newDemoRPCCacheTestNode(t)
+	txid := fixture.Blocks[1].Block.TxHashes[0]
+	otherTxid := fixture.Blocks[2].Block.TxHashes[0]
+	blockHash := fixture.Blocks[1].Block.BlockHash
+	otherBlockHash := fixture.Blocks[2].Block.BlockHash
+	for _, hash := range []string{txid, otherTxid} {
+		if _, _, err := cache.GetRawTx(hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, hash := range []string{blockHash, otherBlockHash} {
+		if _, err := cache.GetBlockBytes(hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Swap the contents of the entries of the two txs and of the two blocks.
+	corrupt := func(kind string, key string, otherKey string) {
+		data, err := os.ReadFile(cache.path(kind, otherKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(cache.path(kind, key), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corrupt("txs", txid, otherTxid)
+	corrupt("blockbytes", blockHash, otherBlockHash)
+
+	misses := cache.Misses
+	_, tx, err := cache.GetRawTx(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.TxID != txid || cache.Misses != misses+1 {
+		t.Errorf("got tx %s and %d misses, expecting tx %s fetched from the node", tx.TxID, cache.Misses-misses, txid)
+	}
+	blockBytes, err := cache.GetBlockBytes(blockHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockBytes.HexString() != fixture.Blocks[1].Hex {
+		t.Errorf("got the bytes of another block for block %s", blockHash)
+	}
+
+	// The corrupted entries are rewritten.
+	hits := cache.Hits
+	if _, _, err := cache.GetRawTx(txid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetBlockBytes(blockHash); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Hits <= hits+1 {
+		t.Errorf("got %d hits reading the rewritten entries, expecting them read from the cache", cache.Hits-hits)
+	}
+}
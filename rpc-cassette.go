@@ -0,0 +1,299 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for recording RPC traffic to a cassette and replaying it.
+//
+// A cassette is a JSON lines file with one interaction per call, holding the method, the params
+// and either the raw result bytes or the error returned by the wrapped client.
+type DemoRPCInteraction struct {
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	// ErrorKind and ErrorCode keep the classification of the error, so that it is replayed as the same DemoRPCError.
+	ErrorKind string    `json:"errorKind,omitempty"`
+	ErrorCode int       `json:"errorCode,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+func (interaction *DemoRPCInteraction) key() string {
+	return interaction.Method + string(interaction.Params)
+}
+
+func newDemoRPCInteraction(method string, params ...interface{}) *DemoRPCInteraction {
+	if params == nil {
+		params = []interface{}{}
+	}
+	// Marshaling plain values does not fail.
+	paramsBytes, _ := json.Marshal(params)
+	return &DemoRPCInteraction{Method: method, Params: paramsBytes}
+}
+
+func LoadDemoRPCCassette(path string) ([]*DemoRPCInteraction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var interactions []*DemoRPCInteraction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		interaction := &DemoRPCInteraction{}
+		err = json.Unmarshal(scanner.Bytes(), interaction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of cassette %s: %v", line, path, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, scanner.Err()
+}
+
+// DemoRPCRecorder passes all calls to the wrapped client and appends them to the cassette file.
+type DemoRPCRecorder struct {
+	client DemoAbecRPCClient
+	file   *os.File
+	mutex  sync.Mutex
+}
+
+func NewDemoRPCRecorder(client DemoAbecRPCClient, path string) (*DemoRPCRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &DemoRPCRecorder{client: client, file: file}, nil
+}
+
+// Close flushes the cassette to disk and closes it, calls recorded after it are logged as failed.
+func (recorder *DemoRPCRecorder) Close() error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	err := recorder.file.Sync()
+	if closeErr := recorder.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// record writes the interaction right away so that the cassette is complete even if the demo exits on error.
+func (recorder *DemoRPCRecorder) record(interaction *DemoRPCInteraction, response interface{}, err error) {
+	interaction.Time = time.Now()
+	var rpcErr *DemoRPCError
+	if errors.As(err, &rpcErr) {
+		interaction.Error = rpcErr.Err.Error()
+		interaction.ErrorKind = rpcErr.Kind.String()
+		interaction.ErrorCode = rpcErr.Code
+	} else if err != nil {
+		interaction.Error = err.Error()
+	} else if data, ok := response.(core.Bytes); ok {
+		interaction.Response = json.RawMessage(data)
+	} else {
+		interaction.Response, _ = json.Marshal(response)
+	}
+
+	line, err := json.Marshal(interaction)
+	if err == nil {
+		recorder.mutex.Lock()
+		_, err = recorder.file.Write(append(line, '\n'))
+		recorder.mutex.Unlock()
+	}
+	if err != nil {
+		demoLog.Printf("failed to record %s to cassette: %v", interaction.Method, err)
+	}
+}
+
+func (recorder *DemoRPCRecorder) GetChainInfo() (core.Bytes, *core.AbecChainInfo, error) {
+	respBytes, chainInfo, err := recorder.client.GetChainInfo()
+	recorder.record(newDemoRPCInteraction("getinfo"), respBytes, err)
+	return respBytes, chainInfo, err
+}
+
+func (recorder *DemoRPCRecorder) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	respBytes, mempool, err := recorder.client.GetMempool()
+	recorder.record(newDemoRPCInteraction("getrawmempool", true), respBytes, err)
+	return respBytes, mempool, err
+}
+
+func (recorder *DemoRPCRecorder) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	respBytes, blockHash, err := recorder.client.GetBlockHash(height)
+	recorder.record(newDemoRPCInteraction("getblockhash", height), respBytes, err)
+	return respBytes, blockHash, err
+}
+
+func (recorder *DemoRPCRecorder) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	respBytes, block, err := recorder.client.GetBlock(hash)
+	recorder.record(newDemoRPCInteraction("getblockabe", hash, 1), respBytes, err)
+	return respBytes, block, err
+}
+
+func (recorder *DemoRPCRecorder) GetBlockBytes(hash string) (core.Bytes, error) {
+	blockBytes, err := recorder.client.GetBlockBytes(hash)
+	recorder.record(newDemoRPCInteraction("getblockabe", hash, 0), blockBytes.HexString(), err)
+	return blockBytes, err
+}
+
+func (recorder *DemoRPCRecorder) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	respBytes, tx, err := recorder.client.GetRawTx(hash)
+	recorder.record(newDemoRPCInteraction("getrawtransaction", hash, true), respBytes, err)
+	return respBytes, tx, err
+}
+
+func (recorder *DemoRPCRecorder) GetBlockBytesByHeight(height int64) (core.Bytes, error) {
+	_, hash, err := recorder.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	return recorder.GetBlockBytes(*hash)
+}
+
+func (recorder *DemoRPCRecorder) GetEstimatedTxFee() int64 {
+	txFee := recorder.client.GetEstimatedTxFee()
+	recorder.record(newDemoRPCInteraction("estimatetxfee"), txFee, nil)
+	return txFee
+}
+
+func (recorder *DemoRPCRecorder) SendRawTx(txStr string) (core.Bytes, *string, error) {
+	respBytes, txid, err := recorder.client.SendRawTx(txStr)
+	recorder.record(newDemoRPCInteraction("sendrawtransactionabe", txStr), respBytes, err)
+	return respBytes, txid, err
+}
+
+// DemoRPCReplayer serves the interactions of a cassette without any node.
+//
+// Identical calls are answered in the order they were recorded, and the last answer is repeated
+// once they are exhausted, so that polling loops see the chain as it was at the end of the recording.
+type DemoRPCReplayer struct {
+	interactions map[string][]*DemoRPCInteraction
+	served       map[string]int
+	mutex        sync.Mutex
+}
+
+func NewDemoRPCReplayer(interactions []*DemoRPCInteraction) *DemoRPCReplayer {
+	replayer := &DemoRPCReplayer{
+		interactions: make(map[string][]*DemoRPCInteraction),
+		served:       make(map[string]int),
+	}
+	for _, interaction := range interactions {
+		key := interaction.key()
+		replayer.interactions[key] = append(replayer.interactions[key], interaction)
+	}
+	return replayer
+}
+
+func (replayer *DemoRPCReplayer) next(request *DemoRPCInteraction) (*DemoRPCInteraction, error) {
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+
+	key := request.key()
+	recorded := replayer.interactions[key]
+	if len(recorded) == 0 {
+		return nil, &DemoRPCError{Kind: DemoRPCErrorServer, Method: request.Method, Err: fmt.Errorf("no recorded response for params %s", request.Params)}
+	}
+	i := replayer.served[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	replayer.served[key] = i + 1
+	return recorded[i], nil
+}
+
+func demoRPCReplay[T any](replayer *DemoRPCReplayer, result *T, method string, params ...interface{}) (core.Bytes, *T, error) {
+	interaction, err := replayer.next(newDemoRPCInteraction(method, params...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if interaction.Error != "" {
+		// Errors recorded without a kind are replayed as server errors.
+		kind := DemoRPCErrorServer
+		for candidate := DemoRPCErrorServer; candidate <= DemoRPCErrorBadResponse; candidate++ {
+			if candidate.String() == interaction.ErrorKind {
+				kind = candidate
+			}
+		}
+		return nil, nil, &DemoRPCError{Kind: kind, Method: method, Code: interaction.ErrorCode, Err: errors.New(interaction.Error)}
+	}
+
+	respBytes := core.AsBytes(interaction.Response)
+	err = respBytes.JSONUnmarshal(result)
+	if err != nil {
+		return respBytes, nil, err
+	}
+	return respBytes, result, nil
+}
+
+func (replayer *DemoRPCReplayer) GetChainInfo() (core.Bytes, *core.AbecChainInfo, error) {
+	return demoRPCReplay(replayer, &core.AbecChainInfo{}, "getinfo")
+}
+
+func (replayer *DemoRPCReplayer) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	return demoRPCReplay(replayer, &core.AbecMempool{}, "getrawmempool", true)
+}
+
+func (replayer *DemoRPCReplayer) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	return demoRPCReplay(replayer, new(string), "getblockhash", height)
+}
+
+func (replayer *DemoRPCReplayer) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	return demoRPCReplay(replayer, &core.AbecBlock{}, "getblockabe", hash, 1)
+}
+
+func (replayer *DemoRPCReplayer) GetBlockBytes(hash string) (core.Bytes, error) {
+	_, blockHex, err := demoRPCReplay(replayer, new(string), "getblockabe", hash, 0)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDemoHex(*blockHex)
+}
+
+func (replayer *DemoRPCReplayer) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	return demoRPCReplay(replayer, &core.AbecTx{}, "getrawtransaction", hash, true)
+}
+
+func (replayer *DemoRPCReplayer) GetBlockBytesByHeight(height int64) (core.Bytes, error) {
+	_, hash, err := replayer.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	return replayer.GetBlockBytes(*hash)
+}
+
+// GetEstimatedTxFee falls back to the default fee of core.AbecRPCClient if the cassette has none.
+func (replayer *DemoRPCReplayer) GetEstimatedTxFee() int64 {
+	_, txFee, err := demoRPCReplay(replayer, new(int64), "estimatetxfee")
+	if err != nil {
+		return core.AbelToNeutrino(0.1)
+	}
+	return *txFee
+}
+
+func (replayer *DemoRPCReplayer) SendRawTx(txStr string) (core.Bytes, *string, error) {
+	return demoRPCReplay(replayer, new(string), "sendrawtransactionabe", txStr)
+}
+
+// getDemoCassettePath resolves a cassette path relative to the demo env directory unless it is absolute.
+func (ds *DemoSet) getDemoCassettePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return ds.getDemoFilePath(path)
+}
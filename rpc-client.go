@@ -0,0 +1,104 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"fmt"
+)
+
+// demoRPCRecorders are the recorders opened by getDemoAbecRPCClient, closed when the demo ends.
+var demoRPCRecorders []*DemoRPCRecorder
+
+func closeDemoRPCRecorders() {
+	for _, recorder := range demoRPCRecorders {
+		if err := recorder.Close(); err != nil {
+			demoLog.Printf("failed to close cassette: %v", err)
+		}
+	}
+	demoRPCRecorders = nil
+}
+
+func (ds *DemoSet) getDemoAbecRPCClient() (DemoAbecRPCClient, error) {
+	// Serve all calls from a recorded cassette without any node if configured.
+	if cassettePath := ds.getDemoConfigStringValue("abec.rpc.replay"); cassettePath != "" {
+		interactions, err := LoadDemoRPCCassette(ds.getDemoCassettePath(cassettePath))
+		if err != nil {
+			return nil, err
+		}
+		return NewDemoRPCReplayer(interactions), nil
+	}
+
+	client, err := ds.getDemoAbecRPCEndpointClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve confirmed blocks and txs from the local cache unless disabled.
+	cache, err := ds.getDemoConfigBoolValue("abec.rpc.cache")
+	if err != nil {
+		return nil, err
+	}
+	if cache {
+		finalityDepth, err := ds.getDemoConfigIntValue("abec.rpc.cacheFinalityDepth")
+		if err != nil {
+			return nil, err
+		}
+		client = NewDemoRPCCache(client, ds.getDemoRPCCacheDir(), finalityDepth)
+	}
+
+	// Record the calls as seen by the demos, including those served by the cache, so that they can be replayed as is.
+	if cassettePath := ds.getDemoConfigStringValue("abec.rpc.record"); cassettePath != "" {
+		recorder, err := NewDemoRPCRecorder(client, ds.getDemoCassettePath(cassettePath))
+		if err != nil {
+			return nil, err
+		}
+		demoRPCRecorders = append(demoRPCRecorders, recorder)
+		client = recorder
+	}
+
+	return client, nil
+}
+
+// getDemoAbecRPCEndpointClient returns a client talking to the configured endpoints without caching.
+func (ds *DemoSet) getDemoAbecRPCEndpointClient() (DemoAbecRPCClient, error) {
+	endpoint := ds.getDemoConfigStringValue("abec.rpc.endpoint")
+	username := ds.getDemoConfigStringValue("abec.rpc.username")
+	password := ds.getDemoConfigStringValue("abec.rpc.password")
+
+	// Additional endpoints are configured as abec.rpc.endpoint.N, with optional
+	// abec.rpc.username.N and abec.rpc.password.N defaulting to the primary credentials.
+	policy, err := ds.getDemoRPCPolicy()
+	if err != nil {
+		return nil, err
+	}
+	clients := map[string]DemoAbecRPCClient{
+		endpoint: NewDemoRPCPolicyClient(NewDemoAbecHTTPClient(endpoint, username, password, policy.Timeout), policy),
+	}
+	for i := 1; ; i++ {
+		extraEndpoint := ds.getDemoConfigStringValue(fmt.Sprintf("abec.rpc.endpoint.%d", i))
+		if extraEndpoint == "" {
+			break
+		}
+		extraUsername := ds.getDemoConfigStringValue(fmt.Sprintf("abec.rpc.username.%d", i))
+		extraPassword := ds.getDemoConfigStringValue(fmt.Sprintf("abec.rpc.password.%d", i))
+		if extraUsername == "" && extraPassword == "" {
+			extraUsername, extraPassword = username, password
+		}
+		clients[extraEndpoint] = NewDemoRPCPolicyClient(NewDemoAbecHTTPClient(extraEndpoint, extraUsername, extraPassword, policy.Timeout), policy)
+	}
+	if len(clients) == 1 {
+		return clients[endpoint], nil
+	}
+
+	maxLag, err := ds.getDemoConfigIntValue("abec.rpc.maxLag")
+	if err != nil {
+		return nil, err
+	}
+	checkInterval, err := ds.getDemoConfigDurationValue("abec.rpc.healthCheckInterval")
+	if err != nil {
+		return nil, err
+	}
+	return NewDemoRPCPool(clients, maxLag, checkInterval), nil
+}
@@ -0,0 +1,222 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define a JSON-RPC client for abec with a timeout on every call and typed errors.
+//
+// It sends the same requests as core.AbecRPCClient, whose http client has no timeout and whose errors are
+// plain strings, through its own http client with a timeout, so that a call which times out is cancelled
+// along with its connection. Errors are classified by the http status and the JSON-RPC error code of the
+// response.
+type DemoAbecHTTPClient struct {
+	httpClient *http.Client
+	endpoint   string
+	username   string
+	password   string
+}
+
+type demoAbecRPCErrorObject struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes of abec, see abejson/jsonrpcerr.go.
+const (
+	demoAbecRPCErrMisc              = -1
+	demoAbecRPCErrInvalidAddressKey = -5
+	demoAbecRPCErrDeserialization   = -22
+	demoAbecRPCErrVerify            = -25
+	demoAbecRPCErrTxRejected        = -26
+	demoAbecRPCErrTxAlreadyExists   = -27
+)
+
+// demoAbecHTTPStatusError is returned for a response which is neither successful nor a JSON-RPC error
+// answered by the node, e.g. an html page of a proxy.
+type demoAbecHTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *demoAbecHTTPStatusError) Error() string {
+	return "http status " + e.Status
+}
+
+func NewDemoAbecHTTPClient(endpoint string, username string, password string, timeout time.Duration) *DemoAbecHTTPClient {
+	return &DemoAbecHTTPClient{
+		httpClient: &http.Client{Timeout: timeout},
+		endpoint:   endpoint,
+		username:   username,
+		password:   password,
+	}
+}
+
+// callForBytes sends a JSON-RPC request and returns the raw result, as core.AbecRPCClient does.
+func (client *DemoAbecHTTPClient) callForBytes(method string, params []interface{}) (core.Bytes, error) {
+	reqBody, err := json.Marshal(&core.AbecJSONRPCRequest{
+		JSONRPC: "1.0",
+		Method:  method,
+		Params:  params,
+		ID:      fmt.Sprintf("%d", time.Now().UnixMilli()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, client.endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, classifyDemoAbecError(method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(client.username, client.password)
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, classifyDemoAbecError(method, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, classifyDemoAbecError(method, err)
+	}
+
+	// The node answers its errors with a JSON-RPC error object, along with a 500 status for some of them.
+	respObj := &core.AbecJSONRPCResponse{}
+	if err := json.Unmarshal(body, respObj); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, classifyDemoAbecError(method, &demoAbecHTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+		}
+		return nil, classifyDemoAbecError(method, err)
+	}
+	if len(respObj.Error) > 0 && string(respObj.Error) != "null" {
+		errObj := &demoAbecRPCErrorObject{}
+		if err := json.Unmarshal(respObj.Error, errObj); err != nil {
+			return nil, &DemoRPCError{Kind: DemoRPCErrorBadResponse, Method: method, Err: fmt.Errorf("malformed error %s", respObj.Error)}
+		}
+		return nil, &DemoRPCError{
+			Kind:   demoAbecRPCErrorKind(method, errObj.Code),
+			Method: method,
+			Code:   errObj.Code,
+			Err:    fmt.Errorf("%d: %s", errObj.Code, errObj.Message),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, classifyDemoAbecError(method, &demoAbecHTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+	return core.AsBytes(respObj.Result), nil
+}
+
+// callDemoAbecRPC sends a JSON-RPC request and decodes its result.
+func callDemoAbecRPC[ResultType any](client *DemoAbecHTTPClient, result *ResultType, method string, params []interface{}) (core.Bytes, *ResultType, error) {
+	resultBytes, err := client.callForBytes(method, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := resultBytes.JSONUnmarshal(result); err != nil {
+		return resultBytes, nil, classifyDemoAbecError(method, err)
+	}
+	return resultBytes, result, nil
+}
+
+// demoAbecRPCErrorKind tells the kind of an error answered by the node from its code.
+func demoAbecRPCErrorKind(method string, code int) DemoRPCErrorKind {
+	switch {
+	case code == demoAbecRPCErrInvalidAddressKey:
+		// Block not found and no information about a tx share the code.
+		return DemoRPCErrorNotFound
+	case code == demoAbecRPCErrMisc && method == "getblockhash":
+		// Heights above the tip are answered as out of range.
+		return DemoRPCErrorNotFound
+	default:
+		return DemoRPCErrorServer
+	}
+}
+
+// classifyDemoAbecError wraps an error of the transport or of the decoding of a response into a DemoRPCError.
+func classifyDemoAbecError(method string, err error) error {
+	var statusErr *demoAbecHTTPStatusError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &statusErr):
+		kind := DemoRPCErrorServer
+		if statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError {
+			// Proxies in front of public nodes answer with html pages on overload.
+			kind = DemoRPCErrorBadResponse
+		}
+		return &DemoRPCError{Kind: kind, Method: method, Err: statusErr}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &DemoRPCError{Kind: DemoRPCErrorTimeout, Method: method, Err: err}
+	case errors.As(err, &netErr):
+		return &DemoRPCError{Kind: DemoRPCErrorNetwork, Method: method, Err: err}
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return &DemoRPCError{Kind: DemoRPCErrorBadResponse, Method: method, Err: err}
+	default:
+		return &DemoRPCError{Kind: DemoRPCErrorNetwork, Method: method, Err: err}
+	}
+}
+
+func (client *DemoAbecHTTPClient) GetChainInfo() (core.Bytes, *core.AbecChainInfo, error) {
+	return callDemoAbecRPC(client, &core.AbecChainInfo{}, "getinfo", nil)
+}
+
+func (client *DemoAbecHTTPClient) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	return callDemoAbecRPC(client, &core.AbecMempool{}, "getrawmempool", []interface{}{true})
+}
+
+func (client *DemoAbecHTTPClient) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	return callDemoAbecRPC(client, new(string), "getblockhash", []interface{}{height})
+}
+
+func (client *DemoAbecHTTPClient) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	return callDemoAbecRPC(client, &core.AbecBlock{}, "getblockabe", []interface{}{hash, 1})
+}
+
+// GetBlockBytes decodes the block hex with DecodeDemoHex, since core.MakeBytesFromHexString panics on a
+// malformed one.
+func (client *DemoAbecHTTPClient) GetBlockBytes(hash string) (core.Bytes, error) {
+	_, blockHex, err := callDemoAbecRPC(client, new(string), "getblockabe", []interface{}{hash, 0})
+	if err != nil {
+		return nil, err
+	}
+	blockBytes, err := DecodeDemoHex(*blockHex)
+	if err != nil {
+		return nil, &DemoRPCError{Kind: DemoRPCErrorBadResponse, Method: "getblockabe", Err: err}
+	}
+	return blockBytes, nil
+}
+
+func (client *DemoAbecHTTPClient) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	return callDemoAbecRPC(client, &core.AbecTx{}, "getrawtransaction", []interface{}{hash, true})
+}
+
+func (client *DemoAbecHTTPClient) GetBlockBytesByHeight(height int64) (core.Bytes, error) {
+	_, hash, err := client.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetBlockBytes(*hash)
+}
+
+// GetEstimatedTxFee returns the fixed fee of core.AbecRPCClient, since abec has no fee estimation.
+func (client *DemoAbecHTTPClient) GetEstimatedTxFee() int64 {
+	return core.AbelToNeutrino(0.1)
+}
+
+func (client *DemoAbecHTTPClient) SendRawTx(txStr string) (core.Bytes, *string, error) {
+	return callDemoAbecRPC(client, new(string), "sendrawtransactionabe", []interface{}{txStr})
+}
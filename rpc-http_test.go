@@ -0,0 +1,81 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// TestDemoAbecHTTPClientErrors checks the classification of the answers of a node, or of a proxy in front of it,
+// and that a call is cancelled once the timeout expires.
+func TestDemoAbecHTTPClientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		delay  time.Duration
+		kind   DemoRPCErrorKind
+		code   int
+	}{
+		{"not found", http.StatusOK, `{"result":null,"error":{"code":-5,"message":"Block not found"},"id":"1"}`, 0, DemoRPCErrorNotFound, -5},
+		{"not found with status", http.StatusInternalServerError, `{"result":null,"error":{"code":-5,"message":"Block not found"},"id":"1"}`, 0, DemoRPCErrorNotFound, -5},
+		{"tx rejected", http.StatusOK, `{"result":null,"error":{"code":-26,"message":"rejected"},"id":"1"}`, 0, DemoRPCErrorServer, -26},
+		{"malformed error", http.StatusOK, `{"result":null,"error":"oops","id":"1"}`, 0, DemoRPCErrorBadResponse, 0},
+		{"proxy overloaded", http.StatusServiceUnavailable, `<html>503 Service Unavailable</html>`, 0, DemoRPCErrorBadResponse, 0},
+		{"rate limited", http.StatusTooManyRequests, `<html>429 Too Many Requests</html>`, 0, DemoRPCErrorBadResponse, 0},
+		{"unauthorized", http.StatusUnauthorized, `401 Unauthorized.`, 0, DemoRPCErrorServer, 0},
+		{"html with status ok", http.StatusOK, `<html></html>`, 0, DemoRPCErrorBadResponse, 0},
+		{"result of another type", http.StatusOK, `{"result":42,"error":null,"id":"1"}`, 0, DemoRPCErrorBadResponse, 0},
+		{"timeout", http.StatusOK, `{"result":"b0","error":null,"id":"1"}`, time.Second, DemoRPCErrorTimeout, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(test.delay)
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			client := NewDemoAbecHTTPClient(server.URL, "user", "pass", 200*time.Millisecond)
+			_, _, err := client.GetBlockHash(0)
+			rpcErr, ok := err.(*DemoRPCError)
+			if !ok {
+				t.Fatalf("got error %v, expecting a DemoRPCError", err)
+			}
+			if rpcErr.Kind != test.kind || rpcErr.Code != test.code || rpcErr.Method != "getblockhash" {
+				t.Errorf("got %v error %v with code %d, expecting %v with code %d", rpcErr.Kind, err, rpcErr.Code, test.kind, test.code)
+			}
+		})
+	}
+}
+
+// TestDemoAbecHTTPClientResult checks that results go through, and that the fee is that of core.AbecRPCClient.
+func TestDemoAbecHTTPClientResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"result":"00ff","error":null,"id":"1"}`))
+	}))
+	defer server.Close()
+
+	client := NewDemoAbecHTTPClient(server.URL, "user", "pass", time.Second)
+	if _, blockHash, err := client.GetBlockHash(0); err != nil || *blockHash != "00ff" {
+		t.Errorf("got block hash %v, %v, expecting 00ff", blockHash, err)
+	}
+	if blockBytes, err := client.GetBlockBytes("00ff"); err != nil || blockBytes.HexString() != "00ff" {
+		t.Errorf("got block bytes %v, %v, expecting 00ff", blockBytes, err)
+	}
+	if fee, want := client.GetEstimatedTxFee(), core.NewAbecRPCClient(server.URL, "user", "pass").GetEstimatedTxFee(); fee != want {
+		t.Errorf("got fee %d, expecting %d", fee, want)
+	}
+}
@@ -0,0 +1,304 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define typed errors for abec RPC calls.
+type DemoRPCErrorKind int
+
+const (
+	DemoRPCErrorServer DemoRPCErrorKind = iota
+	DemoRPCErrorNotFound
+	DemoRPCErrorNetwork
+	DemoRPCErrorTimeout
+	DemoRPCErrorBadResponse
+)
+
+func (kind DemoRPCErrorKind) String() string {
+	switch kind {
+	case DemoRPCErrorServer:
+		return "server error"
+	case DemoRPCErrorNotFound:
+		return "not found"
+	case DemoRPCErrorNetwork:
+		return "network error"
+	case DemoRPCErrorTimeout:
+		return "timeout"
+	case DemoRPCErrorBadResponse:
+		return "bad response"
+	default:
+		return "unknown error"
+	}
+}
+
+type DemoRPCError struct {
+	Kind   DemoRPCErrorKind
+	Method string
+	// Code is the JSON-RPC error code answered by the node, 0 if the node did not answer.
+	Code int
+	Err  error
+}
+
+func (e *DemoRPCError) Error() string {
+	return fmt.Sprintf("abec rpc %s: %s: %v", e.Method, e.Kind, e.Err)
+}
+
+func (e *DemoRPCError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DemoRPCError) DemoErrorKind() DemoErrorKind {
+	return DemoErrorRPC
+}
+
+// DemoErrorCode returns the code of the error in the json output, e.g. rpc_not_found.
+func (e *DemoRPCError) DemoErrorCode() string {
+	return "rpc_" + strings.ReplaceAll(e.Kind.String(), " ", "_")
+}
+
+// IsTransient tells whether the call may succeed if retried.
+func (e *DemoRPCError) IsTransient() bool {
+	return e.Kind == DemoRPCErrorNetwork || e.Kind == DemoRPCErrorTimeout || e.Kind == DemoRPCErrorBadResponse
+}
+
+func IsDemoRPCTransient(err error) bool {
+	var rpcErr *DemoRPCError
+	return errors.As(err, &rpcErr) && rpcErr.IsTransient()
+}
+
+func IsDemoRPCNotFound(err error) bool {
+	var rpcErr *DemoRPCError
+	return errors.As(err, &rpcErr) && rpcErr.Kind == DemoRPCErrorNotFound
+}
+
+// IsDemoRPCTxRejected tells whether the node answered that a submitted tx is invalid, so that it will never be
+// included in a block as it is. Errors without an answer of the node, e.g. timeouts, leave the tx in doubt.
+func IsDemoRPCTxRejected(err error) bool {
+	var rpcErr *DemoRPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Kind != DemoRPCErrorServer {
+		return false
+	}
+	switch rpcErr.Code {
+	case demoAbecRPCErrDeserialization, demoAbecRPCErrVerify, demoAbecRPCErrTxRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDemoRPCTxAlreadyExists tells whether the node answered that a submitted tx is already known to it.
+func IsDemoRPCTxAlreadyExists(err error) bool {
+	var rpcErr *DemoRPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == demoAbecRPCErrTxAlreadyExists
+}
+
+// classifyDemoRPCError wraps an error returned by a client which does not return DemoRPCError into one, by its type.
+func classifyDemoRPCError(method string, err error) *DemoRPCError {
+	var rpcErr *DemoRPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	kind := DemoRPCErrorServer
+	var netErr net.Error
+	var urlErr *url.Error
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		kind = DemoRPCErrorTimeout
+	case errors.As(err, &netErr), errors.As(err, &urlErr):
+		kind = DemoRPCErrorNetwork
+	case errors.As(err, &syntaxErr):
+		// Proxies in front of public nodes answer with HTML pages on overload.
+		kind = DemoRPCErrorBadResponse
+	}
+	return &DemoRPCError{Kind: kind, Method: method, Err: err}
+}
+
+// Define a rate limiter shared by all RPC calls of the process.
+type DemoRateLimiter struct {
+	interval time.Duration
+	next     time.Time
+	mutex    sync.Mutex
+}
+
+func NewDemoRateLimiter(requestsPerSecond float64) *DemoRateLimiter {
+	if requestsPerSecond <= 0 {
+		return &DemoRateLimiter{}
+	}
+	return &DemoRateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// Wait blocks until the next request is allowed.
+func (limiter *DemoRateLimiter) Wait() {
+	if limiter.interval == 0 {
+		return
+	}
+	limiter.mutex.Lock()
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	wait := limiter.next.Sub(now)
+	limiter.next = limiter.next.Add(limiter.interval)
+	limiter.mutex.Unlock()
+	time.Sleep(wait)
+}
+
+// Define data types and methods for the retry, timeout and rate-limit policy.
+type DemoRPCPolicy struct {
+	Timeout        time.Duration
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	Limiter        *DemoRateLimiter
+}
+
+// backoff returns the delay before the given retry, using exponential backoff with full jitter.
+func (policy *DemoRPCPolicy) backoff(retry int) time.Duration {
+	delay := policy.RetryBaseDelay << uint(retry)
+	if delay <= 0 || delay > policy.RetryMaxDelay {
+		delay = policy.RetryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+type DemoRPCPolicyClient struct {
+	client DemoAbecRPCClient
+	policy *DemoRPCPolicy
+}
+
+func NewDemoRPCPolicyClient(client DemoAbecRPCClient, policy *DemoRPCPolicy) *DemoRPCPolicyClient {
+	return &DemoRPCPolicyClient{client: client, policy: policy}
+}
+
+// demoRPCPolicyCall retries the call on transient errors. The timeout is not enforced here but by the
+// http client of each endpoint, see NewDemoAbecHTTPClient, so that timed out calls do not keep running.
+func demoRPCPolicyCall[T any](c *DemoRPCPolicyClient, method string, call func(DemoAbecRPCClient) (core.Bytes, *T, error)) (core.Bytes, *T, error) {
+	policy := c.policy
+	for retry := 0; ; retry++ {
+		if policy.Limiter != nil {
+			policy.Limiter.Wait()
+		}
+
+		respBytes, result, err := call(c.client)
+		if err == nil {
+			return respBytes, result, nil
+		}
+
+		rpcErr := classifyDemoRPCError(method, err)
+		if !rpcErr.IsTransient() || retry >= policy.MaxRetries {
+			return nil, nil, rpcErr
+		}
+		delay := policy.backoff(retry)
+		demoLog.Printf("abec rpc %s failed (%v), retry %d/%d in %v", method, rpcErr.Kind, retry+1, policy.MaxRetries, delay)
+		time.Sleep(delay)
+	}
+}
+
+func (c *DemoRPCPolicyClient) GetChainInfo() (core.Bytes, *core.AbecChainInfo, error) {
+	return demoRPCPolicyCall(c, "getinfo", func(client DemoAbecRPCClient) (core.Bytes, *core.AbecChainInfo, error) {
+		return client.GetChainInfo()
+	})
+}
+
+func (c *DemoRPCPolicyClient) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	return demoRPCPolicyCall(c, "getrawmempool", func(client DemoAbecRPCClient) (core.Bytes, *core.AbecMempool, error) {
+		return client.GetMempool()
+	})
+}
+
+func (c *DemoRPCPolicyClient) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	return demoRPCPolicyCall(c, "getblockhash", func(client DemoAbecRPCClient) (core.Bytes, *string, error) {
+		return client.GetBlockHash(height)
+	})
+}
+
+func (c *DemoRPCPolicyClient) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	return demoRPCPolicyCall(c, "getblockabe", func(client DemoAbecRPCClient) (core.Bytes, *core.AbecBlock, error) {
+		return client.GetBlock(hash)
+	})
+}
+
+func (c *DemoRPCPolicyClient) GetBlockBytes(hash string) (core.Bytes, error) {
+	blockBytes, _, err := demoRPCPolicyCall(c, "getblockabe", func(client DemoAbecRPCClient) (core.Bytes, *struct{}, error) {
+		blockBytes, err := client.GetBlockBytes(hash)
+		return blockBytes, nil, err
+	})
+	return blockBytes, err
+}
+
+func (c *DemoRPCPolicyClient) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	return demoRPCPolicyCall(c, "getrawtransaction", func(client DemoAbecRPCClient) (core.Bytes, *core.AbecTx, error) {
+		return client.GetRawTx(hash)
+	})
+}
+
+func (c *DemoRPCPolicyClient) GetBlockBytesByHeight(height int64) (core.Bytes, error) {
+	_, hash, err := c.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBlockBytes(*hash)
+}
+
+func (c *DemoRPCPolicyClient) GetEstimatedTxFee() int64 {
+	return c.client.GetEstimatedTxFee()
+}
+
+// SendRawTx is not retried on timeout since the node may have accepted the tx.
+func (c *DemoRPCPolicyClient) SendRawTx(txStr string) (core.Bytes, *string, error) {
+	noRetry := *c.policy
+	noRetry.MaxRetries = 0
+	return demoRPCPolicyCall(&DemoRPCPolicyClient{client: c.client, policy: &noRetry}, "sendrawtransactionabe", func(client DemoAbecRPCClient) (core.Bytes, *string, error) {
+		return client.SendRawTx(txStr)
+	})
+}
+
+var demoRateLimiter *DemoRateLimiter
+
+func (ds *DemoSet) getDemoRPCPolicy() (*DemoRPCPolicy, error) {
+	policy := &DemoRPCPolicy{}
+	var err error
+	if policy.Timeout, err = ds.getDemoConfigDurationValue("abec.rpc.timeout"); err != nil {
+		return nil, err
+	}
+	if policy.RetryBaseDelay, err = ds.getDemoConfigDurationValue("abec.rpc.retryBaseDelay"); err != nil {
+		return nil, err
+	}
+	if policy.RetryMaxDelay, err = ds.getDemoConfigDurationValue("abec.rpc.retryMaxDelay"); err != nil {
+		return nil, err
+	}
+	maxRetries, err := ds.getDemoConfigIntValue("abec.rpc.maxRetries")
+	if err != nil {
+		return nil, err
+	}
+	policy.MaxRetries = int(maxRetries)
+
+	// The limiter is shared by all clients so that the limit holds across endpoints.
+	if demoRateLimiter == nil {
+		requestsPerSecond, err := ds.getDemoConfigFloatValue("abec.rpc.requestsPerSecond")
+		if err != nil {
+			return nil, err
+		}
+		demoRateLimiter = NewDemoRateLimiter(requestsPerSecond)
+	}
+	policy.Limiter = demoRateLimiter
+
+	return policy, nil
+}
@@ -0,0 +1,246 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for a pool of abec RPC endpoints with failover.
+type DemoRPCEndpoint struct {
+	Endpoint  string
+	Healthy   bool
+	Tip       int64
+	Latency   time.Duration
+	LastCheck time.Time
+	LastError error
+	client    DemoAbecRPCClient
+}
+
+type DemoRPCPool struct {
+	endpoints     []*DemoRPCEndpoint
+	maxLag        int64
+	checkInterval time.Duration
+	mutex         sync.Mutex
+	// checkMutex serializes the health checks, so that the calls finding the endpoints stale at once run a single one.
+	checkMutex sync.Mutex
+}
+
+func NewDemoRPCPool(clients map[string]DemoAbecRPCClient, maxLag int64, checkInterval time.Duration) *DemoRPCPool {
+	pool := &DemoRPCPool{maxLag: maxLag, checkInterval: checkInterval}
+	for endpoint, client := range clients {
+		pool.endpoints = append(pool.endpoints, &DemoRPCEndpoint{Endpoint: endpoint, Healthy: true, client: client})
+	}
+	sort.Slice(pool.endpoints, func(i, j int) bool {
+		return pool.endpoints[i].Endpoint < pool.endpoints[j].Endpoint
+	})
+	return pool
+}
+
+// CheckHealth calls GetChainInfo on all endpoints concurrently and records their tips and latencies.
+// It returns copies of the endpoints, as Endpoints does.
+func (pool *DemoRPCPool) CheckHealth() []*DemoRPCEndpoint {
+	pool.checkMutex.Lock()
+	defer pool.checkMutex.Unlock()
+	return pool.checkHealth()
+}
+
+func (pool *DemoRPCPool) checkHealth() []*DemoRPCEndpoint {
+	var wg sync.WaitGroup
+	results := make([]DemoRPCEndpoint, len(pool.endpoints))
+	for i, endpoint := range pool.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *DemoRPCEndpoint) {
+			defer wg.Done()
+			begin := time.Now()
+			_, chainInfo, err := endpoint.client.GetChainInfo()
+			results[i] = DemoRPCEndpoint{Healthy: err == nil, Latency: time.Since(begin), LastCheck: time.Now(), LastError: err}
+			if err == nil {
+				results[i].Tip = chainInfo.NumBlocks
+			}
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	for i, endpoint := range pool.endpoints {
+		endpoint.Healthy = results[i].Healthy
+		endpoint.Latency = results[i].Latency
+		endpoint.LastCheck = results[i].LastCheck
+		endpoint.LastError = results[i].LastError
+		if results[i].Healthy {
+			endpoint.Tip = results[i].Tip
+		}
+	}
+	return pool.copyEndpoints()
+}
+
+// candidates returns the endpoints in the order they should be tried:
+// healthy endpoints within maxLag of the best tip first, by tip and latency, then all others.
+func (pool *DemoRPCPool) candidates() []*DemoRPCEndpoint {
+	if pool.needsCheck() {
+		pool.checkMutex.Lock()
+		// The endpoints may have been checked by another call while this one waited.
+		if pool.needsCheck() {
+			pool.checkHealth()
+		}
+		pool.checkMutex.Unlock()
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	bestTip := int64(-1)
+	for _, endpoint := range pool.endpoints {
+		if endpoint.Healthy && endpoint.Tip > bestTip {
+			bestTip = endpoint.Tip
+		}
+	}
+	isPreferred := func(endpoint *DemoRPCEndpoint) bool {
+		return endpoint.Healthy && endpoint.Tip >= bestTip-pool.maxLag
+	}
+
+	candidates := make([]*DemoRPCEndpoint, len(pool.endpoints))
+	copy(candidates, pool.endpoints)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if isPreferred(a) != isPreferred(b) {
+			return isPreferred(a)
+		}
+		if a.Tip != b.Tip {
+			return a.Tip > b.Tip
+		}
+		return a.Latency < b.Latency
+	})
+	return candidates
+}
+
+// needsCheck tells whether any endpoint was last checked more than checkInterval ago.
+func (pool *DemoRPCPool) needsCheck() bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	for _, endpoint := range pool.endpoints {
+		if time.Since(endpoint.LastCheck) > pool.checkInterval {
+			return true
+		}
+	}
+	return false
+}
+
+func (pool *DemoRPCPool) markFailed(endpoint *DemoRPCEndpoint, err error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	endpoint.Healthy = false
+	endpoint.LastError = err
+	demoLog.Printf("abec rpc endpoint %s failed, marked unhealthy: %v", redactDemoURL(endpoint.Endpoint), err)
+}
+
+// demoRPCPoolCall tries the candidates in turn until one answers. It only fails over on transient errors
+// and not found errors: any other error is an answer of the node, which another endpoint would repeat.
+func demoRPCPoolCall[T any](pool *DemoRPCPool, call func(DemoAbecRPCClient) (core.Bytes, *T, error)) (core.Bytes, *T, error) {
+	var lastErr error
+	for _, endpoint := range pool.candidates() {
+		respBytes, result, err := call(endpoint.client)
+		if err == nil {
+			return respBytes, result, nil
+		}
+		lastErr = err
+		// A lagging endpoint may not know the requested block or tx yet, which does not make it unhealthy.
+		if IsDemoRPCNotFound(err) {
+			continue
+		}
+		if !IsDemoRPCTransient(err) {
+			return nil, nil, err
+		}
+		pool.markFailed(endpoint, err)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no abec rpc endpoint configured")
+	}
+	return nil, nil, lastErr
+}
+
+// Endpoints returns copies of the endpoints, which the calls and health checks keep updating.
+func (pool *DemoRPCPool) Endpoints() []*DemoRPCEndpoint {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.copyEndpoints()
+}
+
+func (pool *DemoRPCPool) copyEndpoints() []*DemoRPCEndpoint {
+	endpoints := make([]*DemoRPCEndpoint, len(pool.endpoints))
+	for i, endpoint := range pool.endpoints {
+		copied := *endpoint
+		endpoints[i] = &copied
+	}
+	return endpoints
+}
+
+func (pool *DemoRPCPool) GetChainInfo() (core.Bytes, *core.AbecChainInfo, error) {
+	return demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *core.AbecChainInfo, error) {
+		return client.GetChainInfo()
+	})
+}
+
+func (pool *DemoRPCPool) GetMempool() (core.Bytes, *core.AbecMempool, error) {
+	return demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *core.AbecMempool, error) {
+		return client.GetMempool()
+	})
+}
+
+func (pool *DemoRPCPool) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	return demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *string, error) {
+		return client.GetBlockHash(height)
+	})
+}
+
+func (pool *DemoRPCPool) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	return demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *core.AbecBlock, error) {
+		return client.GetBlock(hash)
+	})
+}
+
+func (pool *DemoRPCPool) GetBlockBytes(hash string) (core.Bytes, error) {
+	blockBytes, _, err := demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *struct{}, error) {
+		blockBytes, err := client.GetBlockBytes(hash)
+		return blockBytes, nil, err
+	})
+	return blockBytes, err
+}
+
+func (pool *DemoRPCPool) GetRawTx(hash string) (core.Bytes, *core.AbecTx, error) {
+	return demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *core.AbecTx, error) {
+		return client.GetRawTx(hash)
+	})
+}
+
+func (pool *DemoRPCPool) GetBlockBytesByHeight(height int64) (core.Bytes, error) {
+	// Resolve the hash and fetch the block from the same endpoint to avoid mixing forks.
+	blockBytes, _, err := demoRPCPoolCall(pool, func(client DemoAbecRPCClient) (core.Bytes, *struct{}, error) {
+		blockBytes, err := client.GetBlockBytesByHeight(height)
+		return blockBytes, nil, err
+	})
+	return blockBytes, err
+}
+
+func (pool *DemoRPCPool) GetEstimatedTxFee() int64 {
+	return pool.candidates()[0].client.GetEstimatedTxFee()
+}
+
+// SendRawTx submits the tx to the preferred endpoint only. It does not fail over, since the endpoint may have
+// accepted the tx even if the call failed, and it relays the tx to the other nodes itself.
+func (pool *DemoRPCPool) SendRawTx(txStr string) (core.Bytes, *string, error) {
+	endpoint := pool.candidates()[0]
+	respBytes, txid, err := endpoint.client.SendRawTx(txStr)
+	if err != nil && IsDemoRPCTransient(err) {
+		pool.markFailed(endpoint, err)
+	}
+	return respBytes, txid, err
+}
@@ -0,0 +1,215 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDemoRPCPoolEndpoints(t *testing.T) {
+	pool := NewDemoRPCPool(map[string]DemoAbecRPCClient{
+		"http://a": &demoTxTrackerTestClient{blocks: make([][]string, 5)},
+		"http://b": &demoTxTrackerTestClient{blocks: make([][]string, 3)},
+	}, 2, time.Hour)
+
+	// Read the endpoints while their health is checked, which -race checks.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pool.CheckHealth()
+		}()
+		go func() {
+			defer wg.Done()
+			for _, endpoint := range pool.Endpoints() {
+				_ = endpoint.Tip
+			}
+		}()
+	}
+	wg.Wait()
+
+	endpoints := pool.Endpoints()
+	if len(endpoints) != 2 || endpoints[0].Tip != 4 || endpoints[1].Tip != 2 {
+		t.Fatalf("got endpoints %+v, expecting http://a at tip 4 and http://b at tip 2", endpoints)
+	}
+	endpoints[0].Healthy = false
+	if !pool.Endpoints()[0].Healthy {
+		t.Errorf("updating a returned endpoint changed the pool")
+	}
+}
+
+// demoRPCPoolTestNode serves a mock node of the golden fixture grown by some blocks, answering 503 while down.
+type demoRPCPoolTestNode struct {
+	server *DemoMockAbecServer
+	http   *httptest.Server
+	tip    int64
+	down   int32
+}
+
+func newDemoRPCPoolTestNode(t *testing.T, blocks int) *demoRPCPoolTestNode {
+	fixture, err := LoadDemoChainFixture(demoGoldenFixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &demoRPCPoolTestNode{server: NewDemoMockAbecServer(fixture, demoGoldenUsername, demoGoldenPassword)}
+	if _, err := node.server.Generate(blocks); err != nil {
+		t.Fatal(err)
+	}
+	node.tip = fixture.Tip()
+	node.http = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&node.down) == 1 {
+			http.Error(w, "<html>503 Service Unavailable</html>", http.StatusServiceUnavailable)
+			return
+		}
+		node.server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(node.http.Close)
+	return node
+}
+
+func (node *demoRPCPoolTestNode) setDown(down bool) {
+	value := int32(0)
+	if down {
+		value = 1
+	}
+	atomic.StoreInt32(&node.down, value)
+}
+
+func newDemoRPCPoolTest(maxLag int64, checkInterval time.Duration, nodes ...*demoRPCPoolTestNode) *DemoRPCPool {
+	clients := make(map[string]DemoAbecRPCClient)
+	for _, node := range nodes {
+		clients[node.http.URL] = NewDemoAbecHTTPClient(node.http.URL, demoGoldenUsername, demoGoldenPassword, 2*time.Second)
+	}
+	return NewDemoRPCPool(clients, maxLag, checkInterval)
+}
+
+func getDemoRPCPoolTestTip(t *testing.T, pool *DemoRPCPool) int64 {
+	_, chainInfo, err := pool.GetChainInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chainInfo.NumBlocks
+}
+
+func getDemoRPCPoolTestEndpoint(pool *DemoRPCPool, node *demoRPCPoolTestNode) *DemoRPCEndpoint {
+	for _, endpoint := range pool.Endpoints() {
+		if endpoint.Endpoint == node.http.URL {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+// TestDemoRPCPoolRouting routes the calls to the highest tip, fails over to a lagging node once it is closed, and
+// orders the unhealthy node by maxLag.
+func TestDemoRPCPoolRouting(t *testing.T) {
+	lagging, behind, ahead := newDemoRPCPoolTestNode(t, 0), newDemoRPCPoolTestNode(t, 2), newDemoRPCPoolTestNode(t, 4)
+	pool := newDemoRPCPoolTest(1, time.Hour, lagging, behind, ahead)
+	if tip := getDemoRPCPoolTestTip(t, pool); tip != ahead.tip {
+		t.Fatalf("got tip %d, expecting the highest tip %d", tip, ahead.tip)
+	}
+
+	ahead.http.Close()
+	if tip := getDemoRPCPoolTestTip(t, pool); tip != behind.tip {
+		t.Fatalf("got tip %d once the node ahead is closed, expecting the next tip %d", tip, behind.tip)
+	}
+	if endpoint := getDemoRPCPoolTestEndpoint(pool, ahead); endpoint.Healthy || !IsDemoRPCTransient(endpoint.LastError) {
+		t.Errorf("got the closed node healthy %v with %v, expecting it failed on a network error", endpoint.Healthy, endpoint.LastError)
+	}
+
+	// The lagging node is tried after the unhealthy one within maxLag 1 of the best tip, and before it within 2.
+	for maxLag, want := range map[int64][]*demoRPCPoolTestNode{1: {behind, ahead, lagging}, 2: {behind, lagging, ahead}} {
+		pool.maxLag = maxLag
+		candidates := pool.candidates()
+		for i, node := range want {
+			if candidates[i].Endpoint != node.http.URL {
+				t.Errorf("got candidate %d at tip %d with maxLag %d, expecting the node at tip %d", i, candidates[i].Tip, maxLag, node.tip)
+			}
+		}
+	}
+}
+
+// TestDemoRPCPoolRecheck marks a node failing on a 503 unhealthy, and routes the calls to it again once it is
+// rechecked healthy.
+func TestDemoRPCPoolRecheck(t *testing.T) {
+	node, other := newDemoRPCPoolTestNode(t, 2), newDemoRPCPoolTestNode(t, 0)
+	pool := newDemoRPCPoolTest(1, 200*time.Millisecond, node, other)
+	if tip := getDemoRPCPoolTestTip(t, pool); tip != node.tip {
+		t.Fatalf("got tip %d, expecting the highest tip %d", tip, node.tip)
+	}
+
+	node.setDown(true)
+	if tip := getDemoRPCPoolTestTip(t, pool); tip != other.tip {
+		t.Fatalf("got tip %d while the node is down, expecting the other tip %d", tip, other.tip)
+	}
+	if endpoint := getDemoRPCPoolTestEndpoint(pool, node); endpoint.Healthy {
+		t.Fatalf("the node answering 503 is still healthy")
+	}
+
+	// The node is only tried again once rechecked.
+	node.setDown(false)
+	if tip := getDemoRPCPoolTestTip(t, pool); tip != other.tip {
+		t.Errorf("got tip %d before the recheck, expecting the other tip %d", tip, other.tip)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if tip := getDemoRPCPoolTestTip(t, pool); tip != node.tip {
+		t.Errorf("got tip %d after the recheck, expecting the tip %d of the recovered node", tip, node.tip)
+	}
+	if endpoint := getDemoRPCPoolTestEndpoint(pool, node); !endpoint.Healthy || endpoint.LastError != nil {
+		t.Errorf("got the recovered node healthy %v with %v, expecting it healthy", endpoint.Healthy, endpoint.LastError)
+	}
+}
+
+// TestDemoRPCPoolSendRawTx submits txs to the preferred node only, whether it is down or rejects them.
+func TestDemoRPCPoolSendRawTx(t *testing.T) {
+	node, other := newDemoRPCPoolTestNode(t, 2), newDemoRPCPoolTestNode(t, 0)
+	pool := newDemoRPCPoolTest(1, time.Hour, node, other)
+	pool.CheckHealth()
+	requests := other.server.Requests()
+
+	// The node answers with an error, which is not transient.
+	if _, _, err := pool.SendRawTx("00"); err == nil || IsDemoRPCTransient(err) {
+		t.Fatalf("got %v submitting a malformed tx, expecting the node to reject it", err)
+	}
+	if endpoint := getDemoRPCPoolTestEndpoint(pool, node); !endpoint.Healthy {
+		t.Errorf("the node rejecting a tx was marked unhealthy")
+	}
+
+	node.setDown(true)
+	if _, _, err := pool.SendRawTx("00"); !IsDemoRPCTransient(err) {
+		t.Fatalf("got %v submitting a tx to the node down, expecting a transient error", err)
+	}
+	if endpoint := getDemoRPCPoolTestEndpoint(pool, node); endpoint.Healthy {
+		t.Errorf("the node down is still healthy")
+	}
+	if other.server.Requests() != requests {
+		t.Errorf("got %d requests to the other node, expecting the txs submitted to the preferred node only", other.server.Requests()-requests)
+	}
+}
+
+// TestDemoRPCPoolConcurrentChecks makes concurrent calls on stale endpoints, which run a single health check.
+func TestDemoRPCPoolConcurrentChecks(t *testing.T) {
+	node := newDemoRPCPoolTestNode(t, 0)
+	pool := newDemoRPCPoolTest(1, time.Hour, node)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			getDemoRPCPoolTestTip(t, pool)
+		}()
+	}
+	wg.Wait()
+	// Every call sends one getinfo, and the only health check another one.
+	if requests := node.server.Requests(); requests != 9 {
+		t.Errorf("got %d requests, expecting 8 calls and a single health check", requests)
+	}
+}
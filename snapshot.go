@@ -0,0 +1,234 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+// Define data types and methods for the balance snapshots of the accounts at past heights.
+//
+// The balance of an account at a height is the value of its coins created at or before the height,
+// minus those whose serial numbers appear in blocks at or before the height, as recorded in the
+// coin store by the scans. The report is signed with an ed25519 key and carries the public key, so
+// that auditors can check it was not altered since it was made.
+type DemoSnapshotAccount struct {
+	AccountSeqNo int    `json:"account"`
+	Fingerprint  string `json:"fingerprint"`
+	Balance      int64  `json:"balance"`
+	// Coins are the IDs of the coins held at the height.
+	Coins []string `json:"coins"`
+}
+
+type DemoSnapshotReport struct {
+	ChainID   int8                   `json:"chainID"`
+	Height    int64                  `json:"height"`
+	BlockHash string                 `json:"blockHash"`
+	BlockTime int64                  `json:"blockTime"`
+	CreatedAt int64                  `json:"createdAt"`
+	Total     int64                  `json:"total"`
+	Accounts  []*DemoSnapshotAccount `json:"accounts"`
+}
+
+type DemoSignedSnapshot struct {
+	// Report is kept as it was signed, so that it is verified byte for byte.
+	Report    json.RawMessage `json:"report"`
+	PublicKey string          `json:"publicKey"`
+	Signature string          `json:"signature"`
+}
+
+// NewDemoSnapshotReport computes the balances of the given accounts, or of all accounts of the coin store
+// if none are given, at a height the coin store has scanned. An account without coins in the coin store
+// holds none, since the coin store is scanned from the first block, and is reported with a zero balance.
+func NewDemoSnapshotReport(client DemoAbecRPCClient, store *DemoCoinStore, chainID int8, height int64, accounts []*DemoAccount) (*DemoSnapshotReport, error) {
+	if height < 0 {
+		return nil, newDemoError(DemoErrorValidation, "height %d is negative", height)
+	}
+	if height > store.ScannedHeight {
+		return nil, newDemoError(DemoErrorValidation, "height %d is not scanned yet, the coin store is scanned up to block %d", height, store.ScannedHeight)
+	}
+	// Balances are only complete if the coin store was scanned from the first block.
+	if store.ScanFromHeight < 0 {
+		return nil, newDemoError(DemoErrorValidation, "the coin store does not record the block its scan started from, rescan it from block 0")
+	}
+	if store.ScanFromHeight > 0 {
+		return nil, newDemoError(DemoErrorValidation, "the coin store is scanned from block %d, the coins of earlier blocks are missing", store.ScanFromHeight)
+	}
+	accountSeqNos := make([]int, 0, len(accounts))
+	fingerprints := make(map[int]string)
+	for _, account := range accounts {
+		accountSeqNos = append(accountSeqNos, account.SerialNo)
+		fingerprints[account.SerialNo] = account.Fingerprint.HexString()
+	}
+	if len(accounts) == 0 {
+		accountSeqNos = store.AccountSeqNos()
+	}
+	for _, seqno := range accountSeqNos {
+		for _, record := range store.AccountCoins(seqno, "") {
+			if record.BlockHeight < store.ScanFromHeight {
+				return nil, newDemoError(DemoErrorValidation, "coin %s of block %d is below the scan start at block %d", record.ID, record.BlockHeight, store.ScanFromHeight)
+			}
+			if fingerprint, ok := fingerprints[seqno]; ok && record.Fingerprint != fingerprint {
+				return nil, newDemoError(DemoErrorValidation, "coin %s of account %d has fingerprint %s, not that of the account %s", record.ID, seqno, record.Fingerprint, fingerprint)
+			}
+		}
+	}
+	_, blockHash, err := client.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	_, block, err := client.GetBlock(*blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DemoSnapshotReport{
+		ChainID:   chainID,
+		Height:    height,
+		BlockHash: *blockHash,
+		BlockTime: block.Time,
+		CreatedAt: time.Now().Unix(),
+		Accounts:  make([]*DemoSnapshotAccount, 0, len(accountSeqNos)),
+	}
+	for _, seqno := range accountSeqNos {
+		account := &DemoSnapshotAccount{AccountSeqNo: seqno, Fingerprint: fingerprints[seqno], Coins: make([]string, 0)}
+		for _, record := range store.AccountCoins(seqno, "") {
+			account.Fingerprint = record.Fingerprint
+			if record.BlockHeight > height || (record.Status == DemoCoinSpent && record.SpentHeight <= height) {
+				continue
+			}
+			account.Balance += record.Value
+			account.Coins = append(account.Coins, record.ID)
+		}
+		report.Total += account.Balance
+		report.Accounts = append(report.Accounts, account)
+	}
+	return report, nil
+}
+
+// FindDemoHeightAtTime returns the height of the last block with a time at or before the given time, or -1 if
+// there is none. It is a binary search, which takes block times as increasing with the height. They are not
+// strictly, since the node only requires the time of a block to be after the median time of the 11 blocks
+// before it. When the given time falls among blocks whose times are out of order, the height found is that of
+// a block at or before the time whose next block is after it, which may not be the last one.
+func FindDemoHeightAtTime(client DemoAbecRPCClient, at time.Time, tip int64) (int64, error) {
+	low, high := int64(0), tip
+	found := int64(-1)
+	for low <= high {
+		middle := (low + high) / 2
+		_, blockHash, err := client.GetBlockHash(middle)
+		if err != nil {
+			return -1, err
+		}
+		_, block, err := client.GetBlock(*blockHash)
+		if err != nil {
+			return -1, err
+		}
+		if block.Time <= at.Unix() {
+			found = middle
+			low = middle + 1
+		} else {
+			high = middle - 1
+		}
+	}
+	return found, nil
+}
+
+// SignDemoSnapshotReport signs a report with the ed25519 private key of the given seed.
+func SignDemoSnapshotReport(report *DemoSnapshotReport, seed []byte) (*DemoSignedSnapshot, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, newDemoError(DemoErrorValidation, "signing key is not a %d bytes seed", ed25519.SeedSize)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	return &DemoSignedSnapshot{
+		Report:    data,
+		PublicKey: hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		Signature: hex.EncodeToString(ed25519.Sign(privateKey, data)),
+	}, nil
+}
+
+// VerifyDemoSnapshot checks that a signed snapshot is signed with the given hex encoded public key, published
+// by the signer, and returns its report if the signature is valid.
+func VerifyDemoSnapshot(snapshot *DemoSignedSnapshot, trustedKey string) (*DemoSnapshotReport, error) {
+	publicKey, err := hex.DecodeString(trustedKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, newDemoError(DemoErrorValidation, "trusted public key is not a hex encoded ed25519 key")
+	}
+	if !strings.EqualFold(snapshot.PublicKey, trustedKey) {
+		return nil, newDemoError(DemoErrorValidation, "snapshot is signed with public key %v, not %v", snapshot.PublicKey, trustedKey)
+	}
+	signature, err := hex.DecodeString(snapshot.Signature)
+	if err != nil {
+		return nil, newDemoError(DemoErrorValidation, "signature is not hex encoded: %v", err)
+	}
+	// The report is signed compact, as marshalled, and indented when the snapshot is saved.
+	var report bytes.Buffer
+	if err := json.Compact(&report, snapshot.Report); err != nil {
+		return nil, newDemoError(DemoErrorValidation, "report is not valid json: %v", err)
+	}
+	if !ed25519.Verify(publicKey, report.Bytes(), signature) {
+		return nil, newDemoError(DemoErrorValidation, "signature does not match the report")
+	}
+	verified := &DemoSnapshotReport{}
+	if err := json.Unmarshal(report.Bytes(), verified); err != nil {
+		return nil, newDemoError(DemoErrorValidation, "failed to parse the report: %v", err)
+	}
+	return verified, nil
+}
+
+func LoadDemoSignedSnapshot(path string) (*DemoSignedSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &DemoSignedSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, newDemoError(DemoErrorValidation, "failed to parse snapshot %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+func (snapshot *DemoSignedSnapshot) Save(path string) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+func (ds *DemoSet) getDemoSnapshotSigningKey() ([]byte, error) {
+	signingKey := ds.getDemoConfigStringValue("snapshot.signingKey")
+	if signingKey == "" {
+		return nil, newDemoError(DemoErrorValidation,
+			"no key to sign the snapshot, set a 32 bytes hex seed with `config set snapshot.signingKey \"$(openssl rand -hex 32)\"`")
+	}
+	seed, err := DecodeDemoHex(signingKey)
+	if err != nil {
+		return nil, newDemoError(DemoErrorValidation, "config snapshot.signingKey is not hex encoded: %v", err)
+	}
+	return seed, nil
+}
+
+func printDemoSnapshotReport(report *DemoSnapshotReport) {
+	fmt.Printf("Chain %d, block %v at %v: %v\n", report.ChainID, report.Height, time.Unix(report.BlockTime, 0).UTC().Format(time.RFC3339), report.BlockHash)
+	for _, account := range report.Accounts {
+		fmt.Printf("  account %d: %v ABEL in %d coins\n", account.AccountSeqNo, core.NeutrinoToAbel(account.Balance), len(account.Coins))
+	}
+	fmt.Printf("  total: %v ABEL\n", core.NeutrinoToAbel(report.Total))
+}
@@ -0,0 +1,205 @@
+//go:build demo || test
+
+// +build: demo test
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	core "abelian.info/sdk/core"
+)
+
+func newDemoSnapshotTestReport() *DemoSnapshotReport {
+	return &DemoSnapshotReport{
+		ChainID:   0,
+		Height:    2,
+		BlockHash: "b2",
+		BlockTime: 1700000256,
+		CreatedAt: 1700001000,
+		Total:     149e6,
+		Accounts: []*DemoSnapshotAccount{
+			{AccountSeqNo: 1, Fingerprint: "f1", Balance: 149e6, Coins: []string{"t2:0"}},
+		},
+	}
+}
+
+func TestDemoSnapshotSignVerify(t *testing.T) {
+	seed := bytes.Repeat([]byte{1}, ed25519.SeedSize)
+	report := newDemoSnapshotTestReport()
+	snapshot, err := SignDemoSnapshotReport(report, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedKey := snapshot.PublicKey
+	if want := hex.EncodeToString(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)); trustedKey != want {
+		t.Fatalf("got public key %s, expecting %s", trustedKey, want)
+	}
+	verified, err := VerifyDemoSnapshot(snapshot, trustedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(verified, report) {
+		t.Errorf("got report %+v, expecting %+v", verified, report)
+	}
+
+	// The saved snapshot is indented, it has to verify as it was signed.
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadDemoSignedSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := VerifyDemoSnapshot(loaded, trustedKey); err != nil || !reflect.DeepEqual(verified, report) {
+		t.Errorf("got report %+v, %v from the saved snapshot, expecting %+v", verified, err, report)
+	}
+
+	otherSnapshot, err := SignDemoSnapshotReport(report, bytes.Repeat([]byte{2}, ed25519.SeedSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		tamper     func(snapshot *DemoSignedSnapshot)
+		trustedKey string
+	}{
+		{"modified report", func(snapshot *DemoSignedSnapshot) {
+			snapshot.Report = bytes.Replace(snapshot.Report, []byte("149000000"), []byte("249000000"), -1)
+		}, trustedKey},
+		{"modified signature", func(snapshot *DemoSignedSnapshot) {
+			signature, _ := hex.DecodeString(snapshot.Signature)
+			signature[0] ^= 1
+			snapshot.Signature = hex.EncodeToString(signature)
+		}, trustedKey},
+		{"signature not hex", func(snapshot *DemoSignedSnapshot) { snapshot.Signature = "zz" }, trustedKey},
+		// A snapshot signed with another key verifies against the key it carries, not against the trusted one.
+		{"other key", func(snapshot *DemoSignedSnapshot) { *snapshot = *otherSnapshot }, trustedKey},
+		{"other key carried", func(snapshot *DemoSignedSnapshot) { snapshot.PublicKey = otherSnapshot.PublicKey }, trustedKey},
+		{"trusted key not hex", func(snapshot *DemoSignedSnapshot) {}, "zz"},
+		{"trusted key too short", func(snapshot *DemoSignedSnapshot) {}, trustedKey[:32]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tampered := &DemoSignedSnapshot{
+				Report:    append([]byte(nil), snapshot.Report...),
+				PublicKey: snapshot.PublicKey,
+				Signature: snapshot.Signature,
+			}
+			test.tamper(tampered)
+			if _, err := VerifyDemoSnapshot(tampered, test.trustedKey); demoExitCode(err) != DemoErrorValidation.ExitCode() {
+				t.Errorf("got error %v, expecting a validation error", err)
+			}
+		})
+	}
+
+	if _, err := SignDemoSnapshotReport(report, seed[:16]); demoExitCode(err) != DemoErrorValidation.ExitCode() {
+		t.Errorf("got error %v signing with a short seed, expecting a validation error", err)
+	}
+}
+
+// demoSnapshotTestClient serves blocks named b<height>, 256 seconds apart.
+type demoSnapshotTestClient struct {
+	DemoAbecRPCClient
+}
+
+func (client *demoSnapshotTestClient) GetBlockHash(height int64) (core.Bytes, *string, error) {
+	hash := fmt.Sprintf("b%d", height)
+	return nil, &hash, nil
+}
+
+func (client *demoSnapshotTestClient) GetBlock(hash string) (core.Bytes, *core.AbecBlock, error) {
+	height, err := strconv.ParseInt(strings.TrimPrefix(hash, "b"), 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, &core.AbecBlock{Height: height, Time: 1700000000 + 256*height}, nil
+}
+
+func newDemoSnapshotTestStore(scanFromHeight int64) *DemoCoinStore {
+	return &DemoCoinStore{ScannedHeight: 2, ScanFromHeight: scanFromHeight, Coins: map[string]*DemoCoinRecord{
+		"t1:0": {ID: "t1:0", AccountSeqNo: 0, Fingerprint: "f0", TxHash: "t1", Value: 5e7, BlockHeight: 1, Status: DemoCoinUnspent},
+	}}
+}
+
+// TestNewDemoSnapshotReport checks that an account without coins is reported with a zero balance and the
+// fingerprint of its address.
+func TestNewDemoSnapshotReport(t *testing.T) {
+	accounts := []*DemoAccount{{SerialNo: 0, Fingerprint: core.Bytes{0xf0}}, {SerialNo: 7, Fingerprint: core.Bytes{0xf7}}}
+	store := newDemoSnapshotTestStore(0)
+	report, err := NewDemoSnapshotReport(&demoSnapshotTestClient{}, store, 0, 2, accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*DemoSnapshotAccount{
+		{AccountSeqNo: 0, Fingerprint: "f0", Balance: 5e7, Coins: []string{"t1:0"}},
+		{AccountSeqNo: 7, Fingerprint: "f7", Balance: 0, Coins: []string{}},
+	}
+	if !reflect.DeepEqual(report.Accounts, want) || report.Total != 5e7 || report.BlockHash != "b2" {
+		t.Errorf("got report %+v with accounts %+v %+v, expecting accounts %+v %+v", report, report.Accounts[0], report.Accounts[1], want[0], want[1])
+	}
+
+	// Without accounts, those of the coin store are reported.
+	report, err = NewDemoSnapshotReport(&demoSnapshotTestClient{}, store, 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Accounts) != 1 || report.Accounts[0].Fingerprint != "f0" || report.Accounts[0].Balance != 0 {
+		t.Errorf("got accounts %+v at block 0, expecting account 0 without coins", report.Accounts[0])
+	}
+}
+
+// TestNewDemoSnapshotReportRejects checks the coin stores whose balances would be incomplete. They are rejected
+// before the node is called.
+func TestNewDemoSnapshotReportRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		store    *DemoCoinStore
+		height   int64
+		accounts []*DemoAccount
+	}{
+		{"negative height", newDemoSnapshotTestStore(0), -1, nil},
+		{"height not scanned", newDemoSnapshotTestStore(0), 3, nil},
+		{"scan start unknown", newDemoSnapshotTestStore(-1), 2, nil},
+		{"scan started after block 0", newDemoSnapshotTestStore(1), 2, nil},
+		{"coin below scan start", newDemoSnapshotTestStore(2), 2, nil},
+		{"coin of another address", newDemoSnapshotTestStore(0), 2, []*DemoAccount{{SerialNo: 0, Fingerprint: core.Bytes{0xf1}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewDemoSnapshotReport(nil, test.store, 0, test.height, test.accounts); demoExitCode(err) != DemoErrorValidation.ExitCode() {
+				t.Errorf("got error %v, expecting a validation error", err)
+			}
+		})
+	}
+}
+
+// TestFindDemoHeightAtTime checks the search at and between the block times.
+func TestFindDemoHeightAtTime(t *testing.T) {
+	tests := []struct {
+		at   int64
+		want int64
+	}{
+		{1699999999, -1},
+		{1700000000, 0},
+		{1700000255, 0},
+		{1700000256, 1},
+		{1700000767, 2},
+		{1800000000, 4},
+	}
+	for _, test := range tests {
+		if got, err := FindDemoHeightAtTime(&demoSnapshotTestClient{}, time.Unix(test.at, 0), 4); err != nil || got != test.want {
+			t.Errorf("got height %d, %v at time %d, expecting %d", got, err, test.at, test.want)
+		}
+	}
+}